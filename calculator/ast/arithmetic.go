@@ -0,0 +1,549 @@
+package ast
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/eliquious/aechbar/calculator/bigmath"
+)
+
+var (
+	// ErrDivisionByZero is returned when dividing by zero
+	ErrDivisionByZero = errors.New("division by zero")
+
+	// ErrExponentTooLarge is returned when an integer power would exceed
+	// maxPowerBits or a decimal power the range of decimals
+	ErrExponentTooLarge = errors.New("exponent too large")
+)
+
+// maxPowerBits limits the size of integer exponentiation results.
+const maxPowerBits = 1 << 24
+
+// maxExactRoot is the largest root of a fraction which is looked for exactly.
+const maxExactRoot = 64
+
+// OperandError is returned when the types of the operands do not support an
+// operation.
+type OperandError struct {
+	Operation   string
+	Left, Right string
+}
+
+// Error returns the string representation of the error.
+func (e *OperandError) Error() string {
+	return fmt.Sprintf("%s of %s and %s unsupported", e.Operation, e.Left, e.Right)
+}
+
+// unsupportedOperation returns an error for operand types which do not
+// support an operation.
+func unsupportedOperation(operation string, lh, rh Expression) error {
+	return &OperandError{Operation: operation, Left: OperandKind(lh), Right: OperandKind(rh)}
+}
+
+// OperandType returns the name of the type of an operand for error messages.
+// Values without a type name, such as modules, are described by their value.
+func OperandType(value Expression) string {
+	if typ := TypeOf(value); typ != "" {
+		return typ
+	}
+	return value.String()
+}
+
+// OperandKind returns the kind of a numeric value, which the type system
+// does not distinguish, or the type of any other operand.
+func OperandKind(value Expression) string {
+	switch value.(type) {
+	case *IntegerLiteral:
+		return "integer"
+	case *DecimalLiteral:
+		return "decimal"
+	case *RationalLiteral:
+		return "rational"
+	}
+	return OperandType(value)
+}
+
+// addValues adds two values through their operand interfaces.
+func addValues(lh, rh Expression) (Expression, error) {
+	if x, ok := lh.(AddExpression); ok {
+		return x.Add(rh)
+	}
+	return nil, unsupportedOperation("Addition", lh, rh)
+}
+
+// subValues subtracts two values through their operand interfaces.
+func subValues(lh, rh Expression) (Expression, error) {
+	if x, ok := lh.(SubExpression); ok {
+		return x.Sub(rh)
+	}
+	return nil, unsupportedOperation("Subtraction", lh, rh)
+}
+
+// multValues multiplies two values through their operand interfaces.
+func multValues(lh, rh Expression) (Expression, error) {
+	if x, ok := lh.(MultExpression); ok {
+		return x.Mult(rh)
+	}
+	return nil, unsupportedOperation("Multiplication", lh, rh)
+}
+
+// divValues divides two values through their operand interfaces.
+func divValues(lh, rh Expression) (Expression, error) {
+	if x, ok := lh.(DivExpression); ok {
+		return x.Div(rh)
+	}
+	return nil, unsupportedOperation("Division", lh, rh)
+}
+
+// powValues exponentiates two values through their operand interfaces.
+func powValues(lh, rh Expression) (Expression, error) {
+	if x, ok := lh.(PowExpression); ok {
+		return x.Pow(rh)
+	}
+	return nil, unsupportedOperation("Exponentiation", lh, rh)
+}
+
+// newFloat returns an empty float using the larger precision of the operands
+// and the rounding mode of the first.
+func newFloat(x, y *big.Float) *big.Float {
+	prec := x.Prec()
+	if y.Prec() > prec {
+		prec = y.Prec()
+	}
+	return new(big.Float).SetPrec(prec).SetMode(x.Mode())
+}
+
+// intToFloat converts an integer using the precision of ref.
+func intToFloat(i *big.Int, ref *big.Float) *big.Float {
+	return newFloat(ref, ref).SetInt(i)
+}
+
+// ratToFloat converts a fraction using the precision of ref.
+func ratToFloat(r *big.Rat, ref *big.Float) *big.Float {
+	return newFloat(ref, ref).SetRat(r)
+}
+
+// newRational returns the fraction as a RationalLiteral or as an
+// IntegerLiteral if the denominator is one.
+func newRational(r *big.Rat) Expression {
+	if r.IsInt() {
+		return &IntegerLiteral{Value: new(big.Int).Set(r.Num())}
+	}
+	return &RationalLiteral{Value: r}
+}
+
+// powInt returns x**n for a non-negative exponent.
+func powInt(x, n *big.Int) (*big.Int, error) {
+	if x.BitLen() > 1 && (!n.IsInt64() || int64(x.BitLen()-1)*n.Int64() > maxPowerBits) {
+		return nil, ErrExponentTooLarge
+	}
+	return new(big.Int).Exp(x, n, nil), nil
+}
+
+// powRat returns r**n for an integer exponent.
+func powRat(r *big.Rat, n *big.Int) (Expression, error) {
+	if n.Sign() < 0 && r.Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+	e := new(big.Int).Abs(n)
+	num, err := powInt(r.Num(), e)
+	if err != nil {
+		return nil, err
+	}
+	den, err := powInt(r.Denom(), e)
+	if err != nil {
+		return nil, err
+	}
+	if n.Sign() < 0 {
+		num, den = den, num
+	}
+	return newRational(new(big.Rat).SetFrac(num, den)), nil
+}
+
+// PowFraction returns r**exp for a fractional exponent. The result is exact
+// if r is a perfect power and otherwise a decimal with the given precision
+// in bits, or the precision of r if it is zero.
+func PowFraction(r, exp *big.Rat, prec uint) (Expression, error) {
+	if z, ok := powExact(r, exp); ok {
+		return z, nil
+	}
+	return powFrac(new(big.Float).SetPrec(prec).SetRat(r), exp)
+}
+
+// powExact returns r**(p/q) exactly if the numerator and denominator of a
+// non-negative r are perfect qth powers, such as (4/9)**(1/2).
+func powExact(r, exp *big.Rat) (Expression, bool) {
+	if r.Sign() < 0 || !exp.Denom().IsInt64() || exp.Denom().Int64() > maxExactRoot {
+		return nil, false
+	}
+	q := exp.Denom().Int64()
+	num, ok := intRoot(r.Num(), q)
+	if !ok {
+		return nil, false
+	}
+	den, ok := intRoot(r.Denom(), q)
+	if !ok {
+		return nil, false
+	}
+	z, err := powRat(new(big.Rat).SetFrac(num, den), exp.Num())
+	return z, err == nil
+}
+
+// intRoot returns the nth root of a non-negative integer if it is an
+// integer.
+func intRoot(x *big.Int, n int64) (*big.Int, bool) {
+	if x.BitLen() <= 1 {
+		return x, true
+	}
+
+	// Newton's iteration decreases from a guess above the root
+	N, N1 := big.NewInt(n), big.NewInt(n-1)
+	z := new(big.Int).Lsh(big.NewInt(1), uint((int64(x.BitLen())+n-1)/n))
+	for {
+		y := new(big.Int).Exp(z, N1, nil)
+		y.Quo(x, y)
+		y.Add(y, new(big.Int).Mul(z, N1))
+		y.Quo(y, N)
+		if y.Cmp(z) >= 0 {
+			break
+		}
+		z = y
+	}
+	return z, new(big.Int).Exp(z, N, nil).Cmp(x) == 0
+}
+
+// powFrac returns x**(p/q) by taking the qth root of x**p.
+func powFrac(x *big.Float, exp *big.Rat) (Expression, error) {
+	if !exp.Denom().IsInt64() || exp.Denom().Int64() > 1<<16 {
+		return powFloat(x, ratToFloat(exp, x))
+	}
+	z, err := bigmath.PowInt(x, exp.Num())
+	if err == nil {
+		z, err = bigmath.Root(z, exp.Denom().Int64())
+	}
+	return decimalPower(z, err)
+}
+
+// powFloat returns x**y for decimal operands.
+func powFloat(x, y *big.Float) (Expression, error) {
+	return decimalPower(bigmath.Pow(x, y))
+}
+
+// decimalPower returns the result of a decimal power. Results too large to
+// be represented are reported as ErrExponentTooLarge.
+func decimalPower(z *big.Float, err error) (Expression, error) {
+	if err == bigmath.ErrOverflow {
+		return nil, ErrExponentTooLarge
+	} else if err != nil {
+		return nil, err
+	}
+	return &DecimalLiteral{Value: z}, nil
+}
+
+func (e IntegerLiteral) Sub(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType:
+		return &IntegerLiteral{Value: new(big.Int).Sub(e.Value, expr.(*IntegerLiteral).Value)}, nil
+	case DecimalLiteralType:
+		rh := expr.(*DecimalLiteral).Value
+		return &DecimalLiteral{Value: newFloat(rh, rh).Sub(intToFloat(e.Value, rh), rh)}, nil
+	case RationalLiteralType:
+		r := new(big.Rat).SetInt(e.Value)
+		return newRational(r.Sub(r, expr.(*RationalLiteral).Value)), nil
+	case QuantityLiteralType:
+		return quantityOf(&e).Sub(expr)
+	case ComplexLiteralType:
+		return complexOf(&e, expr.(*ComplexLiteral).Re).Sub(expr)
+	case ArrayLiteralType:
+		return elementwise("Subtraction", &e, expr, subValues)
+	default:
+		return nil, unsupportedOperation("Integer subtraction", &e, expr)
+	}
+}
+
+func (e IntegerLiteral) Mult(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType:
+		return &IntegerLiteral{Value: new(big.Int).Mul(e.Value, expr.(*IntegerLiteral).Value)}, nil
+	case DecimalLiteralType:
+		rh := expr.(*DecimalLiteral).Value
+		return &DecimalLiteral{Value: newFloat(rh, rh).Mul(intToFloat(e.Value, rh), rh)}, nil
+	case RationalLiteralType:
+		r := new(big.Rat).SetInt(e.Value)
+		return newRational(r.Mul(r, expr.(*RationalLiteral).Value)), nil
+	case QuantityLiteralType:
+		return expr.(*QuantityLiteral).Mult(&e)
+	case ComplexLiteralType:
+		return complexOf(&e, expr.(*ComplexLiteral).Re).Mult(expr)
+	case MatrixLiteralType:
+		return expr.(*MatrixLiteral).Mult(&e)
+	case ArrayLiteralType:
+		return elementwise("Multiplication", &e, expr, multValues)
+	default:
+		return nil, unsupportedOperation("Integer multiplication", &e, expr)
+	}
+}
+
+// Div divides exactly. The result is an IntegerLiteral if the quotient is
+// whole and a RationalLiteral otherwise.
+func (e IntegerLiteral) Div(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType:
+		rh := expr.(*IntegerLiteral).Value
+		if rh.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return newRational(new(big.Rat).SetFrac(e.Value, rh)), nil
+	case DecimalLiteralType:
+		rh := expr.(*DecimalLiteral).Value
+		if rh.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return &DecimalLiteral{Value: newFloat(rh, rh).Quo(intToFloat(e.Value, rh), rh)}, nil
+	case RationalLiteralType:
+		r := new(big.Rat).SetInt(e.Value)
+		return newRational(r.Quo(r, expr.(*RationalLiteral).Value)), nil
+	case QuantityLiteralType:
+		return quantityOf(&e).Div(expr)
+	case ComplexLiteralType:
+		return complexOf(&e, expr.(*ComplexLiteral).Re).Div(expr)
+	case ArrayLiteralType:
+		return elementwise("Division", &e, expr, divValues)
+	default:
+		return nil, unsupportedOperation("Integer division", &e, expr)
+	}
+}
+
+// Pow raises the integer to the power of the operand. Negative integer
+// exponents produce exact fractions, as do fractional exponents of perfect
+// powers such as 8 ** (1/3).
+func (e IntegerLiteral) Pow(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType:
+		n := expr.(*IntegerLiteral).Value
+		if n.Sign() < 0 {
+			return powRat(new(big.Rat).SetInt(e.Value), n)
+		}
+		z, err := powInt(e.Value, n)
+		if err != nil {
+			return nil, err
+		}
+		return &IntegerLiteral{Value: z}, nil
+	case DecimalLiteralType:
+		rh := expr.(*DecimalLiteral).Value
+		return powFloat(intToFloat(e.Value, rh), rh)
+	case RationalLiteralType:
+		return PowFraction(new(big.Rat).SetInt(e.Value), expr.(*RationalLiteral).Value, 0)
+	case ComplexLiteralType:
+		return complexOf(&e, expr.(*ComplexLiteral).Re).Pow(expr)
+	case ArrayLiteralType:
+		return elementwise("Exponentiation", &e, expr, powValues)
+	default:
+		return nil, unsupportedOperation("Integer exponentiation", &e, expr)
+	}
+}
+
+func (e DecimalLiteral) Sub(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType:
+		return &DecimalLiteral{Value: newFloat(e.Value, e.Value).Sub(e.Value, intToFloat(expr.(*IntegerLiteral).Value, e.Value))}, nil
+	case DecimalLiteralType:
+		rh := expr.(*DecimalLiteral).Value
+		return &DecimalLiteral{Value: newFloat(e.Value, rh).Sub(e.Value, rh)}, nil
+	case RationalLiteralType:
+		return &DecimalLiteral{Value: newFloat(e.Value, e.Value).Sub(e.Value, ratToFloat(expr.(*RationalLiteral).Value, e.Value))}, nil
+	case QuantityLiteralType:
+		return quantityOf(&e).Sub(expr)
+	case ComplexLiteralType:
+		return complexOf(&e, expr.(*ComplexLiteral).Re).Sub(expr)
+	case ArrayLiteralType:
+		return elementwise("Subtraction", &e, expr, subValues)
+	default:
+		return nil, unsupportedOperation("Decimal subtraction", &e, expr)
+	}
+}
+
+func (e DecimalLiteral) Mult(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType:
+		return &DecimalLiteral{Value: newFloat(e.Value, e.Value).Mul(e.Value, intToFloat(expr.(*IntegerLiteral).Value, e.Value))}, nil
+	case DecimalLiteralType:
+		rh := expr.(*DecimalLiteral).Value
+		return &DecimalLiteral{Value: newFloat(e.Value, rh).Mul(e.Value, rh)}, nil
+	case RationalLiteralType:
+		return &DecimalLiteral{Value: newFloat(e.Value, e.Value).Mul(e.Value, ratToFloat(expr.(*RationalLiteral).Value, e.Value))}, nil
+	case QuantityLiteralType:
+		return expr.(*QuantityLiteral).Mult(&e)
+	case ComplexLiteralType:
+		return complexOf(&e, expr.(*ComplexLiteral).Re).Mult(expr)
+	case MatrixLiteralType:
+		return expr.(*MatrixLiteral).Mult(&e)
+	case ArrayLiteralType:
+		return elementwise("Multiplication", &e, expr, multValues)
+	default:
+		return nil, unsupportedOperation("Decimal multiplication", &e, expr)
+	}
+}
+
+func (e DecimalLiteral) Div(expr Expression) (Expression, error) {
+	var rh *big.Float
+	switch expr.Type() {
+	case IntegerLiteralType:
+		rh = intToFloat(expr.(*IntegerLiteral).Value, e.Value)
+	case DecimalLiteralType:
+		rh = expr.(*DecimalLiteral).Value
+	case RationalLiteralType:
+		rh = ratToFloat(expr.(*RationalLiteral).Value, e.Value)
+	case QuantityLiteralType:
+		return quantityOf(&e).Div(expr)
+	case ComplexLiteralType:
+		return complexOf(&e, expr.(*ComplexLiteral).Re).Div(expr)
+	case ArrayLiteralType:
+		return elementwise("Division", &e, expr, divValues)
+	default:
+		return nil, unsupportedOperation("Decimal division", &e, expr)
+	}
+
+	if rh.Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+	return &DecimalLiteral{Value: newFloat(e.Value, rh).Quo(e.Value, rh)}, nil
+}
+
+func (e DecimalLiteral) Pow(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType:
+		return decimalPower(bigmath.PowInt(e.Value, expr.(*IntegerLiteral).Value))
+	case DecimalLiteralType:
+		return powFloat(e.Value, expr.(*DecimalLiteral).Value)
+	case RationalLiteralType:
+		return powFrac(e.Value, expr.(*RationalLiteral).Value)
+	case ComplexLiteralType:
+		return complexOf(&e, expr.(*ComplexLiteral).Re).Pow(expr)
+	case ArrayLiteralType:
+		return elementwise("Exponentiation", &e, expr, powValues)
+	default:
+		return nil, unsupportedOperation("Decimal exponentiation", &e, expr)
+	}
+}
+
+func (e RationalLiteral) Add(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType:
+		r := new(big.Rat).SetInt(expr.(*IntegerLiteral).Value)
+		return newRational(r.Add(e.Value, r)), nil
+	case RationalLiteralType:
+		return newRational(new(big.Rat).Add(e.Value, expr.(*RationalLiteral).Value)), nil
+	case DecimalLiteralType:
+		rh := expr.(*DecimalLiteral).Value
+		return &DecimalLiteral{Value: newFloat(rh, rh).Add(ratToFloat(e.Value, rh), rh)}, nil
+	case QuantityLiteralType:
+		return quantityOf(&e).Add(expr)
+	case ComplexLiteralType:
+		return complexOf(&e, expr.(*ComplexLiteral).Re).Add(expr)
+	case ArrayLiteralType:
+		return elementwise("Addition", &e, expr, addValues)
+	default:
+		return nil, unsupportedOperation("Rational addition", &e, expr)
+	}
+}
+
+func (e RationalLiteral) Sub(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType:
+		r := new(big.Rat).SetInt(expr.(*IntegerLiteral).Value)
+		return newRational(r.Sub(e.Value, r)), nil
+	case RationalLiteralType:
+		return newRational(new(big.Rat).Sub(e.Value, expr.(*RationalLiteral).Value)), nil
+	case DecimalLiteralType:
+		rh := expr.(*DecimalLiteral).Value
+		return &DecimalLiteral{Value: newFloat(rh, rh).Sub(ratToFloat(e.Value, rh), rh)}, nil
+	case QuantityLiteralType:
+		return quantityOf(&e).Sub(expr)
+	case ComplexLiteralType:
+		return complexOf(&e, expr.(*ComplexLiteral).Re).Sub(expr)
+	case ArrayLiteralType:
+		return elementwise("Subtraction", &e, expr, subValues)
+	default:
+		return nil, unsupportedOperation("Rational subtraction", &e, expr)
+	}
+}
+
+func (e RationalLiteral) Mult(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType:
+		r := new(big.Rat).SetInt(expr.(*IntegerLiteral).Value)
+		return newRational(r.Mul(e.Value, r)), nil
+	case RationalLiteralType:
+		return newRational(new(big.Rat).Mul(e.Value, expr.(*RationalLiteral).Value)), nil
+	case DecimalLiteralType:
+		rh := expr.(*DecimalLiteral).Value
+		return &DecimalLiteral{Value: newFloat(rh, rh).Mul(ratToFloat(e.Value, rh), rh)}, nil
+	case QuantityLiteralType:
+		return expr.(*QuantityLiteral).Mult(&e)
+	case ComplexLiteralType:
+		return complexOf(&e, expr.(*ComplexLiteral).Re).Mult(expr)
+	case MatrixLiteralType:
+		return expr.(*MatrixLiteral).Mult(&e)
+	case ArrayLiteralType:
+		return elementwise("Multiplication", &e, expr, multValues)
+	default:
+		return nil, unsupportedOperation("Rational multiplication", &e, expr)
+	}
+}
+
+func (e RationalLiteral) Div(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType:
+		rh := expr.(*IntegerLiteral).Value
+		if rh.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		r := new(big.Rat).SetInt(rh)
+		return newRational(r.Quo(e.Value, r)), nil
+	case RationalLiteralType:
+		return newRational(new(big.Rat).Quo(e.Value, expr.(*RationalLiteral).Value)), nil
+	case DecimalLiteralType:
+		rh := expr.(*DecimalLiteral).Value
+		if rh.Sign() == 0 {
+			return nil, ErrDivisionByZero
+		}
+		return &DecimalLiteral{Value: newFloat(rh, rh).Quo(ratToFloat(e.Value, rh), rh)}, nil
+	case QuantityLiteralType:
+		return quantityOf(&e).Div(expr)
+	case ComplexLiteralType:
+		return complexOf(&e, expr.(*ComplexLiteral).Re).Div(expr)
+	case ArrayLiteralType:
+		return elementwise("Division", &e, expr, divValues)
+	default:
+		return nil, unsupportedOperation("Rational division", &e, expr)
+	}
+}
+
+func (e RationalLiteral) Pow(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType:
+		return powRat(e.Value, expr.(*IntegerLiteral).Value)
+	case RationalLiteralType:
+		return PowFraction(e.Value, expr.(*RationalLiteral).Value, 0)
+	case DecimalLiteralType:
+		rh := expr.(*DecimalLiteral).Value
+		return powFloat(ratToFloat(e.Value, rh), rh)
+	case ComplexLiteralType:
+		return complexOf(&e, expr.(*ComplexLiteral).Re).Pow(expr)
+	case ArrayLiteralType:
+		return elementwise("Exponentiation", &e, expr, powValues)
+	default:
+		return nil, unsupportedOperation("Rational exponentiation", &e, expr)
+	}
+}
+
+// Add concatenates strings. Other values are not converted implicitly.
+func (e StringLiteral) Add(expr Expression) (Expression, error) {
+	rh, ok := expr.(*StringLiteral)
+	if !ok {
+		return nil, unsupportedOperation("String concatenation", &e, expr)
+	}
+	return &StringLiteral{Value: e.Value + rh.Value}, nil
+}
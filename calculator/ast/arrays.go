@@ -0,0 +1,161 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ArrayLiteral represents an ordered list of values
+type ArrayLiteral struct {
+	Span
+
+	Elements []Expression
+}
+
+func (e ArrayLiteral) Type() ExpressionType { return ArrayLiteralType }
+func (e ArrayLiteral) String() string {
+	elements := make([]string, len(e.Elements))
+	for i, el := range e.Elements {
+		elements[i] = el.String()
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// IndexExpression represents `a[i]`. Negative indices count from the end.
+type IndexExpression struct {
+	Span
+
+	Expr  Expression
+	Index Expression
+}
+
+func (e IndexExpression) Type() ExpressionType { return IndexExpressionType }
+func (e IndexExpression) String() string {
+	return fmt.Sprintf("%s[%s]", e.Expr.String(), e.Index.String())
+}
+
+// SliceExpression represents `a[start:end]` where either bound may be omitted
+type SliceExpression struct {
+	Span
+
+	Expr  Expression
+	Start Expression
+	End   Expression
+}
+
+func (e SliceExpression) Type() ExpressionType { return SliceExpressionType }
+func (e SliceExpression) String() string {
+	var start, end string
+	if e.Start != nil {
+		start = e.Start.String()
+	}
+	if e.End != nil {
+		end = e.End.String()
+	}
+	return fmt.Sprintf("%s[%s:%s]", e.Expr.String(), start, end)
+}
+
+// LengthError is returned by element-wise operations on arrays of different
+// lengths.
+type LengthError struct {
+	Op          string
+	Left, Right int
+}
+
+// Error returns the string representation of the error.
+func (e *LengthError) Error() string {
+	return fmt.Sprintf("%s of arrays with mismatched lengths %d and %d", e.Op, e.Left, e.Right)
+}
+
+// elementwise applies the operation to each pair of elements of two arrays
+// of the same length, or broadcasts a scalar operand over every element of
+// the other operand.
+func elementwise(op string, lh, rh Expression, fn func(lh, rh Expression) (Expression, error)) (Expression, error) {
+	la, lok := lh.(*ArrayLiteral)
+	ra, rok := rh.(*ArrayLiteral)
+	if lok && rok && len(la.Elements) != len(ra.Elements) {
+		return nil, &LengthError{Op: op, Left: len(la.Elements), Right: len(ra.Elements)}
+	}
+
+	var n int
+	if lok {
+		n = len(la.Elements)
+	} else {
+		n = len(ra.Elements)
+	}
+
+	elements := make([]Expression, n)
+	for i := range elements {
+		x, y := lh, rh
+		if lok {
+			x = la.Elements[i]
+		}
+		if rok {
+			y = ra.Elements[i]
+		}
+
+		var err error
+		if elements[i], err = fn(x, y); err != nil {
+			return nil, err
+		}
+	}
+	return &ArrayLiteral{Elements: elements}, nil
+}
+
+func (e ArrayLiteral) Add(expr Expression) (Expression, error) {
+	return elementwise("Addition", &e, expr, addValues)
+}
+
+func (e ArrayLiteral) Sub(expr Expression) (Expression, error) {
+	return elementwise("Subtraction", &e, expr, subValues)
+}
+
+func (e ArrayLiteral) Mult(expr Expression) (Expression, error) {
+	if m, ok := expr.(*MatrixLiteral); ok {
+		return m.multVector(&e)
+	}
+	return elementwise("Multiplication", &e, expr, multValues)
+}
+
+func (e ArrayLiteral) Div(expr Expression) (Expression, error) {
+	return elementwise("Division", &e, expr, divValues)
+}
+
+func (e ArrayLiteral) Pow(expr Expression) (Expression, error) {
+	return elementwise("Exponentiation", &e, expr, powValues)
+}
+
+// equal returns true if both arrays have the same length and equal elements.
+// Arrays are compared as values, so `[1, 2] == [1, 2]` is a single boolean.
+func (e ArrayLiteral) equal(expr Expression) (bool, error) {
+	rh, ok := expr.(*ArrayLiteral)
+	if !ok {
+		return false, unsupportedOperation("Array comparison", &e, expr)
+	} else if len(e.Elements) != len(rh.Elements) {
+		return false, nil
+	}
+	for i, el := range e.Elements {
+		eq, ok := el.(EqualExpression)
+		if !ok {
+			return false, unsupportedOperation("Array comparison", el, rh.Elements[i])
+		}
+		result, err := eq.Equal(rh.Elements[i])
+		if err != nil {
+			return false, err
+		}
+		if b, ok := result.(*BooleanLiteral); !ok || !b.Value {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (e ArrayLiteral) Equal(expr Expression) (Expression, error) {
+	eq, err := e.equal(expr)
+	return newBoolean(eq, err)
+}
+
+func (e ArrayLiteral) NotEqual(expr Expression) (Expression, error) {
+	eq, err := e.equal(expr)
+	return newBoolean(!eq, err)
+}
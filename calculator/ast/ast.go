@@ -1,8 +1,6 @@
 package ast
 
 import (
-	"errors"
-	"fmt"
 	"github.com/eliquious/lexer"
 	"math/big"
 	"strconv"
@@ -22,6 +20,8 @@ const (
 	AttributeDeclarationType
 	ArrayDeclarationType
 	EnumDeclarationType
+	ConversionDeclarationType
+	TypeDeclarationType
 
 	ImportExpressionType
 	ConversionExpressionType
@@ -31,10 +31,22 @@ const (
 	CallFunctionExpressionType
 	FilterExpressionType
 	ForExpressionType
+	MatchExpressionType
 
 	AssignmentExpressionType
 	BinaryExpressionType
 	UnaryExpressionType
+	IdentifierExpressionType
+	GroupExpressionType
+	QuantityExpressionType
+	UnitExpressionType
+	BlockExpressionType
+	IndexExpressionType
+	SliceExpressionType
+	LambdaExpressionType
+	StructExpressionType
+	FieldExpressionType
+	MethodCallExpressionType
 
 	IntegerLiteralType
 	DecimalLiteralType
@@ -43,14 +55,24 @@ const (
 	TimestampLiteralType
 	BooleanLiteralType
 	StructLiteralType
+	EnumLiteralType
 	ConversionLiteralType
 	ArrayLiteralType
+	RationalLiteralType
+	BuiltinFunctionType
+	QuantityLiteralType
+	ComplexLiteralType
+	MatrixLiteralType
+	FunctionType
+	NilLiteralType
+	ModuleType
 )
 
 // Expression represents AST expressions
 type Expression interface {
 	Type() ExpressionType
 	String() string
+	Position() Span
 }
 
 // IsLiteral returns true for literal expressions
@@ -60,6 +82,12 @@ func IsLiteral(expr Expression) bool {
 		return true
 	case DecimalLiteralType:
 		return true
+	case RationalLiteralType:
+		return true
+	case QuantityLiteralType:
+		return true
+	case ComplexLiteralType:
+		return true
 	case BooleanLiteralType:
 		return true
 	case StringLiteralType:
@@ -71,6 +99,22 @@ func IsLiteral(expr Expression) bool {
 	}
 }
 
+// IsNumber returns true if the expression is a unitless numeric literal.
+func IsNumber(expr Expression) bool {
+	switch expr.Type() {
+	case IntegerLiteralType, DecimalLiteralType, RationalLiteralType:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsNumeric returns true if the expression is a unitless real or complex
+// numeric literal.
+func IsNumeric(expr Expression) bool {
+	return IsNumber(expr) || expr.Type() == ComplexLiteralType
+}
+
 // IsUnaryOperator returns true for unary operators
 func IsUnaryOperator(tok lexer.Token) bool {
 	if tok == lexer.PLUSPLUS || tok == lexer.MINUSMINUS {
@@ -91,6 +135,8 @@ func IsBinaryOperator(tok lexer.Token) bool {
 
 // IntegerLiteral represents literal integers
 type IntegerLiteral struct {
+	Span
+
 	Value *big.Int
 }
 
@@ -101,17 +147,28 @@ func (e IntegerLiteral) Add(expr Expression) (Expression, error) {
 	switch expr.Type() {
 	case IntegerLiteralType:
 		i := new(big.Int)
-		return &IntegerLiteral{i.Add(e.Value, expr.(*IntegerLiteral).Value)}, nil
+		return &IntegerLiteral{Value: i.Add(e.Value, expr.(*IntegerLiteral).Value)}, nil
 	case DecimalLiteralType:
 		f := new(big.Float).SetInt(e.Value)
-		return &DecimalLiteral{f.Add(f, expr.(*DecimalLiteral).Value)}, nil
+		return &DecimalLiteral{Value: f.Add(f, expr.(*DecimalLiteral).Value)}, nil
+	case RationalLiteralType:
+		r := new(big.Rat).SetInt(e.Value)
+		return newRational(r.Add(r, expr.(*RationalLiteral).Value)), nil
+	case QuantityLiteralType:
+		return quantityOf(&e).Add(expr)
+	case ComplexLiteralType:
+		return complexOf(&e, expr.(*ComplexLiteral).Re).Add(expr)
+	case ArrayLiteralType:
+		return elementwise("Addition", &e, expr, addValues)
 	default:
-		return nil, errors.New(fmt.Sprintf("Integer addition of type '%T' unsupported", expr.Type()))
+		return nil, unsupportedOperation("Integer addition", &e, expr)
 	}
 }
 
 // DecimalLiteral represents literal decimals
 type DecimalLiteral struct {
+	Span
+
 	Value *big.Float
 }
 
@@ -122,25 +179,62 @@ func (e DecimalLiteral) Add(expr Expression) (Expression, error) {
 	switch expr.Type() {
 	case IntegerLiteralType:
 		f := new(big.Float).SetInt(expr.(*IntegerLiteral).Value)
-		return &DecimalLiteral{f.Add(f, e.Value)}, nil
+		return &DecimalLiteral{Value: f.Add(f, e.Value)}, nil
 	case DecimalLiteralType:
 		f := new(big.Float)
-		return &DecimalLiteral{f.Add(e.Value, expr.(*DecimalLiteral).Value)}, nil
+		return &DecimalLiteral{Value: f.Add(e.Value, expr.(*DecimalLiteral).Value)}, nil
+	case RationalLiteralType:
+		f := ratToFloat(expr.(*RationalLiteral).Value, e.Value)
+		return &DecimalLiteral{Value: f.Add(f, e.Value)}, nil
+	case QuantityLiteralType:
+		return quantityOf(&e).Add(expr)
+	case ComplexLiteralType:
+		return complexOf(&e, expr.(*ComplexLiteral).Re).Add(expr)
+	case ArrayLiteralType:
+		return elementwise("Addition", &e, expr, addValues)
 	default:
-		return nil, errors.New(fmt.Sprintf("Decimal addition of type '%T' unsupported", expr.Type()))
+		return nil, unsupportedOperation("Decimal addition", &e, expr)
 	}
 }
 
+// RationalLiteral represents exact fractions. Results with a denominator of
+// one are always reduced to an IntegerLiteral.
+//
+// Numbers are promoted from integers to fractions to decimals. Arithmetic on
+// integers and fractions is exact, except for fractional exponents, while an
+// operation with a decimal operand is computed with the precision of the
+// decimal and returns a decimal.
+type RationalLiteral struct {
+	Span
+
+	Value *big.Rat
+}
+
+func (e RationalLiteral) Type() ExpressionType { return RationalLiteralType }
+func (e RationalLiteral) String() string       { return e.Value.RatString() }
+
 // BooleanLiteral represents literal booleans
 type BooleanLiteral struct {
+	Span
+
 	Value bool
 }
 
 func (e BooleanLiteral) Type() ExpressionType { return BooleanLiteralType }
 func (e BooleanLiteral) String() string       { return strconv.FormatBool(e.Value) }
 
+// NilLiteral is the value of an if expression when no branch is taken
+type NilLiteral struct {
+	Span
+}
+
+func (e NilLiteral) Type() ExpressionType { return NilLiteralType }
+func (e NilLiteral) String() string       { return "nil" }
+
 // StringLiteral represents literal strings
 type StringLiteral struct {
+	Span
+
 	Value string
 }
 
@@ -149,6 +243,8 @@ func (e StringLiteral) String() string       { return strconv.Quote(e.Value) }
 
 // DurationLiteral represents literal durations
 type DurationLiteral struct {
+	Span
+
 	Value time.Duration
 }
 
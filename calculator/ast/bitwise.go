@@ -0,0 +1,123 @@
+package ast
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	// ErrNegativeShift is returned when shifting by a negative amount
+	ErrNegativeShift = errors.New("negative shift amount")
+
+	// ErrShiftTooLarge is returned when a left shift would exceed
+	// maxPowerBits
+	ErrShiftTooLarge = errors.New("shift amount too large")
+)
+
+// IntegerOperandError is returned when a bitwise operation is applied to a
+// value which is not an integer.
+type IntegerOperandError struct {
+	Operation string
+	Value     Expression
+}
+
+// Error returns the string representation of the error.
+func (e *IntegerOperandError) Error() string {
+	return fmt.Sprintf("%s requires integers, found %s %s", e.Operation, OperandKind(e.Value), operandText(e.Value))
+}
+
+// BitwiseOperandError returns the error for a bitwise operation whose
+// operands are not both integers. The first operand which is not an integer
+// is reported.
+func BitwiseOperandError(operation string, lh, rh Expression) error {
+	if _, ok := lh.(*IntegerLiteral); !ok {
+		return &IntegerOperandError{Operation: operation, Value: lh}
+	}
+	return &IntegerOperandError{Operation: operation, Value: rh}
+}
+
+// operandText returns the value of an operand as written in a worksheet.
+// Decimals are shown in their shortest form with a decimal point.
+func operandText(value Expression) string {
+	d, ok := value.(*DecimalLiteral)
+	if !ok {
+		return value.String()
+	}
+	text := d.Value.Text('g', -1)
+	if !strings.ContainsAny(text, ".eInf") {
+		text += ".0"
+	}
+	return text
+}
+
+// integerOperand returns the value of an integer operand of a bitwise
+// operation.
+func integerOperand(operation string, lh, rh Expression) (*big.Int, error) {
+	if i, ok := rh.(*IntegerLiteral); ok {
+		return i.Value, nil
+	}
+	return nil, BitwiseOperandError(operation, lh, rh)
+}
+
+// shiftOperand returns the amount of a shift operation.
+func shiftOperand(operation string, lh, rh Expression) (*big.Int, error) {
+	n, err := integerOperand(operation, lh, rh)
+	if err != nil {
+		return nil, err
+	} else if n.Sign() < 0 {
+		return nil, ErrNegativeShift
+	}
+	return n, nil
+}
+
+func (e IntegerLiteral) Ampersand(expr Expression) (Expression, error) {
+	rh, err := integerOperand("bitwise AND", &e, expr)
+	if err != nil {
+		return nil, err
+	}
+	return &IntegerLiteral{Value: new(big.Int).And(e.Value, rh)}, nil
+}
+
+func (e IntegerLiteral) Xor(expr Expression) (Expression, error) {
+	rh, err := integerOperand("bitwise XOR", &e, expr)
+	if err != nil {
+		return nil, err
+	}
+	return &IntegerLiteral{Value: new(big.Int).Xor(e.Value, rh)}, nil
+}
+
+func (e IntegerLiteral) Pipe(expr Expression) (Expression, error) {
+	rh, err := integerOperand("bitwise OR", &e, expr)
+	if err != nil {
+		return nil, err
+	}
+	return &IntegerLiteral{Value: new(big.Int).Or(e.Value, rh)}, nil
+}
+
+// LShift limits the shift amount to maxPowerBits.
+func (e IntegerLiteral) LShift(expr Expression) (Expression, error) {
+	n, err := shiftOperand("left shift", &e, expr)
+	if err != nil {
+		return nil, err
+	} else if !n.IsInt64() || n.Int64() > maxPowerBits {
+		return nil, ErrShiftTooLarge
+	}
+	return &IntegerLiteral{Value: new(big.Int).Lsh(e.Value, uint(n.Int64()))}, nil
+}
+
+// RShift shifts arithmetically so negative values round towards negative
+// infinity. Shifting by the length of the value or more gives 0 or -1.
+func (e IntegerLiteral) RShift(expr Expression) (Expression, error) {
+	n, err := shiftOperand("right shift", &e, expr)
+	if err != nil {
+		return nil, err
+	}
+
+	shift := uint(e.Value.BitLen())
+	if n.IsInt64() && n.Int64() < int64(shift) {
+		shift = uint(n.Int64())
+	}
+	return &IntegerLiteral{Value: new(big.Int).Rsh(e.Value, shift)}, nil
+}
@@ -0,0 +1,314 @@
+package ast
+
+import "math/big"
+
+// ToRat converts a finite numeric literal to an exact fraction.
+func ToRat(expr Expression) (*big.Rat, bool) {
+	switch e := expr.(type) {
+	case *IntegerLiteral:
+		return new(big.Rat).SetInt(e.Value), true
+	case *RationalLiteral:
+		return e.Value, true
+	case *DecimalLiteral:
+		if e.Value.IsInf() {
+			return nil, false
+		}
+		r, _ := e.Value.Rat(nil)
+		return r, true
+	default:
+		return nil, false
+	}
+}
+
+// toFloat converts a numeric literal to a float.
+func toFloat(expr Expression) (*big.Float, bool) {
+	switch e := expr.(type) {
+	case *IntegerLiteral:
+		return new(big.Float).SetInt(e.Value), true
+	case *RationalLiteral:
+		return new(big.Float).SetRat(e.Value), true
+	case *DecimalLiteral:
+		return e.Value, true
+	default:
+		return nil, false
+	}
+}
+
+// compareNumbers compares numeric literals of any type exactly, except for
+// fractions compared with decimals. Infinite decimals are compared as floats
+// and quantities must be dimensionless.
+func compareNumbers(lh, rh Expression) (int, error) {
+	if q, ok := rh.(*QuantityLiteral); ok {
+		return quantityOf(lh).compare(q)
+	}
+
+	// Fractions are rounded to the precision of a decimal they are compared
+	// with, so that 0.1 == 1/10 as 0.1 is the nearest decimal to a tenth
+	if d, ok := lh.(*DecimalLiteral); ok && !d.Value.IsInf() {
+		if r, ok := rh.(*RationalLiteral); ok {
+			return d.Value.Cmp(ratToFloat(r.Value, d.Value)), nil
+		}
+	} else if d, ok := rh.(*DecimalLiteral); ok && !d.Value.IsInf() {
+		if r, ok := lh.(*RationalLiteral); ok {
+			return ratToFloat(r.Value, d.Value).Cmp(d.Value), nil
+		}
+	}
+
+	if lr, ok := ToRat(lh); ok {
+		if rr, ok := ToRat(rh); ok {
+			return lr.Cmp(rr), nil
+		}
+	}
+
+	lf, lok := toFloat(lh)
+	rf, rok := toFloat(rh)
+	if !lok || !rok {
+		return 0, unsupportedOperation("Numeric comparison", lh, rh)
+	}
+	return lf.Cmp(rf), nil
+}
+
+// equalNumbers compares numeric literals of any type, including complex
+// numbers which are only equal to numbers with the same parts.
+func equalNumbers(lh, rh Expression) (bool, error) {
+	if q, ok := rh.(*QuantityLiteral); ok {
+		return quantityOf(lh).equal(q)
+	}
+
+	lc, lok := lh.(*ComplexLiteral)
+	rc, rok := rh.(*ComplexLiteral)
+	if !lok && !rok {
+		cmp, err := compareNumbers(lh, rh)
+		return cmp == 0, err
+	} else if lok {
+		rc = complexOf(rh, lc.Re)
+	} else {
+		lc = complexOf(lh, rc.Re)
+	}
+	if lc == nil || rc == nil {
+		return false, unsupportedOperation("Numeric comparison", lh, rh)
+	}
+	return lc.Re.Cmp(rc.Re) == 0 && lc.Im.Cmp(rc.Im) == 0, nil
+}
+
+// newBoolean returns the result of a comparison.
+func newBoolean(value bool, err error) (Expression, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &BooleanLiteral{Value: value}, nil
+}
+
+func (e IntegerLiteral) compare(expr Expression) (int, error)  { return compareNumbers(&e, expr) }
+func (e DecimalLiteral) compare(expr Expression) (int, error)  { return compareNumbers(&e, expr) }
+func (e RationalLiteral) compare(expr Expression) (int, error) { return compareNumbers(&e, expr) }
+
+func (e StringLiteral) compare(expr Expression) (int, error) {
+	rh, ok := expr.(*StringLiteral)
+	if !ok {
+		return 0, unsupportedOperation("String comparison", &e, expr)
+	} else if e.Value < rh.Value {
+		return -1, nil
+	} else if e.Value > rh.Value {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (e DurationLiteral) compare(expr Expression) (int, error) {
+	rh, ok := expr.(*DurationLiteral)
+	if !ok {
+		return 0, unsupportedOperation("Duration comparison", &e, expr)
+	} else if e.Value < rh.Value {
+		return -1, nil
+	} else if e.Value > rh.Value {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (e BooleanLiteral) compare(expr Expression) (int, error) {
+	rh, ok := expr.(*BooleanLiteral)
+	if !ok {
+		return 0, unsupportedOperation("Boolean comparison", &e, expr)
+	} else if e.Value == rh.Value {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+func (e BooleanLiteral) And(expr Expression) (Expression, error) {
+	rh, ok := expr.(*BooleanLiteral)
+	if !ok {
+		return nil, unsupportedOperation("Logical AND", &e, expr)
+	}
+	return &BooleanLiteral{Value: e.Value && rh.Value}, nil
+}
+
+func (e BooleanLiteral) Or(expr Expression) (Expression, error) {
+	rh, ok := expr.(*BooleanLiteral)
+	if !ok {
+		return nil, unsupportedOperation("Logical OR", &e, expr)
+	}
+	return &BooleanLiteral{Value: e.Value || rh.Value}, nil
+}
+
+func (e IntegerLiteral) Equal(expr Expression) (Expression, error) {
+	eq, err := equalNumbers(&e, expr)
+	return newBoolean(eq, err)
+}
+
+func (e IntegerLiteral) NotEqual(expr Expression) (Expression, error) {
+	eq, err := equalNumbers(&e, expr)
+	return newBoolean(!eq, err)
+}
+
+func (e IntegerLiteral) LessThan(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp < 0, err)
+}
+
+func (e IntegerLiteral) LessThanOrEqualTo(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp <= 0, err)
+}
+
+func (e IntegerLiteral) GreaterThan(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp > 0, err)
+}
+
+func (e IntegerLiteral) GreaterThanOrEqualTo(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp >= 0, err)
+}
+
+func (e DecimalLiteral) Equal(expr Expression) (Expression, error) {
+	eq, err := equalNumbers(&e, expr)
+	return newBoolean(eq, err)
+}
+
+func (e DecimalLiteral) NotEqual(expr Expression) (Expression, error) {
+	eq, err := equalNumbers(&e, expr)
+	return newBoolean(!eq, err)
+}
+
+func (e DecimalLiteral) LessThan(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp < 0, err)
+}
+
+func (e DecimalLiteral) LessThanOrEqualTo(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp <= 0, err)
+}
+
+func (e DecimalLiteral) GreaterThan(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp > 0, err)
+}
+
+func (e DecimalLiteral) GreaterThanOrEqualTo(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp >= 0, err)
+}
+
+func (e RationalLiteral) Equal(expr Expression) (Expression, error) {
+	eq, err := equalNumbers(&e, expr)
+	return newBoolean(eq, err)
+}
+
+func (e RationalLiteral) NotEqual(expr Expression) (Expression, error) {
+	eq, err := equalNumbers(&e, expr)
+	return newBoolean(!eq, err)
+}
+
+func (e RationalLiteral) LessThan(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp < 0, err)
+}
+
+func (e RationalLiteral) LessThanOrEqualTo(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp <= 0, err)
+}
+
+func (e RationalLiteral) GreaterThan(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp > 0, err)
+}
+
+func (e RationalLiteral) GreaterThanOrEqualTo(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp >= 0, err)
+}
+
+func (e StringLiteral) Equal(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp == 0, err)
+}
+
+func (e StringLiteral) NotEqual(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp != 0, err)
+}
+
+func (e StringLiteral) LessThan(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp < 0, err)
+}
+
+func (e StringLiteral) LessThanOrEqualTo(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp <= 0, err)
+}
+
+func (e StringLiteral) GreaterThan(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp > 0, err)
+}
+
+func (e StringLiteral) GreaterThanOrEqualTo(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp >= 0, err)
+}
+
+func (e DurationLiteral) Equal(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp == 0, err)
+}
+
+func (e DurationLiteral) NotEqual(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp != 0, err)
+}
+
+func (e DurationLiteral) LessThan(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp < 0, err)
+}
+
+func (e DurationLiteral) LessThanOrEqualTo(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp <= 0, err)
+}
+
+func (e DurationLiteral) GreaterThan(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp > 0, err)
+}
+
+func (e DurationLiteral) GreaterThanOrEqualTo(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp >= 0, err)
+}
+
+func (e BooleanLiteral) Equal(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp == 0, err)
+}
+
+func (e BooleanLiteral) NotEqual(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp != 0, err)
+}
@@ -0,0 +1,229 @@
+package ast
+
+import (
+	"math/big"
+
+	"github.com/eliquious/aechbar/calculator/bigmath"
+)
+
+// ComplexLiteral represents complex numbers with decimal parts of the same
+// precision. Real numbers are promoted to complex numbers when combined with
+// one and results remain complex when their imaginary part is zero.
+type ComplexLiteral struct {
+	Span
+
+	Re, Im *big.Float
+}
+
+func (e ComplexLiteral) Type() ExpressionType { return ComplexLiteralType }
+
+// String returns imaginary numbers as parsed, such as `3.0E+00i`, and other
+// complex numbers in parentheses so that they read as one operand within
+// expressions.
+func (e ComplexLiteral) String() string {
+	if e.Re.Sign() == 0 {
+		return e.Im.Text('E', 16) + "i"
+	}
+	return "(" + e.Text(16) + ")"
+}
+
+// Text returns the complex number as `re + im i` with the number of digits
+// after the decimal point of each part.
+func (e ComplexLiteral) Text(digits int) string {
+	im, sign := e.Im, " + "
+	if im.Signbit() {
+		im, sign = new(big.Float).Neg(im), " - "
+	}
+	return e.Re.Text('E', digits) + sign + im.Text('E', digits) + "i"
+}
+
+// complexOf converts a number to a complex number using the precision of ref
+// for integers and fractions. It returns nil for values which are not
+// numbers.
+func complexOf(expr Expression, ref *big.Float) *ComplexLiteral {
+	var re *big.Float
+	switch v := expr.(type) {
+	case *ComplexLiteral:
+		return v
+	case *IntegerLiteral:
+		re = intToFloat(v.Value, ref)
+	case *RationalLiteral:
+		re = ratToFloat(v.Value, ref)
+	case *DecimalLiteral:
+		re = v.Value
+	default:
+		return nil
+	}
+	return &ComplexLiteral{Re: re, Im: newFloat(re, re)}
+}
+
+// mulComplex returns x·y. The products are exact so each part is rounded
+// once.
+func mulComplex(x, y *ComplexLiteral) *ComplexLiteral {
+	wide := 2 * newFloat(x.Re, y.Re).Prec()
+	ac := new(big.Float).SetPrec(wide).Mul(x.Re, y.Re)
+	bd := new(big.Float).SetPrec(wide).Mul(x.Im, y.Im)
+	ad := new(big.Float).SetPrec(wide).Mul(x.Re, y.Im)
+	bc := new(big.Float).SetPrec(wide).Mul(x.Im, y.Re)
+	return &ComplexLiteral{Re: newFloat(x.Re, y.Re).Sub(ac, bd), Im: newFloat(x.Re, y.Re).Add(ad, bc)}
+}
+
+// quoComplex returns x/y = x·conj(y) / |y|².
+func quoComplex(x, y *ComplexLiteral) (*ComplexLiteral, error) {
+	if y.Re.Sign() == 0 && y.Im.Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+	wide := 2 * newFloat(x.Re, y.Re).Prec()
+	den := new(big.Float).SetPrec(wide).Mul(y.Re, y.Re)
+	den.Add(den, new(big.Float).SetPrec(wide).Mul(y.Im, y.Im))
+
+	z := mulComplex(x, &ComplexLiteral{Re: y.Re, Im: new(big.Float).Neg(y.Im)})
+	re := new(big.Float).SetPrec(wide).Set(z.Re)
+	im := new(big.Float).SetPrec(wide).Set(z.Im)
+	return &ComplexLiteral{Re: newFloat(x.Re, y.Re).Quo(re, den), Im: newFloat(x.Re, y.Re).Quo(im, den)}, nil
+}
+
+// powComplexInt returns z**n by binary exponentiation.
+func powComplexInt(z *ComplexLiteral, n int64) (*ComplexLiteral, error) {
+	one := newFloat(z.Re, z.Re).SetInt64(1)
+	result := &ComplexLiteral{Re: one, Im: newFloat(z.Re, z.Re)}
+	base := z
+	for e := n; e != 0; e /= 2 {
+		if e%2 != 0 {
+			result = mulComplex(result, base)
+		}
+		base = mulComplex(base, base)
+	}
+	if n < 0 {
+		return quoComplex(&ComplexLiteral{Re: one, Im: newFloat(z.Re, z.Re)}, result)
+	}
+	return result, nil
+}
+
+func (e ComplexLiteral) Add(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType, DecimalLiteralType, RationalLiteralType, ComplexLiteralType:
+		rh := complexOf(expr, e.Re)
+		return &ComplexLiteral{Re: newFloat(e.Re, rh.Re).Add(e.Re, rh.Re), Im: newFloat(e.Re, rh.Re).Add(e.Im, rh.Im)}, nil
+	case QuantityLiteralType:
+		return quantityOf(&e).Add(expr)
+	case ArrayLiteralType:
+		return elementwise("Addition", &e, expr, addValues)
+	default:
+		return nil, unsupportedOperation("Complex addition", &e, expr)
+	}
+}
+
+func (e ComplexLiteral) Sub(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType, DecimalLiteralType, RationalLiteralType, ComplexLiteralType:
+		rh := complexOf(expr, e.Re)
+		return &ComplexLiteral{Re: newFloat(e.Re, rh.Re).Sub(e.Re, rh.Re), Im: newFloat(e.Re, rh.Re).Sub(e.Im, rh.Im)}, nil
+	case QuantityLiteralType:
+		return quantityOf(&e).Sub(expr)
+	case ArrayLiteralType:
+		return elementwise("Subtraction", &e, expr, subValues)
+	default:
+		return nil, unsupportedOperation("Complex subtraction", &e, expr)
+	}
+}
+
+func (e ComplexLiteral) Mult(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType, DecimalLiteralType, RationalLiteralType, ComplexLiteralType:
+		return mulComplex(&e, complexOf(expr, e.Re)), nil
+	case QuantityLiteralType:
+		return expr.(*QuantityLiteral).Mult(&e)
+	case MatrixLiteralType:
+		return expr.(*MatrixLiteral).Mult(&e)
+	case ArrayLiteralType:
+		return elementwise("Multiplication", &e, expr, multValues)
+	default:
+		return nil, unsupportedOperation("Complex multiplication", &e, expr)
+	}
+}
+
+func (e ComplexLiteral) Div(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType, DecimalLiteralType, RationalLiteralType, ComplexLiteralType:
+		return quoComplex(&e, complexOf(expr, e.Re))
+	case QuantityLiteralType:
+		return quantityOf(&e).Div(expr)
+	case ArrayLiteralType:
+		return elementwise("Division", &e, expr, divValues)
+	default:
+		return nil, unsupportedOperation("Complex division", &e, expr)
+	}
+}
+
+// Pow raises the complex number to a power. Integer powers are computed by
+// multiplication, square roots directly and other powers from the principal
+// logarithm, so that `(-4 + 0i) ** 0.5` is 2i.
+func (e ComplexLiteral) Pow(expr Expression) (Expression, error) {
+	switch expr.Type() {
+	case IntegerLiteralType:
+		if n := expr.(*IntegerLiteral).Value; n.IsInt64() {
+			return powComplexInt(&e, n.Int64())
+		}
+	case RationalLiteralType, DecimalLiteralType:
+		if r, ok := ToRat(expr); ok && r.Cmp(big.NewRat(1, 2)) == 0 {
+			re, im, err := bigmath.CSqrt(e.Re, e.Im)
+			if err != nil {
+				return nil, err
+			}
+			return &ComplexLiteral{Re: re, Im: im}, nil
+		}
+	case ComplexLiteralType:
+		// Computed from the principal logarithm below
+	case ArrayLiteralType:
+		return elementwise("Exponentiation", &e, expr, powValues)
+	default:
+		return nil, unsupportedOperation("Complex exponentiation", &e, expr)
+	}
+
+	rh := complexOf(expr, e.Re)
+	re, im, err := bigmath.CPow(e.Re, e.Im, rh.Re, rh.Im)
+	if err != nil {
+		return nil, err
+	}
+	return &ComplexLiteral{Re: re, Im: im}, nil
+}
+
+// Conj returns the complex conjugate.
+func (e ComplexLiteral) Conj() *ComplexLiteral {
+	return &ComplexLiteral{Re: e.Re, Im: new(big.Float).Neg(e.Im)}
+}
+
+// Abs returns the magnitude of the complex number.
+func (e ComplexLiteral) Abs() *big.Float {
+	return bigmath.Hypot(e.Re, e.Im)
+}
+
+// Arg returns the argument of the complex number in the range [-π, π].
+func (e ComplexLiteral) Arg() *big.Float {
+	z, _ := bigmath.Atan2(e.Im, e.Re)
+	return z
+}
+
+// NewPolar returns the complex number with the magnitude and argument.
+func NewPolar(abs, arg *big.Float) (*ComplexLiteral, error) {
+	cos, err := bigmath.Cos(arg)
+	if err != nil {
+		return nil, err
+	}
+	sin, err := bigmath.Sin(arg)
+	if err != nil {
+		return nil, err
+	}
+	return &ComplexLiteral{Re: newFloat(abs, arg).Mul(abs, cos), Im: newFloat(abs, arg).Mul(abs, sin)}, nil
+}
+
+func (e ComplexLiteral) Equal(expr Expression) (Expression, error) {
+	eq, err := equalNumbers(&e, expr)
+	return newBoolean(eq, err)
+}
+
+func (e ComplexLiteral) NotEqual(expr Expression) (Expression, error) {
+	eq, err := equalNumbers(&e, expr)
+	return newBoolean(!eq, err)
+}
@@ -0,0 +1,72 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IfExpression represents `if cond { ... }` with an optional else branch.
+// The else branch is either another IfExpression or an ElseExpression.
+type IfExpression struct {
+	Span
+
+	Condition Expression
+	Body      *BlockExpression
+	Else      Expression
+}
+
+func (e IfExpression) Type() ExpressionType { return IfExpressionType }
+func (e IfExpression) String() string {
+	s := fmt.Sprintf("if %s %s", e.Condition.String(), e.Body.String())
+	if e.Else != nil {
+		s += " else " + e.Else.String()
+	}
+	return s
+}
+
+// ElseExpression represents the final `else { ... }` branch
+type ElseExpression struct {
+	Span
+
+	Body *BlockExpression
+}
+
+func (e ElseExpression) Type() ExpressionType { return ElseExpressionType }
+func (e ElseExpression) String() string       { return e.Body.String() }
+
+// MatchExpression represents `match x { A -> 1; B, C -> 2; else -> 3 }`,
+// which evaluates the body of the first arm with a pattern equal to the
+// subject. Matches on an enum without an else arm must list every member.
+type MatchExpression struct {
+	Span
+
+	Subject Expression
+	Arms    []*MatchArm
+	Else    Expression
+}
+
+func (e MatchExpression) Type() ExpressionType { return MatchExpressionType }
+func (e MatchExpression) String() string {
+	arms := make([]string, len(e.Arms))
+	for i, arm := range e.Arms {
+		arms[i] = arm.String()
+	}
+	if e.Else != nil {
+		arms = append(arms, "else -> "+e.Else.String())
+	}
+	return fmt.Sprintf("match %s { %s }", e.Subject.String(), strings.Join(arms, "; "))
+}
+
+// MatchArm is an arm of a match expression with one or more patterns.
+type MatchArm struct {
+	Patterns []Expression
+	Body     Expression
+}
+
+func (a MatchArm) String() string {
+	patterns := make([]string, len(a.Patterns))
+	for i, p := range a.Patterns {
+		patterns[i] = p.String()
+	}
+	return strings.Join(patterns, ", ") + " -> " + a.Body.String()
+}
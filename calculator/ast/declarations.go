@@ -0,0 +1,41 @@
+package ast
+
+import "fmt"
+
+// VariableDeclaration represents `var` and `let` declarations. Scoped
+// declarations (`let`) are bound to the enclosing block while `var`
+// declarations are bound to the enclosing function.
+type VariableDeclaration struct {
+	Span
+
+	Name   string
+	Value  Expression
+	Scoped bool
+}
+
+func (e VariableDeclaration) Type() ExpressionType {
+	if e.Scoped {
+		return ScopedVariableDeclarationType
+	}
+	return VariableDeclarationType
+}
+
+func (e VariableDeclaration) String() string {
+	if e.Scoped {
+		return fmt.Sprintf("let %s = %s", e.Name, e.Value.String())
+	}
+	return fmt.Sprintf("var %s = %s", e.Name, e.Value.String())
+}
+
+// ConstantDeclaration represents `const` declarations
+type ConstantDeclaration struct {
+	Span
+
+	Name  string
+	Value Expression
+}
+
+func (e ConstantDeclaration) Type() ExpressionType { return ConstantDeclarationType }
+func (e ConstantDeclaration) String() string {
+	return fmt.Sprintf("const %s = %s", e.Name, e.Value.String())
+}
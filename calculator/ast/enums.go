@@ -0,0 +1,92 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EnumDeclaration represents `enum Level = { LOW MEDIUM HIGH }`. The
+// declaration is also the value of the enum type.
+type EnumDeclaration struct {
+	Span
+
+	Name    string
+	Members []string
+}
+
+func (e EnumDeclaration) Type() ExpressionType { return EnumDeclarationType }
+func (e EnumDeclaration) String() string {
+	return fmt.Sprintf("enum %s = { %s }", e.Name, strings.Join(e.Members, " "))
+}
+
+// Member returns the named member or false if the enum has no such member.
+func (e *EnumDeclaration) Member(name string) (*EnumLiteral, bool) {
+	for i, m := range e.Members {
+		if m == name {
+			return &EnumLiteral{Decl: e, Ordinal: i}, true
+		}
+	}
+	return nil, false
+}
+
+// Values returns every member in order of declaration.
+func (e *EnumDeclaration) Values() []Expression {
+	values := make([]Expression, len(e.Members))
+	for i := range e.Members {
+		values[i] = &EnumLiteral{Decl: e, Ordinal: i}
+	}
+	return values
+}
+
+// EnumLiteral is a member of an enum. Members of the same enum are ordered by
+// their ordinal.
+type EnumLiteral struct {
+	Span
+
+	Decl    *EnumDeclaration
+	Ordinal int
+}
+
+func (e EnumLiteral) Type() ExpressionType { return EnumLiteralType }
+func (e EnumLiteral) String() string       { return e.Decl.Name + "." + e.Name() }
+
+// Name returns the name of the member.
+func (e EnumLiteral) Name() string { return e.Decl.Members[e.Ordinal] }
+
+func (e EnumLiteral) compare(expr Expression) (int, error) {
+	rh, ok := expr.(*EnumLiteral)
+	if !ok || rh.Decl != e.Decl {
+		return 0, unsupportedOperation("Enum comparison", &e, expr)
+	}
+	return e.Ordinal - rh.Ordinal, nil
+}
+
+func (e EnumLiteral) Equal(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp == 0, err)
+}
+
+func (e EnumLiteral) NotEqual(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp != 0, err)
+}
+
+func (e EnumLiteral) LessThan(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp < 0, err)
+}
+
+func (e EnumLiteral) LessThanOrEqualTo(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp <= 0, err)
+}
+
+func (e EnumLiteral) GreaterThan(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp > 0, err)
+}
+
+func (e EnumLiteral) GreaterThanOrEqualTo(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp >= 0, err)
+}
@@ -2,21 +2,39 @@ package ast
 
 import (
 	"fmt"
+	"strings"
+
 	"github.com/eliquious/lexer"
 )
 
 type UnaryExpression struct {
-	Op   lexer.Token
-	Expr Expression
+	Span
+
+	Op     lexer.Token
+	Expr   Expression
+	Prefix bool
+
+	// OpPos is the position of the operator
+	OpPos lexer.Pos
 }
 
 func (e UnaryExpression) Type() ExpressionType { return UnaryExpressionType }
-func (e UnaryExpression) String() string       { return e.Expr.String() + e.Op.String() }
+func (e UnaryExpression) String() string {
+	if e.Prefix {
+		return e.Op.String() + e.Expr.String()
+	}
+	return e.Expr.String() + e.Op.String()
+}
 
 type BinaryExpression struct {
+	Span
+
 	Op    lexer.Token
 	LExpr Expression
 	RExpr Expression
+
+	// OpPos is the position of the operator
+	OpPos lexer.Pos
 }
 
 func (e BinaryExpression) Precedence() int      { return e.Op.Precedence() }
@@ -24,3 +42,72 @@ func (e BinaryExpression) Type() ExpressionType { return BinaryExpressionType }
 func (e BinaryExpression) String() string {
 	return fmt.Sprintf("(%s %s %s)", e.LExpr.String(), e.Op.String(), e.RExpr.String())
 }
+
+// Identifier represents a reference to a declared name
+type Identifier struct {
+	Span
+
+	Name string
+}
+
+func (e Identifier) Type() ExpressionType { return IdentifierExpressionType }
+func (e Identifier) String() string       { return e.Name }
+
+// GroupExpression represents a parenthesized expression
+type GroupExpression struct {
+	Span
+
+	Expr Expression
+}
+
+func (e GroupExpression) Type() ExpressionType { return GroupExpressionType }
+func (e GroupExpression) String() string       { return "(" + e.Expr.String() + ")" }
+
+// AssignmentExpression represents assigning a new value to a declared name
+type AssignmentExpression struct {
+	Span
+
+	Name  string
+	Value Expression
+}
+
+func (e AssignmentExpression) Type() ExpressionType { return AssignmentExpressionType }
+func (e AssignmentExpression) String() string {
+	return fmt.Sprintf("%s = %s", e.Name, e.Value.String())
+}
+
+// CallExpression represents calling a function with arguments
+type CallExpression struct {
+	Span
+
+	Function Expression
+	Args     []Expression
+}
+
+func (e CallExpression) Type() ExpressionType { return CallFunctionExpressionType }
+func (e CallExpression) String() string {
+	args := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("%s(%s)", e.Function.String(), strings.Join(args, ", "))
+}
+
+// MethodCallExpression represents `recv.name(args)`. The call resolves to the
+// member of a module or to the function `name(recv, args)`.
+type MethodCallExpression struct {
+	Span
+
+	Recv Expression
+	Name string
+	Args []Expression
+}
+
+func (e MethodCallExpression) Type() ExpressionType { return MethodCallExpressionType }
+func (e MethodCallExpression) String() string {
+	args := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("%s.%s(%s)", e.Recv.String(), e.Name, strings.Join(args, ", "))
+}
@@ -0,0 +1,183 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TypeAnnotation represents the declared type of a parameter, return value or
+// struct field. Annotations name either a built-in type such as `float`, a
+// unit such as `m/s`, an array of another type such as `[]Planet` or a
+// function type. Declared types such as structs, aliases and type parameters
+// are written like single units and are resolved when the annotation is
+// checked.
+type TypeAnnotation struct {
+	Name string
+	Unit *UnitExpression
+	Elem *TypeAnnotation
+	Func *FuncType
+
+	// Alias is the spelling of a built-in type written by another name,
+	// such as `bool` for boolean
+	Alias string
+}
+
+func (a TypeAnnotation) String() string {
+	if a.Elem != nil {
+		return "[]" + a.Elem.String()
+	} else if a.Func != nil {
+		return a.Func.String()
+	} else if a.Unit != nil {
+		return a.Unit.String()
+	} else if a.Alias != "" {
+		return a.Alias
+	}
+	return a.Name
+}
+
+// Parameter represents a function parameter with an optional annotation
+type Parameter struct {
+	Name       string
+	Annotation *TypeAnnotation
+}
+
+func (p Parameter) String() string {
+	if p.Annotation == nil {
+		return p.Name
+	}
+	return fmt.Sprintf("%s %s", p.Name, p.Annotation.String())
+}
+
+// Symbol returns the name of an annotation written as a single identifier,
+// such as a struct, an alias or a type parameter.
+func (a TypeAnnotation) Symbol() (string, bool) {
+	if a.Unit == nil || len(a.Unit.Terms) != 1 || a.Unit.Terms[0].Exp != 1 {
+		return "", false
+	}
+	return a.Unit.Terms[0].Symbol, true
+}
+
+// FuncType represents the annotation `func [A] (a A, i int) -> bool`
+type FuncType struct {
+	TypeParams []string
+	Params     []*Parameter
+	Return     *TypeAnnotation
+}
+
+func (t FuncType) String() string {
+	params := make([]string, len(t.Params))
+	for i, p := range t.Params {
+		params[i] = p.String()
+	}
+
+	s := "func "
+	if len(t.TypeParams) > 0 {
+		s += "[" + strings.Join(t.TypeParams, ", ") + "] "
+	}
+	return fmt.Sprintf("%s(%s) -> %s", s, strings.Join(params, ", "), t.Return.String())
+}
+
+// TypeDeclaration represents the alias `type Filter = func [A] (a A) -> bool`
+type TypeDeclaration struct {
+	Span
+
+	Name       string
+	Annotation *TypeAnnotation
+}
+
+func (e TypeDeclaration) Type() ExpressionType { return TypeDeclarationType }
+func (e TypeDeclaration) String() string {
+	return fmt.Sprintf("type %s = %s", e.Name, e.Annotation.String())
+}
+
+// FunctionDeclaration represents `func name(params) -> expr` and
+// `func name(params) Return = { ... }`. Generic functions declare type
+// parameters before their parameters, `func pair<A, B>(a A, b B) A`.
+type FunctionDeclaration struct {
+	Span
+
+	Name       string
+	TypeParams []string
+	Params     []*Parameter
+	Return     *TypeAnnotation
+	Body       Expression
+}
+
+func (e FunctionDeclaration) Type() ExpressionType { return FunctionDeclarationType }
+func (e FunctionDeclaration) String() string {
+	if e.Return == nil {
+		return fmt.Sprintf("func %s -> %s", e.Signature(), e.Body.String())
+	}
+	return fmt.Sprintf("func %s = %s", e.Signature(), e.Body.String())
+}
+
+// Signature returns the name, type parameters, parameters and return
+// annotation.
+func (e FunctionDeclaration) Signature() string {
+	params := make([]string, len(e.Params))
+	for i, p := range e.Params {
+		params[i] = p.String()
+	}
+
+	s := e.Name
+	if len(e.TypeParams) > 0 {
+		s += "<" + strings.Join(e.TypeParams, ", ") + ">"
+	}
+	s += "(" + strings.Join(params, ", ") + ")"
+	if e.Return != nil {
+		s += " " + e.Return.String()
+	}
+	return s
+}
+
+// IsTypeParam returns true if the annotation names one of the type
+// parameters of the function.
+func (e FunctionDeclaration) IsTypeParam(annotation *TypeAnnotation) (string, bool) {
+	if annotation == nil {
+		return "", false
+	}
+	name, ok := annotation.Symbol()
+	if !ok {
+		return "", false
+	}
+	for _, p := range e.TypeParams {
+		if p == name {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// BlockExpression represents a sequence of statements in braces. The value
+// of a block is the value of its last statement.
+type BlockExpression struct {
+	Span
+
+	Exprs []Expression
+}
+
+func (e BlockExpression) Type() ExpressionType { return BlockExpressionType }
+func (e BlockExpression) String() string {
+	exprs := make([]string, len(e.Exprs))
+	for i, expr := range e.Exprs {
+		exprs[i] = expr.String()
+	}
+	return fmt.Sprintf("{ %s }", strings.Join(exprs, "; "))
+}
+
+// LambdaExpression represents an anonymous function `(a, i) -> a > 5`
+type LambdaExpression struct {
+	Span
+
+	Params []*Parameter
+	Body   Expression
+}
+
+func (e LambdaExpression) Type() ExpressionType { return LambdaExpressionType }
+func (e LambdaExpression) String() string {
+	params := make([]string, len(e.Params))
+	for i, p := range e.Params {
+		params[i] = p.String()
+	}
+	return fmt.Sprintf("(%s) -> %s", strings.Join(params, ", "), e.Body.String())
+}
@@ -0,0 +1,20 @@
+package ast
+
+import "strconv"
+
+// ImportExpression represents `import "path/to/file.calc"` or
+// `import physics`. Exactly one of Path and Name is set.
+type ImportExpression struct {
+	Span
+
+	Path string
+	Name string
+}
+
+func (e ImportExpression) Type() ExpressionType { return ImportExpressionType }
+func (e ImportExpression) String() string {
+	if e.Path != "" {
+		return "import " + strconv.Quote(e.Path)
+	}
+	return "import " + e.Name
+}
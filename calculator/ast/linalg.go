@@ -0,0 +1,222 @@
+package ast
+
+import (
+	"errors"
+	"math/big"
+)
+
+// ErrSingularMatrix is returned when inverting a singular matrix or solving a
+// system of equations without a unique solution.
+var ErrSingularMatrix = errors.New("matrix is singular")
+
+// magnitude returns the absolute value of a number used to choose pivots.
+func magnitude(x Expression) *big.Float {
+	if c, ok := x.(*ComplexLiteral); ok {
+		return c.Abs()
+	}
+	f, ok := toFloat(x)
+	if !ok {
+		return new(big.Float)
+	}
+	return new(big.Float).Abs(f)
+}
+
+// luFactors is the decomposition P·A = L·U of a square matrix. The elements
+// of U are stored on and above the diagonal and the multipliers of the unit
+// lower triangular L below it. perm[i] is the row of A moved to row i.
+type luFactors struct {
+	lu   [][]Expression
+	perm []int
+	sign int
+}
+
+// factorLU decomposes a square matrix by Gaussian elimination with partial
+// pivoting. Exact elements are decomposed exactly. Columns without a non-zero
+// pivot are skipped so singular matrices have a decomposition too.
+func factorLU(op string, m *MatrixLiteral) (*luFactors, error) {
+	n, cols := m.Shape()
+	if n != cols {
+		return nil, &SquareError{Op: op, Rows: n, Cols: cols}
+	}
+
+	f := &luFactors{lu: make([][]Expression, n), perm: make([]int, n), sign: 1}
+	for i := range f.lu {
+		f.lu[i] = append([]Expression{}, m.Rows[i]...)
+		f.perm[i] = i
+	}
+
+	a := f.lu
+	for k := 0; k < n; k++ {
+		p := k
+		for i := k + 1; i < n; i++ {
+			if magnitude(a[i][k]).Cmp(magnitude(a[p][k])) > 0 {
+				p = i
+			}
+		}
+		if p != k {
+			a[p], a[k] = a[k], a[p]
+			f.perm[p], f.perm[k] = f.perm[k], f.perm[p]
+			f.sign = -f.sign
+		}
+		if magnitude(a[k][k]).Sign() == 0 {
+			continue
+		}
+
+		for i := k + 1; i < n; i++ {
+			factor, err := divValues(a[i][k], a[k][k])
+			if err != nil {
+				return nil, err
+			}
+			a[i][k] = factor
+			for j := k + 1; j < n; j++ {
+				prod, err := multValues(factor, a[k][j])
+				if err != nil {
+					return nil, err
+				}
+				if a[i][j], err = subValues(a[i][j], prod); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return f, nil
+}
+
+// solve returns x with A·x = b for a vector b by forward and back
+// substitution.
+func (f *luFactors) solve(b []Expression) ([]Expression, error) {
+	n := len(f.lu)
+	x := make([]Expression, n)
+	for i := 0; i < n; i++ {
+		x[i] = b[f.perm[i]]
+		for j := 0; j < i; j++ {
+			prod, err := multValues(f.lu[i][j], x[j])
+			if err != nil {
+				return nil, err
+			}
+			if x[i], err = subValues(x[i], prod); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := i + 1; j < n; j++ {
+			prod, err := multValues(f.lu[i][j], x[j])
+			if err != nil {
+				return nil, err
+			}
+			if x[i], err = subValues(x[i], prod); err != nil {
+				return nil, err
+			}
+		}
+		if magnitude(f.lu[i][i]).Sign() == 0 {
+			return nil, ErrSingularMatrix
+		}
+		var err error
+		if x[i], err = divValues(x[i], f.lu[i][i]); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+// LU returns the decomposition P·A = L·U of a square matrix with partial
+// pivoting, where L is unit lower triangular, U is upper triangular and P is
+// a permutation matrix.
+func LU(m *MatrixLiteral) (l, u, p *MatrixLiteral, err error) {
+	f, err := factorLU("LU decomposition", m)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	n := len(f.lu)
+	zero, one := &IntegerLiteral{Value: big.NewInt(0)}, &IntegerLiteral{Value: big.NewInt(1)}
+	l, _ = NewMatrix(n, n, func(i, j int) (Expression, error) {
+		switch {
+		case i == j:
+			return one, nil
+		case i > j:
+			return f.lu[i][j], nil
+		}
+		return zero, nil
+	})
+	u, _ = NewMatrix(n, n, func(i, j int) (Expression, error) {
+		if i > j {
+			return zero, nil
+		}
+		return f.lu[i][j], nil
+	})
+	p, _ = NewMatrix(n, n, func(i, j int) (Expression, error) {
+		if f.perm[i] == j {
+			return one, nil
+		}
+		return zero, nil
+	})
+	return l, u, p, nil
+}
+
+// Determinant returns the determinant of a square matrix, which is exact for
+// exact elements.
+func Determinant(m *MatrixLiteral) (Expression, error) {
+	f, err := factorLU("Determinant", m)
+	if err != nil {
+		return nil, err
+	}
+
+	var det Expression = &IntegerLiteral{Value: big.NewInt(int64(f.sign))}
+	for i := range f.lu {
+		if det, err = multValues(det, f.lu[i][i]); err != nil {
+			return nil, err
+		}
+	}
+	return det, nil
+}
+
+// Solve returns x with A·x = b for a square matrix A and a vector or a
+// matrix b.
+func Solve(a *MatrixLiteral, b Expression) (Expression, error) {
+	f, err := factorLU("Solve", a)
+	if err != nil {
+		return nil, err
+	}
+	n := len(f.lu)
+
+	switch b := b.(type) {
+	case *ArrayLiteral:
+		if len(b.Elements) != n {
+			return nil, &ShapeError{Op: "Solution", Left: [2]int{n, n}, Right: [2]int{len(b.Elements), 1}}
+		}
+		x, err := f.solve(b.Elements)
+		if err != nil {
+			return nil, err
+		}
+		return &ArrayLiteral{Elements: x}, nil
+	case *MatrixLiteral:
+		rows, cols := b.Shape()
+		if rows != n {
+			return nil, &ShapeError{Op: "Solution", Left: [2]int{n, n}, Right: [2]int{rows, cols}}
+		}
+		columns := b.Transpose()
+		for j, col := range columns.Rows {
+			if columns.Rows[j], err = f.solve(col); err != nil {
+				return nil, err
+			}
+		}
+		return columns.Transpose(), nil
+	}
+	return nil, unsupportedOperation("Solve", a, b)
+}
+
+// Inverse returns the inverse of a square matrix, which is exact for exact
+// elements.
+func Inverse(m *MatrixLiteral) (*MatrixLiteral, error) {
+	rows, cols := m.Shape()
+	if rows != cols {
+		return nil, &SquareError{Op: "Inverse", Rows: rows, Cols: cols}
+	}
+	inv, err := Solve(m, Identity(rows))
+	if err != nil {
+		return nil, err
+	}
+	return inv.(*MatrixLiteral), nil
+}
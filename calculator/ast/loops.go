@@ -0,0 +1,18 @@
+package ast
+
+import "fmt"
+
+// ForExpression represents `for x in iterable { ... }` which evaluates to an
+// array of the values of the body.
+type ForExpression struct {
+	Span
+
+	Name     string
+	Iterable Expression
+	Body     *BlockExpression
+}
+
+func (e ForExpression) Type() ExpressionType { return ForExpressionType }
+func (e ForExpression) String() string {
+	return fmt.Sprintf("for %s in %s %s", e.Name, e.Iterable.String(), e.Body.String())
+}
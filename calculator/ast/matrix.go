@@ -0,0 +1,275 @@
+package ast
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// MatrixLiteral represents a rectangular matrix of numbers written with rows
+// separated by semicolons, `[1, 2; 3, 4]`. Matrices are multiplied by the
+// row by column rule, with arrays standing for vectors, and are added and
+// scaled element-wise.
+type MatrixLiteral struct {
+	Span
+
+	Rows [][]Expression
+}
+
+func (e MatrixLiteral) Type() ExpressionType { return MatrixLiteralType }
+func (e MatrixLiteral) String() string {
+	rows := make([]string, len(e.Rows))
+	for i, row := range e.Rows {
+		elements := make([]string, len(row))
+		for j, el := range row {
+			elements[j] = el.String()
+		}
+		rows[i] = strings.Join(elements, ", ")
+	}
+	return "[" + strings.Join(rows, "; ") + "]"
+}
+
+// Shape returns the number of rows and columns of the matrix.
+func (e MatrixLiteral) Shape() (int, int) {
+	if len(e.Rows) == 0 {
+		return 0, 0
+	}
+	return len(e.Rows), len(e.Rows[0])
+}
+
+// ShapeError is returned by matrix operations on operands whose shapes do not
+// match. Arrays have the shape of a column vector.
+type ShapeError struct {
+	Op          string
+	Left, Right [2]int
+}
+
+// Error returns the string representation of the error.
+func (e *ShapeError) Error() string {
+	return fmt.Sprintf("%s of matrices with mismatched shapes %dx%d and %dx%d", e.Op, e.Left[0], e.Left[1], e.Right[0], e.Right[1])
+}
+
+// SquareError is returned by operations which are only defined on square
+// matrices.
+type SquareError struct {
+	Op         string
+	Rows, Cols int
+}
+
+// Error returns the string representation of the error.
+func (e *SquareError) Error() string {
+	return fmt.Sprintf("%s requires a square matrix, found %dx%d", e.Op, e.Rows, e.Cols)
+}
+
+// NewMatrix returns a matrix with the elements computed by fn.
+func NewMatrix(rows, cols int, fn func(i, j int) (Expression, error)) (*MatrixLiteral, error) {
+	m := &MatrixLiteral{Rows: make([][]Expression, rows)}
+	for i := range m.Rows {
+		m.Rows[i] = make([]Expression, cols)
+		for j := range m.Rows[i] {
+			var err error
+			if m.Rows[i][j], err = fn(i, j); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+// Identity returns the n×n identity matrix.
+func Identity(n int) *MatrixLiteral {
+	m, _ := NewMatrix(n, n, func(i, j int) (Expression, error) {
+		if i == j {
+			return &IntegerLiteral{Value: big.NewInt(1)}, nil
+		}
+		return &IntegerLiteral{Value: big.NewInt(0)}, nil
+	})
+	return m
+}
+
+// Transpose returns the matrix with its rows and columns exchanged.
+func (e MatrixLiteral) Transpose() *MatrixLiteral {
+	rows, cols := e.Shape()
+	m, _ := NewMatrix(cols, rows, func(i, j int) (Expression, error) {
+		return e.Rows[j][i], nil
+	})
+	return m
+}
+
+// Map returns the matrix with fn applied to each element.
+func (e MatrixLiteral) Map(fn func(el Expression) (Expression, error)) (*MatrixLiteral, error) {
+	rows, cols := e.Shape()
+	return NewMatrix(rows, cols, func(i, j int) (Expression, error) {
+		return fn(e.Rows[i][j])
+	})
+}
+
+// dot returns the sum of the products of the elements of two vectors of the
+// same length.
+func dot(x []Expression, y func(i int) Expression) (Expression, error) {
+	var sum Expression
+	for i := range x {
+		prod, err := multValues(x[i], y(i))
+		if err != nil {
+			return nil, err
+		}
+		if sum == nil {
+			sum = prod
+		} else if sum, err = addValues(sum, prod); err != nil {
+			return nil, err
+		}
+	}
+	return sum, nil
+}
+
+// combine applies the operation to each pair of elements of two matrices
+// of the same shape.
+func (e MatrixLiteral) combine(op string, expr Expression, fn func(lh, rh Expression) (Expression, error)) (Expression, error) {
+	rh, ok := expr.(*MatrixLiteral)
+	if !ok {
+		return nil, unsupportedOperation("Matrix "+strings.ToLower(op), &e, expr)
+	}
+	rows, cols := e.Shape()
+	if r, c := rh.Shape(); r != rows || c != cols {
+		return nil, &ShapeError{Op: op, Left: [2]int{rows, cols}, Right: [2]int{r, c}}
+	}
+	return NewMatrix(rows, cols, func(i, j int) (Expression, error) {
+		return fn(e.Rows[i][j], rh.Rows[i][j])
+	})
+}
+
+func (e MatrixLiteral) Add(expr Expression) (Expression, error) {
+	return e.combine("Addition", expr, addValues)
+}
+
+func (e MatrixLiteral) Sub(expr Expression) (Expression, error) {
+	return e.combine("Subtraction", expr, subValues)
+}
+
+// Mult returns the product of two matrices, the product of the matrix and a
+// column vector or the matrix scaled by a number.
+func (e MatrixLiteral) Mult(expr Expression) (Expression, error) {
+	rows, cols := e.Shape()
+	switch rh := expr.(type) {
+	case *MatrixLiteral:
+		r, c := rh.Shape()
+		if cols != r {
+			return nil, &ShapeError{Op: "Multiplication", Left: [2]int{rows, cols}, Right: [2]int{r, c}}
+		}
+		return NewMatrix(rows, c, func(i, j int) (Expression, error) {
+			return dot(e.Rows[i], func(k int) Expression { return rh.Rows[k][j] })
+		})
+	case *ArrayLiteral:
+		if cols != len(rh.Elements) {
+			return nil, &ShapeError{Op: "Multiplication", Left: [2]int{rows, cols}, Right: [2]int{len(rh.Elements), 1}}
+		}
+		elements := make([]Expression, rows)
+		for i := range elements {
+			var err error
+			if elements[i], err = dot(e.Rows[i], func(k int) Expression { return rh.Elements[k] }); err != nil {
+				return nil, err
+			}
+		}
+		return &ArrayLiteral{Elements: elements}, nil
+	}
+
+	if !IsNumeric(expr) {
+		return nil, unsupportedOperation("Matrix multiplication", &e, expr)
+	}
+	return e.Map(func(el Expression) (Expression, error) { return multValues(el, expr) })
+}
+
+// multVector returns the product of a row vector and the matrix.
+func (e MatrixLiteral) multVector(v *ArrayLiteral) (Expression, error) {
+	rows, cols := e.Shape()
+	if rows != len(v.Elements) {
+		return nil, &ShapeError{Op: "Multiplication", Left: [2]int{1, len(v.Elements)}, Right: [2]int{rows, cols}}
+	}
+	elements := make([]Expression, cols)
+	for j := range elements {
+		var err error
+		if elements[j], err = dot(v.Elements, func(k int) Expression { return e.Rows[k][j] }); err != nil {
+			return nil, err
+		}
+	}
+	return &ArrayLiteral{Elements: elements}, nil
+}
+
+// Div divides each element of the matrix by a number.
+func (e MatrixLiteral) Div(expr Expression) (Expression, error) {
+	if !IsNumeric(expr) {
+		return nil, unsupportedOperation("Matrix division", &e, expr)
+	}
+	return e.Map(func(el Expression) (Expression, error) { return divValues(el, expr) })
+}
+
+// Pow raises a square matrix to an integer power by repeated squaring.
+// Negative powers are powers of the inverse.
+func (e MatrixLiteral) Pow(expr Expression) (Expression, error) {
+	n, ok := expr.(*IntegerLiteral)
+	if !ok {
+		return nil, unsupportedOperation("Matrix exponentiation", &e, expr)
+	}
+	rows, cols := e.Shape()
+	if rows != cols {
+		return nil, &SquareError{Op: "Matrix exponentiation", Rows: rows, Cols: cols}
+	} else if n.Value.CmpAbs(big.NewInt(maxPowerBits)) > 0 {
+		return nil, ErrExponentTooLarge
+	}
+
+	base := &e
+	if n.Value.Sign() < 0 {
+		var err error
+		if base, err = Inverse(&e); err != nil {
+			return nil, err
+		}
+	}
+
+	var result Expression = Identity(rows)
+	for k := new(big.Int).Abs(n.Value).Int64(); k != 0; k /= 2 {
+		var err error
+		if k%2 != 0 {
+			if result, err = result.(*MatrixLiteral).Mult(base); err != nil {
+				return nil, err
+			}
+		}
+		if k > 1 {
+			square, err := base.Mult(base)
+			if err != nil {
+				return nil, err
+			}
+			base = square.(*MatrixLiteral)
+		}
+	}
+	return result, nil
+}
+
+// equal returns true if both matrices have the same shape and equal elements.
+func (e MatrixLiteral) equal(expr Expression) (bool, error) {
+	rh, ok := expr.(*MatrixLiteral)
+	if !ok {
+		return false, unsupportedOperation("Matrix comparison", &e, expr)
+	}
+	rows, cols := e.Shape()
+	if r, c := rh.Shape(); r != rows || c != cols {
+		return false, nil
+	}
+	for i := range e.Rows {
+		for j := range e.Rows[i] {
+			if eq, err := equalNumbers(e.Rows[i][j], rh.Rows[i][j]); err != nil || !eq {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+func (e MatrixLiteral) Equal(expr Expression) (Expression, error) {
+	eq, err := e.equal(expr)
+	return newBoolean(eq, err)
+}
+
+func (e MatrixLiteral) NotEqual(expr Expression) (Expression, error) {
+	eq, err := e.equal(expr)
+	return newBoolean(!eq, err)
+}
@@ -0,0 +1,227 @@
+package ast
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/eliquious/aechbar/calculator/units"
+)
+
+// NewQuantity returns the value with the unit attached. Dimensionless units
+// are folded into the value so that `1 m / 1 ft` is a plain number.
+func NewQuantity(value Expression, unit units.Compound) (Expression, error) {
+	if !unit.IsEmpty() && len(unit.Dimension()) > 0 {
+		return &QuantityLiteral{Value: value, Unit: unit}, nil
+	} else if unit.IsEmpty() {
+		return value, nil
+	}
+	return scaleValue(value, unit.Factor(), unit.Precision())
+}
+
+// scaleValue multiplies a numeric value by a factor. A factor with a
+// non-zero precision approximates an irrational number and gives a decimal.
+func scaleValue(value Expression, factor *big.Rat, prec uint) (Expression, error) {
+	if prec > 0 {
+		return multValues(value, &DecimalLiteral{Value: new(big.Float).SetPrec(prec).SetRat(factor)})
+	} else if factor.Cmp(big.NewRat(1, 1)) == 0 {
+		return value, nil
+	}
+	return multValues(value, newRational(factor))
+}
+
+// ConvertQuantity converts the quantity to the target unit. Quantities in an
+// affine unit such as the degree Celsius are absolute, so the offset between
+// the units is added after scaling and `20 degC to K` is 293.15 K.
+func ConvertQuantity(q *QuantityLiteral, to units.Compound) (Expression, error) {
+	value, err := scaleQuantity(q, to)
+	if err != nil {
+		return nil, err
+	}
+	if offset := q.Unit.ConversionOffset(to); offset != nil {
+		if value, err = addValues(value, newRational(offset)); err != nil {
+			return nil, err
+		}
+	}
+	return &QuantityLiteral{Value: value, Unit: to}, nil
+}
+
+// scaleQuantity returns the value of the quantity scaled to the target unit
+// without applying offsets.
+func scaleQuantity(q *QuantityLiteral, to units.Compound) (Expression, error) {
+	factor, err := q.Unit.ConversionFactor(to)
+	if err != nil {
+		return nil, err
+	}
+	return scaleValue(q.Value, factor, q.Unit.Div(to).Precision())
+}
+
+// compatibleValue returns the value of a quantity operand converted to the
+// unit of the receiver. Operands of sums are differences and are scaled
+// while compared operands are absolute and are converted.
+func (e QuantityLiteral) compatibleValue(op string, expr Expression) (Expression, error) {
+	rh, ok := expr.(*QuantityLiteral)
+	if !ok {
+		return nil, &units.DimensionError{Op: op, Left: e.Unit, Right: units.Compound{}}
+	}
+	if op != "compare" {
+		value, err := scaleQuantity(rh, e.Unit)
+		if err != nil {
+			return nil, &units.DimensionError{Op: op, Left: e.Unit, Right: rh.Unit}
+		}
+		return value, nil
+	}
+	converted, err := ConvertQuantity(rh, e.Unit)
+	if err != nil {
+		return nil, &units.DimensionError{Op: op, Left: e.Unit, Right: rh.Unit}
+	}
+	return converted.(*QuantityLiteral).Value, nil
+}
+
+func (e QuantityLiteral) Add(expr Expression) (Expression, error) {
+	if expr.Type() == ArrayLiteralType {
+		return elementwise("Addition", &e, expr, addValues)
+	}
+	rh, err := e.compatibleValue("add", expr)
+	if err != nil {
+		return nil, err
+	}
+	add, ok := e.Value.(AddExpression)
+	if !ok {
+		return nil, unsupportedOperation("Quantity addition", &e, expr)
+	}
+	value, err := add.Add(rh)
+	if err != nil {
+		return nil, err
+	}
+	return NewQuantity(value, e.Unit)
+}
+
+func (e QuantityLiteral) Sub(expr Expression) (Expression, error) {
+	if expr.Type() == ArrayLiteralType {
+		return elementwise("Subtraction", &e, expr, subValues)
+	}
+	rh, err := e.compatibleValue("subtract", expr)
+	if err != nil {
+		return nil, err
+	}
+	sub, ok := e.Value.(SubExpression)
+	if !ok {
+		return nil, unsupportedOperation("Quantity subtraction", &e, expr)
+	}
+	value, err := sub.Sub(rh)
+	if err != nil {
+		return nil, err
+	}
+	return NewQuantity(value, e.Unit)
+}
+
+func (e QuantityLiteral) Mult(expr Expression) (Expression, error) {
+	if expr.Type() == ArrayLiteralType {
+		return elementwise("Multiplication", &e, expr, multValues)
+	}
+	unit, rh := e.Unit, expr
+	if q, ok := expr.(*QuantityLiteral); ok {
+		unit, rh = unit.Mul(q.Unit), q.Value
+	}
+	value, err := multValues(e.Value, rh)
+	if err != nil {
+		return nil, err
+	}
+	return NewQuantity(value, unit)
+}
+
+func (e QuantityLiteral) Div(expr Expression) (Expression, error) {
+	if expr.Type() == ArrayLiteralType {
+		return elementwise("Division", &e, expr, divValues)
+	}
+	unit, rh := e.Unit, expr
+	if q, ok := expr.(*QuantityLiteral); ok {
+		unit, rh = unit.Div(q.Unit), q.Value
+	}
+	div, ok := e.Value.(DivExpression)
+	if !ok {
+		return nil, unsupportedOperation("Quantity division", &e, expr)
+	}
+	value, err := div.Div(rh)
+	if err != nil {
+		return nil, err
+	}
+	return NewQuantity(value, unit)
+}
+
+// Pow raises the quantity to an integer power or to a fractional power
+// which evenly divides every unit exponent, such as `(4 m^2) ** 0.5`.
+func (e QuantityLiteral) Pow(expr Expression) (Expression, error) {
+	if expr.Type() == ArrayLiteralType {
+		return elementwise("Exponentiation", &e, expr, powValues)
+	}
+	exp, ok := ToRat(expr)
+	if !ok || !exp.Num().IsInt64() || !exp.Denom().IsInt64() {
+		return nil, fmt.Errorf("quantity exponent must be a number, found %s", expr.String())
+	}
+	unit, ok := e.Unit.Pow(int(exp.Num().Int64())).Root(int(exp.Denom().Int64()))
+	if !ok {
+		return nil, fmt.Errorf("cannot raise %s to the power of %s", e.Unit, exp.RatString())
+	}
+
+	pow, ok := e.Value.(PowExpression)
+	if !ok {
+		return nil, unsupportedOperation("Quantity exponentiation", &e, expr)
+	}
+	value, err := pow.Pow(expr)
+	if err != nil {
+		return nil, err
+	}
+	return NewQuantity(value, unit)
+}
+
+func (e QuantityLiteral) compare(expr Expression) (int, error) {
+	rh, err := e.compatibleValue("compare", expr)
+	if err != nil {
+		return 0, err
+	}
+	return compareNumbers(e.Value, rh)
+}
+
+// quantityOf returns a number as a dimensionless quantity.
+func quantityOf(expr Expression) *QuantityLiteral {
+	return &QuantityLiteral{Value: expr}
+}
+
+func (e QuantityLiteral) equal(expr Expression) (bool, error) {
+	rh, err := e.compatibleValue("compare", expr)
+	if err != nil {
+		return false, err
+	}
+	return equalNumbers(e.Value, rh)
+}
+
+func (e QuantityLiteral) Equal(expr Expression) (Expression, error) {
+	eq, err := e.equal(expr)
+	return newBoolean(eq, err)
+}
+
+func (e QuantityLiteral) NotEqual(expr Expression) (Expression, error) {
+	eq, err := e.equal(expr)
+	return newBoolean(!eq, err)
+}
+
+func (e QuantityLiteral) LessThan(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp < 0, err)
+}
+
+func (e QuantityLiteral) LessThanOrEqualTo(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp <= 0, err)
+}
+
+func (e QuantityLiteral) GreaterThan(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp > 0, err)
+}
+
+func (e QuantityLiteral) GreaterThanOrEqualTo(expr Expression) (Expression, error) {
+	cmp, err := e.compare(expr)
+	return newBoolean(cmp >= 0, err)
+}
@@ -0,0 +1,25 @@
+package ast
+
+import "github.com/eliquious/lexer"
+
+// Span is the range of source text an expression was parsed from. End is
+// the position following the last character of the expression. Values
+// created during evaluation have an empty span.
+type Span struct {
+	Start lexer.Pos
+	End   lexer.Pos
+}
+
+// Position returns the span. Expressions embed a Span so that every node
+// reports where it was parsed from.
+func (s Span) Position() Span { return s }
+
+// SetPosition updates the span.
+func (s *Span) SetPosition(start, end lexer.Pos) {
+	s.Start, s.End = start, end
+}
+
+// IsValid returns true if the span was recorded by the parser.
+func (s Span) IsValid() bool {
+	return s.End != (lexer.Pos{})
+}
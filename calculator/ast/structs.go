@@ -0,0 +1,107 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StructField represents a named and typed field of a struct declaration
+type StructField struct {
+	Name       string
+	Annotation *TypeAnnotation
+}
+
+func (f StructField) String() string {
+	return fmt.Sprintf("%s %s", f.Name, f.Annotation.String())
+}
+
+// StructDeclaration represents `struct Planet = { Name string; Mass (kg) }`.
+// The declaration is also the value of the struct type.
+type StructDeclaration struct {
+	Span
+
+	Name   string
+	Fields []*StructField
+}
+
+func (e StructDeclaration) Type() ExpressionType { return StructDeclarationType }
+func (e StructDeclaration) String() string {
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = f.String()
+	}
+	return fmt.Sprintf("struct %s = { %s }", e.Name, strings.Join(fields, "; "))
+}
+
+// Field returns the index of the named field or -1.
+func (e StructDeclaration) Field(name string) int {
+	for i, f := range e.Fields {
+		if f.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// FieldValue represents `Name: value` in a struct expression
+type FieldValue struct {
+	Name  string
+	Value Expression
+}
+
+// StructExpression represents a keyed struct literal,
+// `Planet{Name: "Earth", Mass: 5.972E24}`, before evaluation.
+type StructExpression struct {
+	Span
+
+	Name   string
+	Fields []*FieldValue
+}
+
+func (e StructExpression) Type() ExpressionType { return StructExpressionType }
+func (e StructExpression) String() string {
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = fmt.Sprintf("%s: %s", f.Name, f.Value.String())
+	}
+	return fmt.Sprintf("%s{%s}", e.Name, strings.Join(fields, ", "))
+}
+
+// StructLiteral is an evaluated struct. Values are in the order of the fields
+// of the declaration.
+type StructLiteral struct {
+	Span
+
+	Decl   *StructDeclaration
+	Values []Expression
+}
+
+func (e StructLiteral) Type() ExpressionType { return StructLiteralType }
+func (e StructLiteral) String() string {
+	fields := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		fields[i] = fmt.Sprintf("%s: %s", e.Decl.Fields[i].Name, v.String())
+	}
+	return fmt.Sprintf("%s{%s}", e.Decl.Name, strings.Join(fields, ", "))
+}
+
+// Get returns the value of the named field.
+func (e StructLiteral) Get(name string) (Expression, bool) {
+	if i := e.Decl.Field(name); i >= 0 {
+		return e.Values[i], true
+	}
+	return nil, false
+}
+
+// FieldExpression represents the field access `planet.Mass`
+type FieldExpression struct {
+	Span
+
+	Expr Expression
+	Name string
+}
+
+func (e FieldExpression) Type() ExpressionType { return FieldExpressionType }
+func (e FieldExpression) String() string {
+	return fmt.Sprintf("%s.%s", e.Expr.String(), e.Name)
+}
@@ -0,0 +1,81 @@
+package ast
+
+import "strings"
+
+// Names of the types of values. Types are compared by name when type
+// parameters are bound, both by the evaluator and by the static checker.
+// Arrays are named `[]` followed by the type of their elements and an empty
+// name stands for a type which is not known.
+const (
+	NumberTypeName    = "number"
+	ComplexTypeName   = "complex"
+	MatrixTypeName    = "matrix"
+	StringTypeName    = "string"
+	BooleanTypeName   = "boolean"
+	DurationTypeName  = "duration"
+	TimestampTypeName = "timestamp"
+	FunctionTypeName  = "func"
+	NilTypeName       = "nil"
+)
+
+// TypeOf returns the name of the type of a value. Structs and enums are named
+// by their declaration and quantities by their dimension.
+func TypeOf(value Expression) string {
+	switch v := value.(type) {
+	case *IntegerLiteral, *DecimalLiteral, *RationalLiteral:
+		return NumberTypeName
+	case *ComplexLiteral:
+		return ComplexTypeName
+	case *MatrixLiteral:
+		return MatrixTypeName
+	case *StringLiteral:
+		return StringTypeName
+	case *BooleanLiteral:
+		return BooleanTypeName
+	case *DurationLiteral:
+		return DurationTypeName
+	case *NilLiteral:
+		return NilTypeName
+	case *QuantityLiteral:
+		if v.Unit.IsEmpty() {
+			return NumberTypeName
+		}
+		return "quantity(" + v.Unit.Dimension().String() + ")"
+	case *StructLiteral:
+		return v.Decl.Name
+	case *EnumLiteral:
+		return v.Decl.Name
+	case *ArrayLiteral:
+		elem := ""
+		for _, el := range v.Elements {
+			var ok bool
+			if elem, ok = UnifyTypes(elem, TypeOf(el)); !ok {
+				return "[]"
+			}
+		}
+		return "[]" + elem
+	}
+
+	switch value.Type() {
+	case TimestampLiteralType:
+		return TimestampTypeName
+	case FunctionType, BuiltinFunctionType:
+		return FunctionTypeName
+	default:
+		return ""
+	}
+}
+
+// UnifyTypes returns the more specific of two compatible type names. Unknown
+// types are compatible with every type, including the elements of arrays.
+func UnifyTypes(a, b string) (string, bool) {
+	if a == "" || a == b {
+		return b, true
+	} else if b == "" {
+		return a, true
+	} else if strings.HasPrefix(a, "[]") && strings.HasPrefix(b, "[]") {
+		elem, ok := UnifyTypes(a[2:], b[2:])
+		return "[]" + elem, ok
+	}
+	return "", false
+}
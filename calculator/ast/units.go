@@ -0,0 +1,139 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eliquious/aechbar/calculator/units"
+)
+
+// UnitTerm is a unit symbol raised to an integer power
+type UnitTerm struct {
+	Symbol string
+	Exp    int
+}
+
+// UnitExpression represents a product of unit symbols such as `kg*m/s^2`.
+// Symbols are resolved during evaluation.
+type UnitExpression struct {
+	Span
+
+	Terms []UnitTerm
+}
+
+func (e UnitExpression) Type() ExpressionType { return UnitExpressionType }
+func (e UnitExpression) String() string {
+	var num, den []string
+	for _, t := range e.Terms {
+		exp := t.Exp
+		if exp < 0 {
+			exp = -exp
+		}
+
+		term := t.Symbol
+		if exp != 1 {
+			term = fmt.Sprintf("%s^%d", t.Symbol, exp)
+		}
+		if t.Exp < 0 {
+			den = append(den, term)
+		} else {
+			num = append(num, term)
+		}
+	}
+
+	s := strings.Join(num, "*")
+	if s == "" {
+		s = "1"
+	}
+	for _, d := range den {
+		s += "/" + d
+	}
+	return s
+}
+
+// QuantityExpression represents a number followed by a unit such as `5 kg`
+type QuantityExpression struct {
+	Span
+
+	Value Expression
+	Unit  *UnitExpression
+}
+
+func (e QuantityExpression) Type() ExpressionType { return QuantityExpressionType }
+func (e QuantityExpression) String() string {
+	return fmt.Sprintf("%s %s", e.Value.String(), e.Unit.String())
+}
+
+// ConversionExpression represents converting a quantity with `to`
+type ConversionExpression struct {
+	Span
+
+	Expr Expression
+	Unit *UnitExpression
+}
+
+func (e ConversionExpression) Type() ExpressionType { return ConversionExpressionType }
+func (e ConversionExpression) String() string {
+	return fmt.Sprintf("(%s to %s)", e.Expr.String(), e.Unit.String())
+}
+
+// UnitConversion relates an amount of the declared unit to a quantity of
+// another unit, as in `1 = 1 kg * m / s^2`.
+type UnitConversion struct {
+	Amount Expression
+	Value  Expression
+	Unit   *UnitExpression
+}
+
+func (c UnitConversion) String() string {
+	return fmt.Sprintf("%s = %s %s", c.Amount.String(), c.Value.String(), c.Unit.String())
+}
+
+// UnitDeclaration represents `unit Name (symbol) { conversions }`. Units
+// without conversions to known units are base units.
+type UnitDeclaration struct {
+	Span
+
+	Name        string
+	Symbol      string
+	Conversions []*UnitConversion
+}
+
+func (e UnitDeclaration) Type() ExpressionType { return UnitDeclarationType }
+func (e UnitDeclaration) String() string {
+	if len(e.Conversions) == 0 {
+		return fmt.Sprintf("unit %s (%s)", e.Name, e.Symbol)
+	}
+	conversions := make([]string, len(e.Conversions))
+	for i, c := range e.Conversions {
+		conversions[i] = c.String()
+	}
+	return fmt.Sprintf("unit %s (%s) { %s }", e.Name, e.Symbol, strings.Join(conversions, "; "))
+}
+
+// ConversionDeclaration represents `conversion 1 m = 3.28084 ft` which
+// defines whichever of the two units is unknown in terms of the other.
+type ConversionDeclaration struct {
+	Span
+
+	LValue *QuantityExpression
+	RValue *QuantityExpression
+}
+
+func (e ConversionDeclaration) Type() ExpressionType { return ConversionDeclarationType }
+func (e ConversionDeclaration) String() string {
+	return fmt.Sprintf("conversion %s = %s", e.LValue.String(), e.RValue.String())
+}
+
+// QuantityLiteral represents a number with a resolved unit
+type QuantityLiteral struct {
+	Span
+
+	Value Expression
+	Unit  units.Compound
+}
+
+func (e QuantityLiteral) Type() ExpressionType { return QuantityLiteralType }
+func (e QuantityLiteral) String() string {
+	return fmt.Sprintf("%s %s", e.Value.String(), e.Unit.String())
+}
@@ -0,0 +1,295 @@
+// Package bigmath implements elementary functions for big.Float values. All
+// functions return results rounded to the precision of their first argument.
+package bigmath
+
+import (
+	"errors"
+	"math/big"
+)
+
+// guardBits are the extra bits of precision used for intermediate results.
+const guardBits = 64
+
+var (
+	// ErrDomain is returned when an argument is outside of the domain of a function.
+	ErrDomain = errors.New("argument out of domain")
+
+	// ErrOverflow is returned when a result is too large to be represented.
+	ErrOverflow = errors.New("result out of range")
+
+	// ErrNoConvergence is returned when an iteration does not converge.
+	ErrNoConvergence = errors.New("iteration did not converge")
+)
+
+// prec returns the working precision of x.
+func prec(x *big.Float) uint {
+	if x.Prec() == 0 {
+		return 64
+	}
+	return x.Prec()
+}
+
+// newFloat returns a new float with the given precision and rounding mode.
+func newFloat(prec uint, mode big.RoundingMode) *big.Float {
+	return new(big.Float).SetPrec(prec).SetMode(mode)
+}
+
+// round rounds x to the precision and rounding mode of ref.
+func round(x, ref *big.Float) *big.Float {
+	return newFloat(prec(ref), ref.Mode()).Set(x)
+}
+
+// IsInt returns true if x is a finite integer.
+func IsInt(x *big.Float) bool {
+	return !x.IsInf() && x.IsInt()
+}
+
+// Ln2 returns ln(2) with the given precision.
+func Ln2(prec uint) *big.Float {
+	z := newFloat(prec+guardBits, big.ToNearestEven)
+	third := newFloat(z.Prec(), big.ToNearestEven).Quo(big.NewFloat(1), big.NewFloat(3))
+	return atanhSeries(z, third).SetPrec(prec)
+}
+
+// atanhSeries returns 2·atanh(x) = ln((1+x)/(1-x)) for |x| < 1 using the
+// Taylor series. The result is stored in z.
+func atanhSeries(z, x *big.Float) *big.Float {
+	p := z.Prec()
+	x2 := newFloat(p, big.ToNearestEven).Mul(x, x)
+	term := newFloat(p, big.ToNearestEven).Set(x)
+	sum := newFloat(p, big.ToNearestEven).Set(x)
+	t := newFloat(p, big.ToNearestEven)
+	for i := int64(3); ; i += 2 {
+		term.Mul(term, x2)
+		t.Quo(term, new(big.Float).SetInt64(i))
+		if t.Sign() == 0 || t.MantExp(nil)-sum.MantExp(nil) < -int(p) {
+			break
+		}
+		sum.Add(sum, t)
+	}
+	return z.Mul(sum, big.NewFloat(2))
+}
+
+// Log returns the natural logarithm of x.
+func Log(x *big.Float) (*big.Float, error) {
+	if x.Sign() <= 0 {
+		return nil, ErrDomain
+	} else if x.IsInf() {
+		return new(big.Float).SetInf(false), nil
+	} else if x.Cmp(big.NewFloat(1)) == 0 {
+		return newFloat(prec(x), x.Mode()), nil
+	}
+	p := prec(x) + guardBits
+
+	// x = m · 2^k with m in [0.5, 1)
+	m := newFloat(p, big.ToNearestEven)
+	k := x.MantExp(m)
+
+	// ln(m) = 2·atanh((m - 1) / (m + 1))
+	num := newFloat(p, big.ToNearestEven).Sub(m, big.NewFloat(1))
+	den := newFloat(p, big.ToNearestEven).Add(m, big.NewFloat(1))
+	z := atanhSeries(newFloat(p, big.ToNearestEven), num.Quo(num, den))
+
+	// ln(x) = ln(m) + k·ln(2)
+	if k != 0 {
+		ln2 := Ln2(p)
+		z.Add(z, ln2.Mul(ln2, new(big.Float).SetInt64(int64(k))))
+	}
+	return round(z, x), nil
+}
+
+// Log10 returns the base 10 logarithm of x.
+func Log10(x *big.Float) (*big.Float, error) {
+	ln10, _ := Log(newFloat(prec(x)+guardBits, big.ToNearestEven).SetInt64(10))
+	return logBase(x, ln10)
+}
+
+// Log2 returns the base 2 logarithm of x.
+func Log2(x *big.Float) (*big.Float, error) {
+	return logBase(x, Ln2(prec(x)+guardBits))
+}
+
+// logBase returns ln(x) / ln(b) given the natural logarithm of the base.
+func logBase(x, lnb *big.Float) (*big.Float, error) {
+	z, err := Log(newFloat(prec(x)+guardBits, big.ToNearestEven).Set(x))
+	if err != nil {
+		return nil, err
+	}
+	return round(z.Quo(z, lnb), x), nil
+}
+
+// Exp returns e**x.
+func Exp(x *big.Float) (*big.Float, error) {
+	if x.IsInf() {
+		if x.Signbit() {
+			return new(big.Float), nil
+		}
+		return new(big.Float).SetInf(false), nil
+	}
+	p := prec(x) + guardBits
+
+	// x = k·ln(2) + r with |r| < ln(2)
+	ln2 := Ln2(p)
+	kf, _ := newFloat(p, big.ToNearestEven).Quo(x, ln2).Int(nil)
+	if !kf.IsInt64() || kf.Int64() > big.MaxExp || kf.Int64() < big.MinExp {
+		return nil, ErrDomain
+	}
+	k := kf.Int64()
+	r := newFloat(p, big.ToNearestEven).Mul(ln2, new(big.Float).SetInt64(k))
+	r.Sub(x, r)
+
+	// Scale r down further so the series converges quickly and square the
+	// result afterwards.
+	const halvings = 16
+	r.SetMantExp(r, -halvings)
+
+	sum := newFloat(p, big.ToNearestEven).SetInt64(1)
+	term := newFloat(p, big.ToNearestEven).SetInt64(1)
+	for i := int64(1); ; i++ {
+		term.Mul(term, r)
+		term.Quo(term, new(big.Float).SetInt64(i))
+		if term.Sign() == 0 || term.MantExp(nil) < -int(p) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	for i := 0; i < halvings; i++ {
+		sum.Mul(sum, sum)
+	}
+	return round(sum.SetMantExp(sum, int(k)), x), nil
+}
+
+// PowInt returns x**n for an integer exponent using binary exponentiation.
+// Results beyond the exponent range of big.Float are an error.
+func PowInt(x *big.Float, n *big.Int) (*big.Float, error) {
+	p := prec(x) + guardBits
+	if x.IsInf() || (n.Sign() < 0 && x.Sign() == 0) {
+		return nil, ErrDomain
+	}
+
+	z := newFloat(p, big.ToNearestEven).SetInt64(1)
+	b := newFloat(p, big.ToNearestEven).Set(x)
+	e := new(big.Int).Abs(n)
+	for i := 0; i < e.BitLen(); i++ {
+		if e.Bit(i) == 1 {
+			z.Mul(z, b)
+		}
+		b.Mul(b, b)
+	}
+	if z.IsInf() {
+		if n.Sign() > 0 {
+			return nil, ErrOverflow
+		}
+		return newFloat(prec(x), x.Mode()), nil
+	} else if n.Sign() < 0 {
+		z.Quo(newFloat(p, big.ToNearestEven).SetInt64(1), z)
+	}
+	if z = round(z, x); z.IsInf() {
+		return nil, ErrOverflow
+	}
+	return z, nil
+}
+
+// Pow returns x**y for real exponents. Negative bases are only supported
+// for integer exponents.
+func Pow(x, y *big.Float) (*big.Float, error) {
+	if x.IsInf() || y.IsInf() {
+		return nil, ErrDomain
+	} else if IsInt(y) {
+		n, _ := y.Int(nil)
+		return PowInt(x, n)
+	} else if x.Sign() < 0 {
+		return nil, ErrDomain
+	} else if x.Sign() == 0 {
+		if y.Sign() < 0 {
+			return nil, ErrDomain
+		}
+		return new(big.Float).SetPrec(prec(x)), nil
+	}
+
+	// x**y = e**(y·ln(x))
+	p := prec(x) + guardBits
+	ln, err := Log(newFloat(p, big.ToNearestEven).Set(x))
+	if err != nil {
+		return nil, err
+	}
+	t := ln.Mul(ln, y)
+	z, err := Exp(t)
+	if err == ErrDomain {
+		// The exponent is beyond the range of big.Float
+		if t.Sign() > 0 {
+			return nil, ErrOverflow
+		}
+		return newFloat(prec(x), x.Mode()), nil
+	} else if err != nil {
+		return nil, err
+	}
+	if z = round(z, x); z.IsInf() {
+		return nil, ErrOverflow
+	}
+	return z, nil
+}
+
+// Sqrt returns the square root of x.
+func Sqrt(x *big.Float) (*big.Float, error) {
+	return Root(x, 2)
+}
+
+// Cbrt returns the cube root of x.
+func Cbrt(x *big.Float) (*big.Float, error) {
+	return Root(x, 3)
+}
+
+// Root returns the nth root of x. Odd roots of negative numbers are negative.
+func Root(x *big.Float, n int64) (*big.Float, error) {
+	if n <= 0 {
+		return nil, ErrDomain
+	} else if n == 1 || x.Sign() == 0 {
+		return round(x, x), nil
+	} else if x.Sign() < 0 {
+		if n%2 == 0 {
+			return nil, ErrDomain
+		}
+		z, err := Root(new(big.Float).Neg(x), n)
+		if err != nil {
+			return nil, err
+		}
+		return z.Neg(z), nil
+	} else if n == 2 {
+		return newFloat(prec(x), x.Mode()).Sqrt(x), nil
+	}
+
+	// Newton's method: z = ((n-1)·z + x / z**(n-1)) / n
+	p := prec(x) + guardBits
+	bn := new(big.Float).SetInt64(n)
+	inv := newFloat(p, big.ToNearestEven).Quo(big.NewFloat(1), bn)
+	z, err := Pow(newFloat(p, big.ToNearestEven).Set(x), inv)
+	if err != nil {
+		return nil, err
+	}
+	nm1 := big.NewInt(n - 1)
+	bnm1 := new(big.Float).SetInt64(n - 1)
+	for i := 0; i < 8; i++ {
+		zn, _ := PowInt(z, nm1)
+		t := newFloat(p, big.ToNearestEven).Quo(x, zn)
+		z.Mul(z, bnm1).Add(z, t).Quo(z, bn)
+	}
+	return round(z, x), nil
+}
+
+// Pi returns π with the given precision.
+func Pi(prec uint) *big.Float {
+	// Machin's formula: π = 16·atan(1/5) - 4·atan(1/239)
+	p := prec + guardBits
+	a := atanSeries(newFloat(p, big.ToNearestEven).Quo(big.NewFloat(1), big.NewFloat(5)))
+	b := atanSeries(newFloat(p, big.ToNearestEven).Quo(big.NewFloat(1), big.NewFloat(239)))
+	a.Mul(a, big.NewFloat(16))
+	b.Mul(b, big.NewFloat(4))
+	return a.Sub(a, b).SetPrec(prec)
+}
+
+// E returns e with the given precision.
+func E(prec uint) *big.Float {
+	z, _ := Exp(newFloat(prec+guardBits, big.ToNearestEven).SetInt64(1))
+	return z.SetPrec(prec)
+}
@@ -0,0 +1,250 @@
+package bigmath
+
+import (
+	"math/big"
+	"testing"
+)
+
+// parse returns the decimal string as a float with the given precision.
+func parse(s string, prec uint) *big.Float {
+	f, _, err := big.ParseFloat(s, 10, prec, big.ToNearestEven)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func TestElementaryFunctions(t *testing.T) {
+	const prec = 256
+	one := parse("1", prec)
+	two := parse("2", prec)
+
+	tests := []struct {
+		name     string
+		fn       func() (*big.Float, error)
+		expected string
+	}{
+		{"exp(1)", func() (*big.Float, error) { return Exp(one) }, "2.718281828459045235360287471352662497757247093699959574966967627724"},
+		{"ln(2)", func() (*big.Float, error) { return Log(two) }, "0.693147180559945309417232121458176568075500134360255254120680009493"},
+		{"ln(1e-20)", func() (*big.Float, error) { return Log(parse("1e-20", prec)) }, "-46.05170185988091368035982909368728415202202977257545952066655801935"},
+		{"exp(-10)", func() (*big.Float, error) { return Exp(parse("-10", prec)) }, "0.00004539992976248485153559151556055061023791808886656496925907130565"},
+		{"2**0.5", func() (*big.Float, error) { return Pow(two, parse("0.5", prec)) }, "1.414213562373095048801688724209698078569671875376948073176679737990"},
+		{"cbrt(2)", func() (*big.Float, error) { return Root(two, 3) }, "1.259921049894873164767210607278228350570251464701507980081975112155"},
+		{"cbrt(-27)", func() (*big.Float, error) { return Root(parse("-27", prec), 3) }, "-3"},
+		{"2**-3", func() (*big.Float, error) { return PowInt(two, big.NewInt(-3)) }, "0.125"},
+		{"sqrt(2)", func() (*big.Float, error) { return Sqrt(two) }, "1.414213562373095048801688724209698078569671875376948073176679737990"},
+		{"log10(2)", func() (*big.Float, error) { return Log10(two) }, "0.301029995663981195213738894724493026768189881462108541310427461127"},
+		{"log10(1000)", func() (*big.Float, error) { return Log10(parse("1000", prec)) }, "3"},
+		{"log2(10)", func() (*big.Float, error) { return Log2(parse("10", prec)) }, "3.321928094887362347870319429489390175864831393024580612054756395815"},
+		{"pi", func() (*big.Float, error) { return Pi(prec), nil }, "3.141592653589793238462643383279502884197169399375105820974944592307"},
+		{"e", func() (*big.Float, error) { return E(prec), nil }, "2.718281828459045235360287471352662497757247093699959574966967627724"},
+		{"sin(1)", func() (*big.Float, error) { return Sin(one) }, "0.841470984807896506652502321630298999622563060798371065672751709991"},
+		{"cos(1)", func() (*big.Float, error) { return Cos(one) }, "0.540302305868139717400936607442976603732310420617922227670097255381"},
+		{"tan(1)", func() (*big.Float, error) { return Tan(one) }, "1.557407724654902230506974807458360173087250772381520038383946605698"},
+		{"sin(100)", func() (*big.Float, error) { return Sin(parse("100", prec)) }, "-0.506365641109758793656557610459785432065032721290657323443392473594"},
+		{"cos(1e6)", func() (*big.Float, error) { return Cos(parse("1e6", prec)) }, "0.936752127533144786938532535074918775708097804212365879720578341116"},
+		{"atan(1)", func() (*big.Float, error) { return Atan(one) }, "0.785398163397448309615660845819875721049292349843776455243736148076"},
+		{"atan(10)", func() (*big.Float, error) { return Atan(parse("10", prec)) }, "1.471127674303734591852875571761730851855306377183238262471963519343"},
+		{"asin(0.5)", func() (*big.Float, error) { return Asin(parse("0.5", prec)) }, "0.523598775598298873077107230546583814032861566562517636829157432051"},
+		{"acos(0.5)", func() (*big.Float, error) { return Acos(parse("0.5", prec)) }, "1.047197551196597746154214461093167628065723133125035273658314864102"},
+		{"acos(-0.3)", func() (*big.Float, error) { return Acos(parse("-0.3", prec)) }, "1.875488980810294127203324652867280609053144731394329297880450244900"},
+		{"sinh(1)", func() (*big.Float, error) { return Sinh(one) }, "1.175201193643801456882381850595600815155717981334095870229565413013"},
+		{"sinh(1e-30)", func() (*big.Float, error) { return Sinh(parse("1e-30", prec)) }, "1.000000000000000000000000000000000000000000000000000000000000166666e-30"},
+		{"cosh(1)", func() (*big.Float, error) { return Cosh(one) }, "1.543080634815243778477905620757061682601529112365863704737402214710"},
+		{"tanh(0.5)", func() (*big.Float, error) { return Tanh(parse("0.5", prec)) }, "0.462117157260009758502318483643672548730289280330113038552731815838"},
+		{"tanh(20)", func() (*big.Float, error) { return Tanh(parse("20", prec)) }, "0.999999999999999991503291489416822045438558191190987257130474564227"},
+		{"asinh(1)", func() (*big.Float, error) { return Asinh(one) }, "0.881373587019543025232609324979792309028160328261635410753295608653"},
+		{"acosh(2)", func() (*big.Float, error) { return Acosh(two) }, "1.316957896924816708625046347307968444026981971467516479768472256920"},
+		{"atanh(0.5)", func() (*big.Float, error) { return Atanh(parse("0.5", prec)) }, "0.549306144334054845697622618461262852323745278911374725867347166818"},
+	}
+
+	for _, test := range tests {
+		z, err := test.fn()
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		// Compare to 60 significant digits
+		expected := parse(test.expected, prec)
+		diff := new(big.Float).Sub(z, expected)
+		if diff.Sign() != 0 && diff.MantExp(nil)-expected.MantExp(nil) > -200 {
+			t.Errorf("%s: expected %s, got %s", test.name, expected.Text('g', 60), z.Text('g', 60))
+		}
+	}
+}
+
+func TestComplexFunctions(t *testing.T) {
+	const prec = 256
+	zero := parse("0", prec)
+	one := parse("1", prec)
+
+	tests := []struct {
+		name   string
+		fn     func() (*big.Float, *big.Float, error)
+		re, im string
+	}{
+		{"sqrt(-4)", func() (*big.Float, *big.Float, error) { return CSqrt(parse("-4", prec), zero) }, "0", "2"},
+		{"sqrt(3+4i)", func() (*big.Float, *big.Float, error) { return CSqrt(parse("3", prec), parse("4", prec)) }, "2", "1"},
+		{"sqrt(-3-4i)", func() (*big.Float, *big.Float, error) { return CSqrt(parse("-3", prec), parse("-4", prec)) }, "1", "-2"},
+		{"exp(πi)", func() (*big.Float, *big.Float, error) { return CExp(zero, Pi(prec)) }, "-1", "0"},
+		{"ln(-1)", func() (*big.Float, *big.Float, error) { return CLog(parse("-1", prec), zero) }, "0", "3.141592653589793238462643383279502884197169399375105820974944592307"},
+		{"ln(1+i)", func() (*big.Float, *big.Float, error) { return CLog(one, one) }, "0.346573590279972654708616060729088284037750067180127627060340004746", "0.785398163397448309615660845819875721049292349843776455243736148076"},
+		{"i**i", func() (*big.Float, *big.Float, error) { return CPow(zero, one, zero, one) }, "0.207879576350761908546955619834978770033877841631769608075135883055", "0"},
+		{"(1+i)**2", func() (*big.Float, *big.Float, error) { return CPow(one, one, parse("2", prec), zero) }, "0", "2"},
+	}
+
+	for _, test := range tests {
+		re, im, err := test.fn()
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		// Compare to about 60 digits, absolutely for parts which are zero
+		for _, part := range []struct{ z, expected *big.Float }{{re, parse(test.re, prec)}, {im, parse(test.im, prec)}} {
+			diff := new(big.Float).Sub(part.z, part.expected)
+			if diff.Sign() != 0 && diff.MantExp(nil)-part.expected.MantExp(nil) > -200 {
+				t.Errorf("%s: expected %s%+si, got %s%+si", test.name, test.re, test.im, re.Text('g', 60), im.Text('g', 60))
+				break
+			}
+		}
+	}
+
+	z, err := Atan2(one, parse("-1", prec))
+	if expected := parse("2.356194490192344928846982537459627163147877049531329365731208444230", prec); err != nil || new(big.Float).Sub(z, expected).MantExp(nil) > -200 {
+		t.Errorf("atan2(1, -1): expected %s, got %v", expected.Text('g', 60), z)
+	}
+	if z := Hypot(parse("3", prec), parse("4", prec)); z.Cmp(parse("5", prec)) != 0 {
+		t.Errorf("hypot(3, 4): expected 5, got %s", z.Text('g', 60))
+	}
+	if _, _, err := CLog(zero, zero); err != ErrDomain {
+		t.Errorf("ln(0): expected ErrDomain, got %v", err)
+	}
+}
+
+// matrix parses the rows of a matrix.
+func matrix(prec uint, rows ...[]string) [][]*big.Float {
+	m := make([][]*big.Float, len(rows))
+	for i, row := range rows {
+		m[i] = make([]*big.Float, len(row))
+		for j, el := range row {
+			m[i][j] = parse(el, prec)
+		}
+	}
+	return m
+}
+
+// near returns true if x and y agree to about 60 digits.
+func near(x, y *big.Float) bool {
+	diff := new(big.Float).Sub(x, y)
+	return diff.Sign() == 0 || diff.MantExp(nil) < -200
+}
+
+func TestMatrixFunctions(t *testing.T) {
+	const prec = 256
+	a := matrix(prec, []string{"1", "2"}, []string{"3", "4"}, []string{"5", "6"})
+	q, r := QR(a)
+	if len(q) != 3 || len(q[0]) != 2 || len(r) != 2 || len(r[0]) != 2 {
+		t.Fatalf("qr: expected 3x2 and 2x2 factors, got %dx%d and %dx%d", len(q), len(q[0]), len(r), len(r[0]))
+	}
+	for i := range a {
+		for j := range a[i] {
+			z := new(big.Float).SetPrec(prec)
+			for k := range r {
+				z.Add(z, new(big.Float).SetPrec(prec).Mul(q[i][k], r[k][j]))
+			}
+			if !near(z, a[i][j]) {
+				t.Errorf("qr: (Q·R)[%d][%d] expected %s, got %s", i, j, a[i][j].Text('g', 20), z.Text('g', 60))
+			}
+		}
+	}
+	if r[1][0].Sign() != 0 || r[0][0].Sign() <= 0 || r[1][1].Sign() <= 0 {
+		t.Errorf("qr: expected upper triangular R with a positive diagonal, got %v", r)
+	}
+
+	half := parse("0.866025403784438646763723170752936183471402626905190314027903489725", prec)
+	tests := []struct {
+		name   string
+		a      [][]*big.Float
+		re, im []string
+	}{
+		{"symmetric", matrix(prec, []string{"2", "1"}, []string{"1", "2"}), []string{"1", "3"}, []string{"0", "0"}},
+		{"rotation", matrix(prec, []string{"0", "-1"}, []string{"1", "0"}), []string{"0", "0"}, []string{"-1", "1"}},
+		{"triangular", matrix(prec, []string{"1", "5", "7"}, []string{"0", "2", "3"}, []string{"0", "0", "3"}), []string{"1", "2", "3"}, []string{"0", "0", "0"}},
+		{"permutation", matrix(prec, []string{"0", "0", "1"}, []string{"1", "0", "0"}, []string{"0", "1", "0"}), []string{"-0.5", "-0.5", "1"}, []string{"-" + half.Text('g', 70), half.Text('g', 70), "0"}},
+	}
+	for _, test := range tests {
+		im := make([][]*big.Float, len(test.a))
+		for i := range im {
+			im[i] = make([]*big.Float, len(test.a))
+			for j := range im[i] {
+				im[i][j] = new(big.Float).SetPrec(prec)
+			}
+		}
+		re, vim, err := Eigenvalues(test.a, im)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		for i := range re {
+			if !near(re[i], parse(test.re[i], prec)) || !near(vim[i], parse(test.im[i], prec)) {
+				t.Errorf("%s: eigenvalue %d expected %s%+si, got %s%+si", test.name, i, test.re[i], test.im[i], re[i].Text('g', 60), vim[i].Text('g', 60))
+			}
+		}
+	}
+}
+
+func TestDomainErrors(t *testing.T) {
+	if _, err := Log(big.NewFloat(-1)); err != ErrDomain {
+		t.Errorf("ln(-1): expected ErrDomain, got %v", err)
+	}
+	if _, err := Pow(big.NewFloat(-8), big.NewFloat(0.5)); err != ErrDomain {
+		t.Errorf("(-8)**0.5: expected ErrDomain, got %v", err)
+	}
+	if _, err := Root(big.NewFloat(-4), 2); err != ErrDomain {
+		t.Errorf("sqrt(-4): expected ErrDomain, got %v", err)
+	}
+	if _, err := Asin(big.NewFloat(1.5)); err != ErrDomain {
+		t.Errorf("asin(1.5): expected ErrDomain, got %v", err)
+	}
+	if _, err := Acosh(big.NewFloat(0.5)); err != ErrDomain {
+		t.Errorf("acosh(0.5): expected ErrDomain, got %v", err)
+	}
+	if _, err := Atanh(big.NewFloat(1)); err != ErrDomain {
+		t.Errorf("atanh(1): expected ErrDomain, got %v", err)
+	}
+	if _, err := Log10(big.NewFloat(0)); err != ErrDomain {
+		t.Errorf("log10(0): expected ErrDomain, got %v", err)
+	}
+}
+
+func TestPowOverflow(t *testing.T) {
+	two, half := big.NewFloat(2), big.NewFloat(0.5)
+	if _, err := PowInt(two, big.NewInt(10000000000)); err != ErrOverflow {
+		t.Errorf("2**10000000000: expected ErrOverflow, got %v", err)
+	}
+	if _, err := PowInt(half, big.NewInt(-10000000000)); err != ErrOverflow {
+		t.Errorf("0.5**-10000000000: expected ErrOverflow, got %v", err)
+	}
+	if z, err := PowInt(two, big.NewInt(-10000000000)); err != nil || z.Sign() != 0 {
+		t.Errorf("2**-10000000000: expected 0, got %v, %v", z, err)
+	}
+	if _, err := Pow(two, big.NewFloat(1e10+0.5)); err != ErrOverflow {
+		t.Errorf("2**(1e10+0.5): expected ErrOverflow, got %v", err)
+	}
+	if z, err := Pow(two, big.NewFloat(-1e10-0.5)); err != nil || z.Sign() != 0 {
+		t.Errorf("2**-(1e10+0.5): expected 0, got %v, %v", z, err)
+	}
+
+	inf := new(big.Float).SetInf(false)
+	if _, err := PowInt(inf, big.NewInt(2)); err != ErrDomain {
+		t.Errorf("Inf**2: expected ErrDomain, got %v", err)
+	}
+	if _, err := Pow(inf, half); err != ErrDomain {
+		t.Errorf("Inf**0.5: expected ErrDomain, got %v", err)
+	}
+	if _, err := Pow(big.NewFloat(1), inf); err != ErrDomain {
+		t.Errorf("1**Inf: expected ErrDomain, got %v", err)
+	}
+}
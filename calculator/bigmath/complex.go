@@ -0,0 +1,107 @@
+package bigmath
+
+import (
+	"math/big"
+)
+
+// The complex functions take and return the real and imaginary parts of
+// complex numbers. Both parts of the result have the precision and rounding
+// mode of the real part of the first argument.
+
+// CSqrt returns the principal square root of re + im·i.
+func CSqrt(re, im *big.Float) (*big.Float, *big.Float, error) {
+	if re.Sign() == 0 && im.Sign() == 0 {
+		return round(re, re), round(im, re), nil
+	}
+
+	// sqrt(z) = t + im/(2t)·i with t = sqrt((|z| + re) / 2) for re >= 0, the
+	// parts are swapped for re < 0 to avoid cancellation
+	p := prec(re) + guardBits
+	abs := Hypot(newFloat(p, big.ToNearestEven).Set(re), im)
+	t := newFloat(p, big.ToNearestEven).Abs(re)
+	t.Add(abs, t).SetMantExp(t, -1)
+	t.Sqrt(t)
+	u := newFloat(p, big.ToNearestEven).Quo(im, t)
+	u.SetMantExp(u, -1)
+	if re.Sign() >= 0 {
+		return round(t, re), round(u, re), nil
+	}
+
+	u.Abs(u)
+	if im.Signbit() {
+		t.Neg(t)
+	}
+	return round(u, re), round(t, re), nil
+}
+
+// CExp returns e**(re + im·i).
+func CExp(re, im *big.Float) (*big.Float, *big.Float, error) {
+	p := prec(re) + guardBits
+	ex, err := Exp(newFloat(p, big.ToNearestEven).Set(re))
+	if err != nil {
+		return nil, nil, err
+	}
+	if im.Sign() == 0 {
+		return round(ex, re), newFloat(prec(re), re.Mode()), nil
+	}
+
+	cos, err := sincos(im, p, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	sin, err := sincos(im, p, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return round(cos.Mul(cos, ex), re), round(sin.Mul(sin, ex), re), nil
+}
+
+// CLog returns the principal natural logarithm of re + im·i, ln|z| + arg(z)·i.
+func CLog(re, im *big.Float) (*big.Float, *big.Float, error) {
+	if re.Sign() == 0 && im.Sign() == 0 {
+		return nil, nil, ErrDomain
+	}
+
+	// ln|z| = ln(re² + im²) / 2
+	p := prec(re) + guardBits
+	a := newFloat(p, big.ToNearestEven).Mul(re, re)
+	a.Add(a, newFloat(p, big.ToNearestEven).Mul(im, im))
+	ln, err := Log(a)
+	if err != nil {
+		return nil, nil, err
+	}
+	arg, err := Atan2(newFloat(p, big.ToNearestEven).Set(im), re)
+	if err != nil {
+		return nil, nil, err
+	}
+	return round(ln.SetMantExp(ln, -1), re), round(arg, re), nil
+}
+
+// CPow returns (a + b·i)**(c + d·i) = e**((c + d·i)·ln(a + b·i)) for the
+// principal logarithm. Zero raised to a power with a positive real part is
+// zero.
+func CPow(a, b, c, d *big.Float) (*big.Float, *big.Float, error) {
+	if a.Sign() == 0 && b.Sign() == 0 {
+		if c.Sign() > 0 {
+			return newFloat(prec(a), a.Mode()), newFloat(prec(a), a.Mode()), nil
+		}
+		return nil, nil, ErrDomain
+	}
+
+	// The exponent of the result amplifies the error of the logarithm
+	p := prec(a) + 2*guardBits
+	lr, li, err := CLog(newFloat(p, big.ToNearestEven).Set(a), b)
+	if err != nil {
+		return nil, nil, err
+	}
+	re := newFloat(p, big.ToNearestEven).Mul(c, lr)
+	re.Sub(re, newFloat(p, big.ToNearestEven).Mul(d, li))
+	im := newFloat(p, big.ToNearestEven).Mul(c, li)
+	im.Add(im, newFloat(p, big.ToNearestEven).Mul(d, lr))
+
+	zr, zi, err := CExp(re, im)
+	if err != nil {
+		return nil, nil, err
+	}
+	return round(zr, a), round(zi, a), nil
+}
@@ -0,0 +1,312 @@
+package bigmath
+
+import (
+	"math/big"
+	"sort"
+)
+
+// The matrix functions take and return matrices as slices of rows. Results
+// have the precision and rounding mode of the first element of the first
+// argument.
+
+// maxIterations limits the number of QR steps spent on each eigenvalue.
+const maxIterations = 60
+
+// QR returns the reduced QR decomposition of a real m×n matrix computed with
+// Householder reflections. Q has min(m, n) orthonormal columns and R is upper
+// triangular with a non-negative diagonal.
+func QR(a [][]*big.Float) (q, r [][]*big.Float) {
+	ref := a[0][0]
+	m, n := len(a), len(a[0])
+	k := m
+	if n < k {
+		k = n
+	}
+
+	p := prec(ref) + guardBits
+	r = make([][]*big.Float, m)
+	q = make([][]*big.Float, m)
+	for i := range r {
+		r[i] = make([]*big.Float, n)
+		for j := range r[i] {
+			r[i][j] = newFloat(p, big.ToNearestEven).Set(a[i][j])
+		}
+		q[i] = make([]*big.Float, m)
+		for j := range q[i] {
+			q[i][j] = newFloat(p, big.ToNearestEven)
+		}
+		q[i][i].SetInt64(1)
+	}
+
+	for j := 0; j < k; j++ {
+		// The reflection maps the column below the diagonal onto the axis
+		norm := newFloat(p, big.ToNearestEven)
+		zero := true
+		for i := j; i < m; i++ {
+			norm.Add(norm, newFloat(p, big.ToNearestEven).Mul(r[i][j], r[i][j]))
+			zero = zero && (i == j || r[i][j].Sign() == 0)
+		}
+		if zero {
+			continue
+		}
+		norm.Sqrt(norm)
+		if r[j][j].Sign() > 0 {
+			norm.Neg(norm)
+		}
+
+		v := make([]*big.Float, m-j)
+		vv := newFloat(p, big.ToNearestEven)
+		for i := range v {
+			v[i] = newFloat(p, big.ToNearestEven).Set(r[j+i][j])
+			if i == 0 {
+				v[i].Sub(v[i], norm)
+			}
+			vv.Add(vv, newFloat(p, big.ToNearestEven).Mul(v[i], v[i]))
+		}
+		vv.SetMantExp(vv, -1)
+
+		// R = H·R and Q = Q·H with H = I - 2·v·vᵀ / (vᵀ·v)
+		for c := j; c < n; c++ {
+			reflect(v, vv, p, func(i int) *big.Float { return r[j+i][c] })
+		}
+		for row := 0; row < m; row++ {
+			reflect(v, vv, p, func(i int) *big.Float { return q[row][j+i] })
+		}
+		for i := j + 1; i < m; i++ {
+			r[i][j].SetInt64(0)
+		}
+	}
+
+	for j := 0; j < k; j++ {
+		if r[j][j].Sign() >= 0 {
+			continue
+		}
+		for c := j; c < n; c++ {
+			r[j][c].Neg(r[j][c])
+		}
+		for row := 0; row < m; row++ {
+			q[row][j].Neg(q[row][j])
+		}
+	}
+	return roundMatrix(q, m, k, ref), roundMatrix(r, k, n, ref)
+}
+
+// reflect applies the Householder reflection with the vector v, where vv is
+// half of vᵀ·v, to the vector whose elements are returned by x.
+func reflect(v []*big.Float, vv *big.Float, p uint, x func(i int) *big.Float) {
+	s := newFloat(p, big.ToNearestEven)
+	for i := range v {
+		s.Add(s, newFloat(p, big.ToNearestEven).Mul(v[i], x(i)))
+	}
+	s.Quo(s, vv)
+	for i := range v {
+		el := x(i)
+		el.Sub(el, newFloat(p, big.ToNearestEven).Mul(s, v[i]))
+	}
+}
+
+// roundMatrix returns the leading rows×cols block of a rounded to the
+// precision of ref.
+func roundMatrix(a [][]*big.Float, rows, cols int, ref *big.Float) [][]*big.Float {
+	z := make([][]*big.Float, rows)
+	for i := range z {
+		z[i] = make([]*big.Float, cols)
+		for j := range z[i] {
+			z[i][j] = round(a[i][j], ref)
+		}
+	}
+	return z
+}
+
+// cfloat is a complex number of the eigenvalue iteration.
+type cfloat struct {
+	re, im *big.Float
+}
+
+func (z cfloat) isZero() bool { return z.re.Sign() == 0 && z.im.Sign() == 0 }
+func (z cfloat) conj() cfloat { return cfloat{z.re, new(big.Float).Neg(z.im)} }
+
+func cadd(p uint, x, y cfloat) cfloat {
+	return cfloat{newFloat(p, big.ToNearestEven).Add(x.re, y.re), newFloat(p, big.ToNearestEven).Add(x.im, y.im)}
+}
+
+func csub(p uint, x, y cfloat) cfloat {
+	return cfloat{newFloat(p, big.ToNearestEven).Sub(x.re, y.re), newFloat(p, big.ToNearestEven).Sub(x.im, y.im)}
+}
+
+func cmul(p uint, x, y cfloat) cfloat {
+	re := newFloat(p, big.ToNearestEven).Mul(x.re, y.re)
+	re.Sub(re, newFloat(p, big.ToNearestEven).Mul(x.im, y.im))
+	im := newFloat(p, big.ToNearestEven).Mul(x.re, y.im)
+	im.Add(im, newFloat(p, big.ToNearestEven).Mul(x.im, y.re))
+	return cfloat{re, im}
+}
+
+// cscale returns x/r for a real r.
+func cscale(p uint, x cfloat, r *big.Float) cfloat {
+	return cfloat{newFloat(p, big.ToNearestEven).Quo(x.re, r), newFloat(p, big.ToNearestEven).Quo(x.im, r)}
+}
+
+// cabs2 returns |x|².
+func cabs2(p uint, x cfloat) *big.Float {
+	z := newFloat(p, big.ToNearestEven).Mul(x.re, x.re)
+	return z.Add(z, newFloat(p, big.ToNearestEven).Mul(x.im, x.im))
+}
+
+func csqrt(p uint, x cfloat) cfloat {
+	re, im, _ := CSqrt(x.re, x.im)
+	return cfloat{newFloat(p, big.ToNearestEven).Set(re), newFloat(p, big.ToNearestEven).Set(im)}
+}
+
+// Eigenvalues returns the eigenvalues of a square matrix with the real parts
+// re and the imaginary parts im, sorted by their real and then imaginary
+// parts. They are computed by the QR algorithm with Wilkinson shifts, which
+// is intended for small matrices. Imaginary parts of the eigenvalues of real
+// matrices which are within rounding error of zero are zero.
+func Eigenvalues(re, im [][]*big.Float) ([]*big.Float, []*big.Float, error) {
+	ref := re[0][0]
+	n := len(re)
+	p := prec(ref) + guardBits
+
+	isReal := true
+	a := make([][]cfloat, n)
+	norm := newFloat(p, big.ToNearestEven)
+	for i := range a {
+		a[i] = make([]cfloat, n)
+		for j := range a[i] {
+			a[i][j] = cfloat{newFloat(p, big.ToNearestEven).Set(re[i][j]), newFloat(p, big.ToNearestEven).Set(im[i][j])}
+			norm.Add(norm, cabs2(p, a[i][j]))
+			isReal = isReal && im[i][j].Sign() == 0
+		}
+	}
+	norm.Sqrt(norm)
+
+	// Rows whose elements left of the diagonal are below the tolerance are
+	// deflated
+	tol := newFloat(p, big.ToNearestEven).SetMantExp(norm, 8-int(p))
+	tol.Mul(tol, tol)
+
+	values := make([]cfloat, 0, n)
+	for last, iter := n-1, 0; last >= 0; {
+		if last == 0 {
+			values = append(values, a[0][0])
+			break
+		} else if deflated(a[last][:last], tol, p) {
+			values = append(values, a[last][last])
+			last, iter = last-1, 0
+			continue
+		} else if last == 1 {
+			l1, l2 := eigenvalues2(a, 0, p)
+			values = append(values, l1, l2)
+			break
+		} else if iter == maxIterations {
+			return nil, nil, ErrNoConvergence
+		}
+
+		iter++
+		l1, l2 := eigenvalues2(a, last-1, p)
+		d := a[last][last]
+		mu := l1
+		if cabs2(p, csub(p, l2, d)).Cmp(cabs2(p, csub(p, l1, d))) < 0 {
+			mu = l2
+		}
+		if iter%10 == 0 {
+			// An exceptional shift breaks cycles such as those of
+			// permutation matrices
+			r := newFloat(p, big.ToNearestEven).Sqrt(cabs2(p, a[last][last-1]))
+			mu = cadd(p, d, cfloat{newFloat(p, big.ToNearestEven).Mul(r, big.NewFloat(0.75)), newFloat(p, big.ToNearestEven).Mul(r, big.NewFloat(0.5))})
+		}
+		qrStep(a[:last+1], mu, p)
+	}
+
+	if isReal {
+		zero := newFloat(p, big.ToNearestEven).SetMantExp(norm, 8-int(prec(ref)))
+		for _, v := range values {
+			if cmpAbs(v.im, zero) <= 0 {
+				v.im.SetInt64(0)
+			}
+		}
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if c := values[i].re.Cmp(values[j].re); c != 0 {
+			return c < 0
+		}
+		return values[i].im.Cmp(values[j].im) < 0
+	})
+
+	vre := make([]*big.Float, n)
+	vim := make([]*big.Float, n)
+	for i, v := range values {
+		vre[i], vim[i] = round(v.re, ref), round(v.im, ref)
+	}
+	return vre, vim, nil
+}
+
+// deflated returns true if the squared magnitude of every element is at most
+// tol.
+func deflated(row []cfloat, tol *big.Float, p uint) bool {
+	for _, x := range row {
+		if cabs2(p, x).Cmp(tol) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// eigenvalues2 returns the eigenvalues of the 2×2 block [w x; y z] of a at
+// row and column i, (w + z)/2 ± sqrt(((w - z)/2)² + x·y).
+func eigenvalues2(a [][]cfloat, i int, p uint) (cfloat, cfloat) {
+	w, x := a[i][i], a[i][i+1]
+	y, z := a[i+1][i], a[i+1][i+1]
+
+	two := big.NewFloat(2)
+	mid := cscale(p, cadd(p, w, z), two)
+	h := cscale(p, csub(p, w, z), two)
+	disc := csqrt(p, cadd(p, cmul(p, h, h), cmul(p, x, y)))
+	return cadd(p, mid, disc), csub(p, mid, disc)
+}
+
+// qrStep replaces the square matrix a - μ·I = Q·R by R·Q + μ·I using Givens
+// rotations, which preserves the eigenvalues of a.
+func qrStep(a [][]cfloat, mu cfloat, p uint) {
+	n := len(a)
+	for i := range a {
+		a[i][i] = csub(p, a[i][i], mu)
+	}
+
+	type rotation struct {
+		i    int
+		c, s cfloat
+	}
+	var rotations []rotation
+	for j := 0; j < n-1; j++ {
+		for i := n - 1; i > j; i-- {
+			x, y := a[i-1][j], a[i][j]
+			if y.isZero() {
+				continue
+			}
+			r := newFloat(p, big.ToNearestEven).Add(cabs2(p, x), cabs2(p, y))
+			r.Sqrt(r)
+			c, s := cscale(p, x, r), cscale(p, y, r)
+			for k := j; k < n; k++ {
+				x, y := a[i-1][k], a[i][k]
+				a[i-1][k] = cadd(p, cmul(p, c.conj(), x), cmul(p, s.conj(), y))
+				a[i][k] = csub(p, cmul(p, c, y), cmul(p, s, x))
+			}
+			a[i][j] = cfloat{new(big.Float), new(big.Float)}
+			rotations = append(rotations, rotation{i, c, s})
+		}
+	}
+
+	for _, g := range rotations {
+		for k := 0; k < n; k++ {
+			x, y := a[k][g.i-1], a[k][g.i]
+			a[k][g.i-1] = cadd(p, cmul(p, x, g.c), cmul(p, y, g.s))
+			a[k][g.i] = csub(p, cmul(p, y, g.c.conj()), cmul(p, x, g.s.conj()))
+		}
+	}
+
+	for i := range a {
+		a[i][i] = cadd(p, a[i][i], mu)
+	}
+}
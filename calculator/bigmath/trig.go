@@ -0,0 +1,404 @@
+package bigmath
+
+import (
+	"math/big"
+)
+
+// atanSeries returns atan(x) for |x| < 1 using the Taylor series. The result
+// has the precision of x.
+func atanSeries(x *big.Float) *big.Float {
+	p := prec(x)
+	x2 := newFloat(p, big.ToNearestEven).Mul(x, x)
+	term := newFloat(p, big.ToNearestEven).Set(x)
+	sum := newFloat(p, big.ToNearestEven).Set(x)
+	t := newFloat(p, big.ToNearestEven)
+	for i := int64(3); ; i += 2 {
+		term.Neg(term.Mul(term, x2))
+		t.Quo(term, new(big.Float).SetInt64(i))
+		if t.Sign() == 0 || t.MantExp(nil)-sum.MantExp(nil) < -int(p) {
+			break
+		}
+		sum.Add(sum, t)
+	}
+	return sum
+}
+
+// sinSeries returns sin(x) if odd is true and cos(x) otherwise using the
+// Taylor series. The result has the precision of x.
+func sinSeries(x *big.Float, odd bool) *big.Float {
+	p := prec(x)
+	x2 := newFloat(p, big.ToNearestEven).Mul(x, x)
+	term := newFloat(p, big.ToNearestEven).SetInt64(1)
+	i := int64(1)
+	if odd {
+		term.Set(x)
+		i = 2
+	}
+	sum := newFloat(p, big.ToNearestEven).Set(term)
+	for ; ; i += 2 {
+		term.Neg(term.Mul(term, x2))
+		term.Quo(term, new(big.Float).SetInt64(i*(i+1)))
+		if term.Sign() == 0 || term.MantExp(nil)-sum.MantExp(nil) < -int(p) {
+			break
+		}
+		sum.Add(sum, term)
+	}
+	return sum
+}
+
+// cmpAbs compares |x| and y.
+func cmpAbs(x, y *big.Float) int {
+	return new(big.Float).Abs(x).Cmp(y)
+}
+
+// exponent returns the binary exponent of x.
+func exponent(x *big.Float) int {
+	return x.MantExp(nil)
+}
+
+// reduce returns r and q such that x = n·π/2 + r with |r| <= π/4 and q = n
+// mod 4. The precision of r is increased by the exponent of x so that r
+// keeps p bits after the reduction.
+func reduce(x *big.Float, p uint) (*big.Float, int, error) {
+	if e := exponent(x); e > 0 {
+		if e > 1<<20 {
+			return nil, 0, ErrDomain
+		}
+		p += uint(e)
+	}
+
+	halfPi := Pi(p)
+	halfPi.SetMantExp(halfPi, -1)
+	n, _ := newFloat(p, big.ToNearestEven).Quo(x, halfPi).Int(nil)
+	r := newFloat(p, big.ToNearestEven).Quo(x, halfPi)
+	r.Sub(r, new(big.Float).SetInt(n))
+	if r.Cmp(big.NewFloat(0.5)) > 0 {
+		n.Add(n, big.NewInt(1))
+	} else if r.Cmp(big.NewFloat(-0.5)) < 0 {
+		n.Sub(n, big.NewInt(1))
+	}
+
+	r.Mul(halfPi, new(big.Float).SetInt(n))
+	r.Sub(newFloat(p, big.ToNearestEven).Set(x), r)
+	q := new(big.Int).Mod(n, big.NewInt(4)).Int64()
+	return r, int(q), nil
+}
+
+// sincos returns sin(x) if sine is true and cos(x) otherwise with p bits of
+// precision.
+func sincos(x *big.Float, p uint, sine bool) (*big.Float, error) {
+	if x.IsInf() {
+		return nil, ErrDomain
+	}
+	r, q, err := reduce(x, p)
+	if err != nil {
+		return nil, err
+	}
+	if !sine {
+		q++
+	}
+
+	// sin(x) for each quadrant is sin(r), cos(r), -sin(r) and -cos(r)
+	z := sinSeries(r, q%2 == 0)
+	if q%4 >= 2 {
+		z.Neg(z)
+	}
+	return z, nil
+}
+
+// Sin returns the sine of x.
+func Sin(x *big.Float) (*big.Float, error) {
+	if x.Sign() == 0 {
+		return round(x, x), nil
+	}
+	z, err := sincos(x, prec(x)+guardBits, true)
+	if err != nil {
+		return nil, err
+	}
+	return round(z, x), nil
+}
+
+// Cos returns the cosine of x.
+func Cos(x *big.Float) (*big.Float, error) {
+	z, err := sincos(x, prec(x)+guardBits, false)
+	if err != nil {
+		return nil, err
+	}
+	return round(z, x), nil
+}
+
+// Tan returns the tangent of x.
+func Tan(x *big.Float) (*big.Float, error) {
+	if x.Sign() == 0 {
+		return round(x, x), nil
+	}
+	p := prec(x) + guardBits
+	s, err := sincos(x, p, true)
+	if err != nil {
+		return nil, err
+	}
+	c, err := sincos(x, p, false)
+	if err != nil {
+		return nil, err
+	} else if c.Sign() == 0 {
+		return nil, ErrDomain
+	}
+	return round(s.Quo(s, c), x), nil
+}
+
+// Atan returns the arctangent of x in the range [-π/2, π/2].
+func Atan(x *big.Float) (*big.Float, error) {
+	if x.Sign() == 0 {
+		return round(x, x), nil
+	}
+	p := prec(x) + guardBits
+	if x.IsInf() {
+		z := Pi(p)
+		z.SetMantExp(z, -1)
+		if x.Signbit() {
+			z.Neg(z)
+		}
+		return round(z, x), nil
+	}
+
+	// atan(x) = π/2 - atan(1/x) for |x| > 1
+	z := newFloat(p, big.ToNearestEven).Abs(x)
+	invert := z.Cmp(big.NewFloat(1)) > 0
+	if invert {
+		z.Quo(big.NewFloat(1), z)
+	}
+
+	// atan(z) = 2·atan(z / (1 + sqrt(1 + z²))) halves the argument so the
+	// series converges quickly
+	const halvings = 4
+	for i := 0; i < halvings; i++ {
+		t := newFloat(p, big.ToNearestEven).Mul(z, z)
+		t.Add(t, big.NewFloat(1)).Sqrt(t).Add(t, big.NewFloat(1))
+		z.Quo(z, t)
+	}
+	z = atanSeries(z)
+	z.SetMantExp(z, halvings)
+
+	if invert {
+		halfPi := Pi(p)
+		halfPi.SetMantExp(halfPi, -1)
+		z.Sub(halfPi, z)
+	}
+	if x.Signbit() {
+		z.Neg(z)
+	}
+	return round(z, x), nil
+}
+
+// Asin returns the arcsine of x in the range [-π/2, π/2].
+func Asin(x *big.Float) (*big.Float, error) {
+	one := big.NewFloat(1)
+	if cmpAbs(x, one) > 0 {
+		return nil, ErrDomain
+	} else if cmpAbs(x, one) == 0 {
+		return Atan(newFloat(prec(x), x.Mode()).SetInf(x.Signbit()))
+	}
+
+	// asin(x) = atan(x / sqrt((1 - x)·(1 + x)))
+	p := prec(x) + guardBits
+	a := newFloat(p, big.ToNearestEven).Sub(one, x)
+	b := newFloat(p, big.ToNearestEven).Add(one, x)
+	a.Sqrt(a.Mul(a, b))
+	z, err := Atan(a.Quo(x, a))
+	if err != nil {
+		return nil, err
+	}
+	return round(z, x), nil
+}
+
+// Acos returns the arccosine of x in the range [0, π].
+func Acos(x *big.Float) (*big.Float, error) {
+	one := big.NewFloat(1)
+	if cmpAbs(x, one) > 0 {
+		return nil, ErrDomain
+	}
+	p := prec(x) + guardBits
+	if x.Cmp(big.NewFloat(-1)) == 0 {
+		return round(Pi(p), x), nil
+	}
+
+	// acos(x) = 2·atan(sqrt((1 - x) / (1 + x)))
+	a := newFloat(p, big.ToNearestEven).Sub(one, x)
+	b := newFloat(p, big.ToNearestEven).Add(one, x)
+	a.Sqrt(a.Quo(a, b))
+	z, err := Atan(a)
+	if err != nil {
+		return nil, err
+	}
+	return round(z.SetMantExp(z, 1), x), nil
+}
+
+// hyperbolicPrec returns the working precision of the hyperbolic functions.
+// Arguments close to zero lose bits to cancellation which are made up by
+// extra precision.
+func hyperbolicPrec(x *big.Float) uint {
+	p := prec(x) + guardBits
+	if e := exponent(x); e < 0 {
+		p += uint(-e)
+	}
+	return p
+}
+
+// expPair returns e**x and e**-x with the given precision.
+func expPair(x *big.Float, p uint) (*big.Float, *big.Float, error) {
+	ex, err := Exp(newFloat(p, big.ToNearestEven).Set(x))
+	if err != nil {
+		return nil, nil, err
+	}
+	return ex, newFloat(p, big.ToNearestEven).Quo(big.NewFloat(1), ex), nil
+}
+
+// Sinh returns the hyperbolic sine of x.
+func Sinh(x *big.Float) (*big.Float, error) {
+	if x.Sign() == 0 || x.IsInf() {
+		return round(x, x), nil
+	}
+	ex, inv, err := expPair(x, hyperbolicPrec(x))
+	if err != nil {
+		return nil, err
+	}
+	ex.Sub(ex, inv)
+	return round(ex.SetMantExp(ex, -1), x), nil
+}
+
+// Cosh returns the hyperbolic cosine of x.
+func Cosh(x *big.Float) (*big.Float, error) {
+	if x.IsInf() {
+		return newFloat(prec(x), x.Mode()).SetInf(false), nil
+	}
+	ex, inv, err := expPair(x, prec(x)+guardBits)
+	if err != nil {
+		return nil, err
+	}
+	ex.Add(ex, inv)
+	return round(ex.SetMantExp(ex, -1), x), nil
+}
+
+// Tanh returns the hyperbolic tangent of x.
+func Tanh(x *big.Float) (*big.Float, error) {
+	if x.Sign() == 0 {
+		return round(x, x), nil
+	}
+
+	// tanh(|x|) = 1 - 2 / (e**2|x| + 1) which is 1 to any precision once
+	// e**2|x| overflows
+	p := hyperbolicPrec(x)
+	t := newFloat(p, big.ToNearestEven).Abs(x)
+	z := newFloat(p, big.ToNearestEven).SetInt64(1)
+	if e2, err := Exp(t.SetMantExp(t, 1)); err == nil && !e2.IsInf() {
+		e2.Quo(big.NewFloat(2), e2.Add(e2, big.NewFloat(1)))
+		z.Sub(z, e2)
+	}
+	if x.Signbit() {
+		z.Neg(z)
+	}
+	return round(z, x), nil
+}
+
+// Asinh returns the inverse hyperbolic sine of x.
+func Asinh(x *big.Float) (*big.Float, error) {
+	if x.Sign() == 0 || x.IsInf() {
+		return round(x, x), nil
+	}
+
+	// asinh(|x|) = ln(|x| + sqrt(x² + 1))
+	p := hyperbolicPrec(x)
+	a := newFloat(p, big.ToNearestEven).Abs(x)
+	t := newFloat(p, big.ToNearestEven).Mul(a, a)
+	t.Add(t, big.NewFloat(1)).Sqrt(t)
+	z, err := Log(t.Add(t, a))
+	if err != nil {
+		return nil, err
+	}
+	if x.Signbit() {
+		z.Neg(z)
+	}
+	return round(z, x), nil
+}
+
+// Acosh returns the inverse hyperbolic cosine of x for x >= 1.
+func Acosh(x *big.Float) (*big.Float, error) {
+	if x.Cmp(big.NewFloat(1)) < 0 {
+		return nil, ErrDomain
+	} else if x.IsInf() {
+		return round(x, x), nil
+	}
+
+	// acosh(x) = ln(x + sqrt((x - 1)·(x + 1)))
+	p := prec(x) + 2*guardBits
+	a := newFloat(p, big.ToNearestEven).Sub(x, big.NewFloat(1))
+	b := newFloat(p, big.ToNearestEven).Add(x, big.NewFloat(1))
+	a.Sqrt(a.Mul(a, b))
+	z, err := Log(a.Add(a, x))
+	if err != nil {
+		return nil, err
+	}
+	return round(z, x), nil
+}
+
+// Atanh returns the inverse hyperbolic tangent of x for |x| < 1.
+func Atanh(x *big.Float) (*big.Float, error) {
+	one := big.NewFloat(1)
+	if cmpAbs(x, one) >= 0 {
+		return nil, ErrDomain
+	} else if x.Sign() == 0 {
+		return round(x, x), nil
+	}
+
+	// atanh(x) = ln((1 + x) / (1 - x)) / 2
+	p := hyperbolicPrec(x)
+	a := newFloat(p, big.ToNearestEven).Add(one, x)
+	b := newFloat(p, big.ToNearestEven).Sub(one, x)
+	z, err := Log(a.Quo(a, b))
+	if err != nil {
+		return nil, err
+	}
+	return round(z.SetMantExp(z, -1), x), nil
+}
+
+// Atan2 returns the argument of the point (x, y) in the range [-π, π]. The
+// result has the precision of y.
+func Atan2(y, x *big.Float) (*big.Float, error) {
+	p := prec(y) + guardBits
+	if x.Sign() == 0 {
+		if y.Sign() == 0 {
+			return newFloat(prec(y), y.Mode()), nil
+		}
+		z := Pi(p)
+		z.SetMantExp(z, -1)
+		if y.Sign() < 0 {
+			z.Neg(z)
+		}
+		return round(z, y), nil
+	}
+
+	z, err := Atan(newFloat(p, big.ToNearestEven).Quo(y, x))
+	if err != nil {
+		return nil, err
+	}
+	if x.Sign() < 0 {
+		// atan2(y, x) = atan(y / x) ± π in the left half-plane
+		if y.Signbit() {
+			z.Sub(z, Pi(p))
+		} else {
+			z.Add(z, Pi(p))
+		}
+	}
+	return round(z, y), nil
+}
+
+// Hypot returns sqrt(x² + y²) with the precision of x.
+func Hypot(x, y *big.Float) *big.Float {
+	p := prec(x) + guardBits
+	a := newFloat(p, big.ToNearestEven).Mul(x, x)
+	b := newFloat(p, big.ToNearestEven).Mul(y, y)
+	if a.IsInf() || b.IsInf() {
+		return newFloat(prec(x), x.Mode()).SetInf(false)
+	}
+	return round(a.Sqrt(a.Add(a, b)), x)
+}
@@ -0,0 +1,168 @@
+// Package check implements a static pass over parsed worksheets which
+// reports type and dimension errors before evaluation.
+//
+// Types are named as by ast.TypeOf. Expressions whose type cannot be known
+// before evaluation have the empty type, which is compatible with every type,
+// so the checker only reports errors which evaluation would also encounter.
+// Quantities are typed by their dimension, resolved against the standard
+// units and the units declared by the worksheet.
+package check
+
+import (
+	"fmt"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/units"
+	"github.com/eliquious/lexer"
+)
+
+// Error is a type error found by the checker.
+type Error struct {
+	Message string
+	Pos     lexer.Pos
+}
+
+// Error returns the string representation of the error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at line %d, char %d", e.Message, e.Pos.Line+1, e.Pos.Char+1)
+}
+
+// symbol is a name declared in a checked worksheet.
+type symbol struct {
+	typ      string
+	fn       *ast.FunctionDeclaration
+	alias    *ast.TypeAnnotation
+	constant bool
+
+	// isType is set for struct and enum declarations
+	isType bool
+	strct  *ast.StructDeclaration
+	enum   *ast.EnumDeclaration
+}
+
+// scope holds the symbols of a block. Function scopes hold the variables
+// declared with `var` in their blocks.
+type scope struct {
+	parent   *scope
+	symbols  map[string]*symbol
+	function bool
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{parent: parent, symbols: make(map[string]*symbol)}
+}
+
+// lookup returns the symbol bound to the name in the nearest scope.
+func (s *scope) lookup(name string) (*symbol, bool) {
+	for ; s != nil; s = s.parent {
+		if sym, ok := s.symbols[name]; ok {
+			return sym, true
+		}
+	}
+	return nil, false
+}
+
+// functionScope returns the nearest enclosing function scope.
+func (s *scope) functionScope() *scope {
+	for !s.function && s.parent != nil {
+		s = s.parent
+	}
+	return s
+}
+
+// Checker validates statements before they are evaluated. Declarations are
+// remembered across calls to Check so that a worksheet may be checked one
+// statement at a time.
+type Checker struct {
+	scope  *scope
+	errors []*Error
+
+	// units holds the standard units and those declared by the worksheet or
+	// the function being checked. Declared units whose dimension cannot be
+	// resolved are opaque.
+	units  *units.Registry
+	opaque map[string]bool
+
+	// dims maps the names of quantity types to their dimensions
+	dims map[string]units.Dimension
+
+	// bodies counts the function bodies being checked. Their names are
+	// resolved when the function is called, so they may refer to names
+	// declared later and undefined names are not reported.
+	bodies int
+}
+
+// New returns a checker with the standard units and physical constants
+// declared. Like the worksheet of an environment, the statements are checked
+// in a function scope nested in the scope of the constants.
+func New() *Checker {
+	c := &Checker{
+		scope:  newScope(nil),
+		units:  units.Standard().Extend(),
+		opaque: make(map[string]bool),
+		dims:   make(map[string]units.Dimension),
+	}
+	c.declareConstants(units.Constants)
+	c.scope = newScope(c.scope)
+	c.scope.function = true
+	return c
+}
+
+// Check returns the type errors of a statement.
+func (c *Checker) Check(expr ast.Expression) []*Error {
+	c.errors = nil
+	c.infer(expr)
+	return c.errors
+}
+
+// CheckProgram returns the type errors of every statement of a worksheet in
+// the order they were found.
+func (c *Checker) CheckProgram(stmts []ast.Expression) []*Error {
+	var errs []*Error
+	for _, stmt := range stmts {
+		errs = append(errs, c.Check(stmt)...)
+	}
+	return errs
+}
+
+// errorf records an error at the given position.
+func (c *Checker) errorf(pos lexer.Pos, format string, args ...interface{}) {
+	c.errors = append(c.errors, &Error{Message: fmt.Sprintf(format, args...), Pos: pos})
+}
+
+// declare binds a symbol in the current scope.
+func (c *Checker) declare(name string, sym *symbol) {
+	c.scope.symbols[name] = sym
+}
+
+// bind declares a symbol in the scope unless the name is bound to a
+// constant in it.
+func (c *Checker) bind(s *scope, pos lexer.Pos, name string, sym *symbol) {
+	if old, ok := s.symbols[name]; ok && old.constant {
+		c.errorf(pos, "cannot assign to constant '%s'", name)
+		return
+	}
+	s.symbols[name] = sym
+}
+
+// push enters a new block scope and returns a function restoring the
+// enclosing scope.
+func (c *Checker) push() func() {
+	outer := c.scope
+	c.scope = newScope(outer)
+	return func() { c.scope = outer }
+}
+
+// pushFunction enters the scope of a function body, whose unit declarations
+// are local to it, and returns a function restoring the enclosing scope.
+func (c *Checker) pushFunction() func() {
+	pop, outer := c.push(), c.units
+	c.scope.function = true
+	c.units = outer.Extend()
+	c.bodies++
+	return func() {
+		pop()
+		c.units = outer
+		c.bodies--
+	}
+}
@@ -0,0 +1,381 @@
+package check
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/parser"
+)
+
+// checkStrings checks each statement with a single checker and returns the
+// errors of the last statement.
+func checkStrings(t *testing.T, inputs ...string) []*Error {
+	c := New()
+	var errs []*Error
+	for _, input := range inputs {
+		expr, err := parser.ParseExpression(input)
+		if err != nil {
+			t.Fatalf("parse %q: %s", input, err)
+		}
+		errs = c.Check(expr)
+	}
+	return errs
+}
+
+func TestGenerics(t *testing.T) {
+	decls := []string{
+		"func same<A>(a A, b A) A = a",
+		"func first<A>(xs []A) A -> xs[0]",
+		"type Filter = func [A] (a A, i int) -> bool",
+		"func keep<A>(xs []A, f Filter) []A -> filter(xs, f)",
+		"func twice(f func (x float) -> float, x float) -> f(f(x))",
+		"func inc(x float) -> x + 1",
+		"func label(s string) -> s",
+	}
+
+	valid := []string{
+		"same(1, 2)",
+		`same("a", "b")`,
+		"same(abs(-1), 1)",
+		"same(1 m, 2 ft)",
+		"same(first([1, 2]), 3)",
+		"same([1], [])",
+		"keep([1, 2], (a, i) -> a > 1)",
+		"keep([1, 2], (a, i) -> unknown(a))",
+		"twice(inc, 1)",
+		"[1, 2].keep((a, i) -> true)",
+		`var s = label("a")`,
+		"func g<A>(a A) A -> a",
+		"func g<A>(xs []A) A -> xs[0]",
+		"func g<A>(a A) []A -> [a, a]",
+	}
+	for _, input := range valid {
+		if errs := checkStrings(t, append(decls, input)...); len(errs) != 0 {
+			t.Errorf("%q: unexpected errors %v", input, errs)
+		}
+	}
+
+	invalid := []struct {
+		input   string
+		message string
+	}{
+		{`same(1, "a")`, "type parameter A of same inferred as both number and string at line 1, char 1"},
+		{`same(first([1]), "a")`, "type parameter A of same inferred as both number and string"},
+		{`first([true])  + same(true, 1)`, "inferred as both boolean and number"},
+		{"first(1)", "cannot use number as []A in argument to first"},
+		{"same(1)", "same expects 2 argument(s), found 1"},
+		{"keep([1], (a) -> true)", "cannot use lambda with 1 parameter(s) as func [A] (a A, i int) -> bool"},
+		{"keep([1], (a, i) -> 1)", "cannot use lambda returning number"},
+		{"keep([1], 5)", "cannot use number as func [A] (a A, i int) -> bool"},
+		{"twice(same, 1)", "cannot use same with 2 parameter(s)"},
+		{"label(1)", "cannot use number as string in argument to label"},
+		{"[1].keep((a) -> true)", "cannot use lambda with 1 parameter(s)"},
+		{"if true { label(1) }", "cannot use number as string"},
+		{"func g<A>(a A) A -> 5", "cannot return number from g declared to return A at line 1, char 21"},
+		{`func g<A>(a A) []A -> ["a"]`, "cannot return []string from g declared to return []A"},
+	}
+	for _, test := range invalid {
+		errs := checkStrings(t, append(decls, test.input)...)
+		if len(errs) == 0 {
+			t.Errorf("%q: expected error %q", test.input, test.message)
+		} else if !strings.Contains(errs[0].Error(), test.message) {
+			t.Errorf("%q: expected error %q, got %q", test.input, test.message, errs[0])
+		}
+	}
+}
+
+func TestScopes(t *testing.T) {
+	// Declarations are remembered between statements
+	if errs := checkStrings(t, "func label(s string) -> s", `var name = "x"`, "label(name)"); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if errs := checkStrings(t, "func label(s string) -> s", "var n = 1", "label(n)"); len(errs) != 1 {
+		t.Errorf("expected an error, got %v", errs)
+	}
+
+	// Names declared with let in blocks do not escape
+	if errs := checkStrings(t, "func label(s string) -> s", "var n = 1", `if true { let n = "x"; label(n) }`); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if errs := checkStrings(t, "func label(s string) -> s", "var n = 1", `if true { let n = "x" }`, "label(n)"); len(errs) != 1 {
+		t.Errorf("expected an error, got %v", errs)
+	}
+	if errs := checkStrings(t, "if true { let n = 1 }", "n"); len(errs) != 1 {
+		t.Errorf("expected an error, got %v", errs)
+	}
+
+	// Names declared with var are bound to the function, and have an unknown
+	// type if they replace a variable of another type
+	if errs := checkStrings(t, "func label(s string) -> s", "var n = 1", `if true { var n = "x" }`, "label(n)"); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if errs := checkStrings(t, "func label(s string) -> s", `if true { var n = 1 }`, "label(n)"); len(errs) != 1 {
+		t.Errorf("expected an error, got %v", errs)
+	}
+
+	// Function bodies are checked with typed parameters
+	if errs := checkStrings(t, "func label(s string) -> s", "func f(x float) -> label(x)"); len(errs) != 1 {
+		t.Errorf("expected an error, got %v", errs)
+	}
+}
+
+func TestNames(t *testing.T) {
+	decls := []string{
+		"var x = 1",
+		"const K = 1",
+		"func f(a float) -> a + later",
+		"import physics",
+		`import "lib/geometry.calc"`,
+	}
+
+	valid := []string{
+		"x + 1",
+		"x = 2",
+		"K + 1",
+		"var K2 = K",
+		"pi * e",
+		"sqrt(16) + SQRT(16)",
+		"filter([1], (a, i) -> a > x)",
+		"physics.force(1 kg)",
+		"geometry.area",
+		"G * c",
+		"var c = 1",
+		"if true { const K = 2 }",
+		"for i in range(3) { i + x }",
+		"func g() -> { var y = 1; y = 2 }",
+	}
+	for _, input := range valid {
+		if errs := checkStrings(t, append(decls, input)...); len(errs) != 0 {
+			t.Errorf("%q: unexpected errors %v", input, errs)
+		}
+	}
+
+	invalid := []struct {
+		input   string
+		message string
+	}{
+		{"undefinedvar + 1", "undefined: undefinedvar at line 1, char 1"},
+		{"undefined_function(1, 2)", "undefined: undefined_function at line 1, char 1"},
+		{"y = 3", "undefined: y at line 1, char 1"},
+		{"pi = 3", "undefined: pi"},
+		{"[1, y]", "undefined: y at line 1, char 5"},
+		{"K = 2", "cannot assign to constant 'K' at line 1, char 1"},
+		{"const K = 2", "cannot assign to constant 'K'"},
+		{"var K = 2", "cannot assign to constant 'K'"},
+		{"func K() -> 1", "cannot assign to constant 'K'"},
+		{"c = 1", "cannot assign to constant 'c'"},
+		{"G_uncertainty = 1", "cannot assign to constant 'G_uncertainty'"},
+		{"func g() -> { K = 2 }", "cannot assign to constant 'K'"},
+	}
+	for _, test := range invalid {
+		errs := checkStrings(t, append(decls, test.input)...)
+		if len(errs) == 0 {
+			t.Errorf("%q: expected error %q", test.input, test.message)
+		} else if !strings.Contains(errs[0].Error(), test.message) {
+			t.Errorf("%q: expected error %q, got %q", test.input, test.message, errs[0])
+		}
+	}
+}
+
+func TestDimensions(t *testing.T) {
+	decls := []string{
+		"func force(m kg, a m/s^2) -> m * a",
+		"func area(w m, h m) m^2 = w * h",
+		"unit Furlong (fur) { 1 = 201.168 m }",
+		"conversion 1 ly = 9460730472580800 m",
+		"var speed = 10 m / 2 s",
+		"func local() -> { unit League (lea) { 1 = 4828 m }; 1 lea }",
+	}
+
+	valid := []string{
+		"1 m + 2 ft",
+		"1 km / 1 h + 3 m/s",
+		"(4 m^2) ** 0.5 + 1 m",
+		"(2 m) ** 2 + 1 m^2",
+		"speed * 2 s + 1 m",
+		"force(1 kg, 9.81 m/s^2) + 1 N",
+		"area(1 m, 2 m) + 1 m^2",
+		"1 fur to m",
+		"1 ly + 1 m",
+		"g_n * 1 kg to N",
+		"G + G_uncertainty",
+		"1 m / 1 ft + 1",
+		"[1 m, 2 m] * 2",
+		"[1 m, 2 m] + 1 ft",
+		"1 m - [1 ft]",
+		"[1, 2] * 1 m + [1 m, 2 m]",
+		"[1 m, 2 m] ** 2 + 1 m^2",
+		"[[1 m], [2 m]] / 1 s + [1 m/s]",
+		`["a"] + "b"`,
+		"1 m < 2 ft",
+		"(3 + 4i) * 1 ohm + 2 ohm",
+		"2 ** 1i + 1",
+		"[1, 2; 3, 4] * [1, 1] + [1, 2]",
+		"-[1i, 2; 3, 4] ** 2",
+		"transpose([1, 2; 3, 4]) * 2",
+		"[1, 2; 3, 4][0][1] + 1",
+		"sin(30 deg) + cos([1, 2])[0]",
+		"sqrt(4 m^2) + 1 m",
+		"range(1, 10, 2)",
+		"func g(x m) ft -> 2 * x",
+		`"a" + "b"`,
+	}
+	for _, input := range valid {
+		if errs := checkStrings(t, append(decls, input)...); len(errs) != 0 {
+			t.Errorf("%q: unexpected errors %v", input, errs)
+		}
+	}
+
+	invalid := []struct {
+		input   string
+		message string
+	}{
+		{"1 m + 1 s", "cannot add quantity(m) and quantity(s): incompatible dimensions at line 1, char 5"},
+		{"1 m - 1", "cannot subtract quantity(m) and number"},
+		{"speed + 1 m", "cannot add quantity(m/s) and quantity(m)"},
+		{"1 m < 1 kg", "cannot compare quantity(m) and quantity(kg)"},
+		{"1 m to s", "cannot convert quantity(m) to s: incompatible dimensions"},
+		{"c_uncertainty + 1 m", "cannot add quantity(m/s) and quantity(m)"},
+		{"1 furlong", "unknown unit: furlong"},
+		{"1 lea", "unknown unit: lea"},
+		{"(2 m) ** 0.5", "cannot raise quantity(m) to the power of 1/2"},
+		{"force(1 kg, 1 m)", "cannot use quantity(m) as m/s^2 in argument to force"},
+		{"force(1, 1 m/s^2)", "cannot use number as kg in argument to force"},
+		{"area(1 m, 1 m) + 1 m", "cannot add quantity(m^2) and quantity(m)"},
+		{"conversion 1 m = 1 s", "cannot convert m to s: incompatible dimensions"},
+		{`"a" * 2`, "operator * not defined on string"},
+		{`"t=" + 5 s`, "cannot concatenate string and quantity(s): operands must be strings"},
+		{`"a" - "b"`, "operator - not defined on string"},
+		{"[1, 2] + 1 m", "cannot add number and quantity(m): incompatible dimensions at line 1, char 8"},
+		{"1 m - [1 s]", "cannot subtract quantity(m) and quantity(s)"},
+		{"[1 m, 2 m] + [1 s]", "cannot add quantity(m) and quantity(s)"},
+		{"[1, 2] * 1 m + 1 m^2", "cannot add quantity(m) and quantity(m^2)"},
+		{"[[1 m]] + 1 kg", "cannot add quantity(m) and quantity(kg)"},
+		{"[1 m] ** 0.5", "cannot raise quantity(m) to the power of 1/2"},
+		{`["a"] + 1`, "cannot concatenate string and number"},
+		{"-true", "operator - not defined on boolean"},
+		{"1 & 1 m", "operator & not defined on quantity(m)"},
+		{"1 and true", "AND operand must be boolean, found number"},
+		{`1 < "a"`, "cannot compare number and string"},
+		{"1i + 1 m", "cannot add complex and quantity(m)"},
+		{"(2 m) ** 1i", "exponent must be a number, found complex"},
+		{"1i & 1", "operator & not defined on complex"},
+		{`[1, 2; "a", 4]`, "matrix elements must be numbers, found string"},
+		{"[1 m, 2; 3, 4]", "matrix elements must be numbers, found quantity(m)"},
+		{`[1, 2; 3, 4] * "a"`, "operator * not defined on string"},
+		{"func f(x m) s -> x", "cannot return quantity(m) from f declared to return s at line 1, char 18"},
+		{`func f(x m) string = { x * 2 }`, "cannot return quantity(m) from f declared to return string"},
+		{"sin(1 m)", "sin expects a dimensionless number, found quantity(m) at line 1, char 5"},
+		{"[1 m].exp()", "exp expects a dimensionless number, found []quantity(m)"},
+		{`range("a")`, "range expects a number, found string"},
+		{"range(1 m)", "range expects a number, found quantity(m)"},
+		{`sqrt("a")`, "sqrt expects a number, found string"},
+	}
+	for _, test := range invalid {
+		errs := checkStrings(t, append(decls, test.input)...)
+		if len(errs) == 0 {
+			t.Errorf("%q: expected error %q", test.input, test.message)
+		} else if !strings.Contains(errs[0].Error(), test.message) {
+			t.Errorf("%q: expected error %q, got %q", test.input, test.message, errs[0])
+		}
+	}
+}
+
+func TestStructsAndEnums(t *testing.T) {
+	decls := []string{
+		"struct Planet = { Name string; Mass kg; Radius m }",
+		"enum Level = { LOW HIGH }",
+		"struct Alarm = { Level Level; Tags []string }",
+		`var earth = Planet{Name: "Earth", Mass: 5.972E24 kg}`,
+	}
+
+	valid := []string{
+		"Planet{Mass: 5}",
+		"earth.Mass + 1 kg",
+		`earth.Name == "Earth"`,
+		"Alarm{Level: Level.HIGH}",
+		"Level.LOW.Ordinal + 1",
+		"Level.HIGH.Name",
+		`Alarm{Tags: ["a"]}`,
+		"for l in Level { l.Name }",
+		"if earth.Mass > 1 kg { earth.Name }",
+		"match Level.LOW { Level.LOW -> 1; Level.HIGH -> 2 } + 1",
+		"match (Alarm{}).Level { Level.HIGH -> 1; else -> 0 }",
+		"match earth.Name { \"Earth\" -> 1 }",
+		`match Level.LOW { Level.LOW -> 1; Level.HIGH -> "a" } + 1`,
+	}
+	for _, input := range valid {
+		if errs := checkStrings(t, append(decls, input)...); len(errs) != 0 {
+			t.Errorf("%q: unexpected errors %v", input, errs)
+		}
+	}
+
+	invalid := []struct {
+		input   string
+		message string
+	}{
+		{"Planet{Moons: 2}", "unknown field Moons in struct Planet at line 1, char 1"},
+		{`Planet{Name: "X", Name: "Y"}`, "duplicate field Name in struct Planet"},
+		{"Planet{Name: 5}", "Planet.Name: cannot use number as string"},
+		{"Planet{Mass: 5 m}", "Planet.Mass: cannot convert quantity(m) to kg: incompatible dimensions at line 1, char 14"},
+		{"earth.Moons", "unknown field Moons in struct Planet at line 1, char 1"},
+		{"earth.Mass + 1 m", "cannot add quantity(kg) and quantity(m)"},
+		{"earth.Name.Length", "cannot access field Length of string"},
+		{"Level.EXTREME", "unknown member EXTREME in enum Level"},
+		{"Level.LOW.Value", "unknown field Value of Level"},
+		{`Alarm{Level: "HIGH"}`, "Alarm.Level: cannot use string as Level"},
+		{"Alarm{Tags: [1]}", "Alarm.Tags: cannot use []number as []string"},
+		{"if earth.Mass { 1 }", "non-boolean condition earth.Mass: found quantity(kg) at line 1, char 4"},
+		{"for x in earth.Mass { x }", "cannot iterate over quantity(kg)"},
+		{"earth.Mass[0]", "cannot index quantity(kg) at line 1, char 1"},
+		{"match Level.LOW { Level.LOW -> 1 }", "match on Level is not exhaustive: missing HIGH at line 1, char 1"},
+		{"match Level.LOW { Level.LOW, Level.HIGH -> 1; Level.LOW -> 2 }", "duplicate arm for Level.LOW at line 1, char 47"},
+		{"match Level.LOW { 1 -> 1; else -> 2 }", "cannot match number against Level at line 1, char 19"},
+	}
+	for _, test := range invalid {
+		errs := checkStrings(t, append(decls, test.input)...)
+		if len(errs) == 0 {
+			t.Errorf("%q: expected error %q", test.input, test.message)
+		} else if !strings.Contains(errs[0].Error(), test.message) {
+			t.Errorf("%q: expected error %q, got %q", test.input, test.message, errs[0])
+		}
+	}
+}
+
+func TestCheckProgram(t *testing.T) {
+	input := `var d = 1 m + 1 s
+func f(x float) -> x
+f("a")
+var ok = 1 m + 1 ft
+d + 1 furlong
+`
+	p := parser.NewParser(strings.NewReader(input))
+	var stmts []ast.Expression
+	for {
+		expr, err := p.ParseExpression()
+		if err == parser.EOF {
+			break
+		} else if err == parser.EOL {
+			continue
+		} else if err != nil {
+			t.Fatalf("parse: %s", err)
+		}
+		stmts = append(stmts, expr)
+	}
+
+	expected := []string{
+		"cannot add quantity(m) and quantity(s): incompatible dimensions at line 1, char 13",
+		"cannot use string as float in argument to f at line 3, char 1",
+		"unknown unit: furlong at line 5, char 7",
+	}
+	errs := New().CheckProgram(stmts)
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %v", len(expected), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], err)
+		}
+	}
+}
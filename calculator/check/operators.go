@@ -0,0 +1,234 @@
+package check
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/units"
+	"github.com/eliquious/lexer"
+)
+
+// inferBinary returns the type of a binary expression. Comparisons and logic
+// operators are boolean, arithmetic on quantities combines their dimensions
+// and bitwise operators require integers.
+func (c *Checker) inferBinary(e *ast.BinaryExpression) string {
+	lh, rh := c.infer(e.LExpr), c.infer(e.RExpr)
+	switch e.Op {
+	case lexer.AND, lexer.OR:
+		for _, typ := range []string{lh, rh} {
+			if typ != "" && typ != ast.BooleanTypeName {
+				c.errorf(e.OpPos, "%s operand must be boolean, found %s", e.Op, typ)
+				break
+			}
+		}
+		return ast.BooleanTypeName
+	case lexer.EQEQ, lexer.NEQ, lexer.LT, lexer.LTE, lexer.GT, lexer.GTE:
+		c.checkComparison(e, lh, rh)
+		return ast.BooleanTypeName
+	case lexer.PLUS, lexer.MINUS:
+		return broadcast(lh, rh, func(lh, rh string) string { return c.inferSum(e, lh, rh) })
+	case lexer.MUL, lexer.DIV:
+		return broadcast(lh, rh, func(lh, rh string) string { return c.inferProduct(e, lh, rh) })
+	case lexer.POW:
+		return broadcast(lh, rh, func(lh, rh string) string { return c.inferPower(e, lh, rh) })
+	}
+
+	// Bitwise operators
+	for _, typ := range []string{lh, rh} {
+		if typ != "" && typ != ast.NumberTypeName {
+			c.errorf(e.OpPos, "operator %s not defined on %s", e.Op, typ)
+			return ""
+		}
+	}
+	if lh == ast.NumberTypeName && rh == ast.NumberTypeName {
+		return ast.NumberTypeName
+	}
+	return ""
+}
+
+// inferUnary returns the type of a unary expression. Quantities only
+// support signs and the remaining operators require numbers.
+func (c *Checker) inferUnary(e *ast.UnaryExpression) string {
+	typ := c.infer(e.Expr)
+	switch {
+	case typ == "" || isArray(typ) || typ == ast.MatrixTypeName:
+		return ""
+	case typ == ast.NumberTypeName:
+		return typ
+	case c.isNumeric(typ) && (e.Op == lexer.MINUS || e.Op == lexer.PLUS):
+		return typ
+	}
+	c.errorf(e.OpPos, "operator %s not defined on %s", e.Op, typ)
+	return ""
+}
+
+// broadcast returns the type of arithmetic on arrays, which is computed
+// element-wise with a scalar operand applied to every element, so the
+// operator is checked against the types of the elements. Arrays combined
+// with matrices are not unwrapped.
+func broadcast(lh, rh string, op func(lh, rh string) string) string {
+	if lh == ast.MatrixTypeName || rh == ast.MatrixTypeName || !isArray(lh) && !isArray(rh) {
+		return op(lh, rh)
+	}
+	if isArray(lh) {
+		lh = elemType(lh)
+	}
+	if isArray(rh) {
+		rh = elemType(rh)
+	}
+	return "[]" + broadcast(lh, rh, op)
+}
+
+// arithmetic reports operands which do not support arithmetic and returns
+// true if the dimensions of both operands are known. Matrices and the arrays
+// multiplied by them have no dimension.
+func (c *Checker) arithmetic(e *ast.BinaryExpression, lh, rh string) bool {
+	for _, typ := range []string{lh, rh} {
+		if typ != "" && !isArray(typ) && typ != ast.MatrixTypeName && !c.isNumeric(typ) {
+			c.errorf(e.OpPos, "operator %s not defined on %s", e.Op, typ)
+			return false
+		}
+	}
+	return c.isNumeric(lh) && c.isNumeric(rh)
+}
+
+// inferSum checks that the operands of an addition or subtraction have the
+// same dimension. Strings are concatenated only with strings.
+func (c *Checker) inferSum(e *ast.BinaryExpression, lh, rh string) string {
+	if e.Op == lexer.PLUS && (lh == ast.StringTypeName || rh == ast.StringTypeName) {
+		if lh == "" || rh == "" || lh == rh {
+			return ast.StringTypeName
+		}
+		c.errorf(e.OpPos, "cannot concatenate %s and %s: operands must be strings", lh, rh)
+		return ""
+	}
+	if !c.arithmetic(e, lh, rh) {
+		return ""
+	}
+
+	ld, _ := c.dimension(lh)
+	rd, _ := c.dimension(rh)
+	if !ld.Equal(rd) {
+		op := "add"
+		if e.Op == lexer.MINUS {
+			op = "subtract"
+		}
+		c.errorf(e.OpPos, "cannot %s %s and %s: incompatible dimensions", op, lh, rh)
+		return ""
+	}
+	return c.numeric(ld, lh, rh)
+}
+
+// inferProduct returns the type of a product or quotient whose dimension
+// combines the dimensions of the operands.
+func (c *Checker) inferProduct(e *ast.BinaryExpression, lh, rh string) string {
+	if !c.arithmetic(e, lh, rh) {
+		return ""
+	}
+
+	ld, _ := c.dimension(lh)
+	rd, _ := c.dimension(rh)
+	if e.Op == lexer.DIV {
+		rd = rd.Pow(-1)
+	}
+	return c.numeric(ld.Mul(rd), lh, rh)
+}
+
+// numeric returns the type of the result of arithmetic with the dimension.
+// Dimensionless results are complex if either operand is complex.
+func (c *Checker) numeric(dim units.Dimension, lh, rh string) string {
+	typ := c.quantity(dim)
+	if typ == ast.NumberTypeName && (lh == ast.ComplexTypeName || rh == ast.ComplexTypeName) {
+		return ast.ComplexTypeName
+	}
+	return typ
+}
+
+// inferPower returns the type of an exponentiation. Quantities may only be
+// raised to a real number and the dimension of the result is known if the
+// exponent is a literal.
+func (c *Checker) inferPower(e *ast.BinaryExpression, lh, rh string) string {
+	dimensionless := lh == ast.NumberTypeName || lh == ast.ComplexTypeName
+	if !c.arithmetic(e, lh, rh) {
+		return ""
+	} else if rh != ast.NumberTypeName && (rh != ast.ComplexTypeName || !dimensionless) {
+		c.errorf(e.OpPos, "exponent must be a number, found %s", rh)
+		return ""
+	} else if dimensionless {
+		return c.numeric(nil, lh, rh)
+	}
+
+	exp, ok := literalRat(e.RExpr)
+	if !ok {
+		return ""
+	}
+	dim, ok := root(c.dims[lh], exp)
+	if !ok {
+		c.errorf(e.OpPos, "cannot raise %s to the power of %s", lh, exp.RatString())
+		return ""
+	}
+	return c.quantity(dim)
+}
+
+// checkComparison reports comparisons between values which cannot be
+// ordered against each other. Only the types of scalar values are compared.
+func (c *Checker) checkComparison(e *ast.BinaryExpression, lh, rh string) {
+	if !isScalar(lh) && !c.isNumeric(lh) || !isScalar(rh) && !c.isNumeric(rh) {
+		return
+	}
+
+	ld, lok := c.dimension(lh)
+	rd, rok := c.dimension(rh)
+	if lok && rok && !ld.Equal(rd) {
+		c.errorf(e.OpPos, "cannot compare %s and %s: incompatible dimensions", lh, rh)
+	} else if lok != rok || (!lok && lh != rh) {
+		c.errorf(e.OpPos, "cannot compare %s and %s", lh, rh)
+	}
+}
+
+// literalRat returns the value of a numeric literal, possibly in parentheses.
+func literalRat(expr ast.Expression) (*big.Rat, bool) {
+	for {
+		group, ok := expr.(*ast.GroupExpression)
+		if !ok {
+			break
+		}
+		expr = group.Expr
+	}
+	return ast.ToRat(expr)
+}
+
+// root returns the dimension raised to a fractional power which must evenly
+// divide every exponent.
+func root(dim units.Dimension, exp *big.Rat) (units.Dimension, bool) {
+	if !exp.Num().IsInt64() || !exp.Denom().IsInt64() {
+		return nil, false
+	}
+	num, den := exp.Num().Int64(), exp.Denom().Int64()
+	z := make(units.Dimension, len(dim))
+	for k, v := range dim {
+		n := int64(v) * num
+		if n%den != 0 {
+			return nil, false
+		} else if n != 0 {
+			z[k] = int(n / den)
+		}
+	}
+	return z, true
+}
+
+// isScalar returns true for the types of values which compare only against
+// values of the same type.
+func isScalar(typ string) bool {
+	switch typ {
+	case ast.StringTypeName, ast.BooleanTypeName, ast.DurationTypeName:
+		return true
+	}
+	return false
+}
+
+// isArray returns true for array types.
+func isArray(typ string) bool {
+	return strings.HasPrefix(typ, "[]")
+}
@@ -0,0 +1,172 @@
+package check
+
+import (
+	"strings"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
+)
+
+// inferStruct checks the fields of a struct literal against the declaration
+// of the struct. Structs which are not declared by the worksheet, such as
+// those of imported modules, are not checked.
+func (c *Checker) inferStruct(e *ast.StructExpression) string {
+	var decl *ast.StructDeclaration
+	if sym, ok := c.scope.lookup(e.Name); ok {
+		decl = sym.strct
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range e.Fields {
+		typ := c.infer(f.Value)
+		if decl == nil {
+			continue
+		}
+
+		i := decl.Field(f.Name)
+		if i < 0 {
+			c.errorf(e.Position().Start, "unknown field %s in struct %s", f.Name, decl.Name)
+			continue
+		} else if seen[f.Name] {
+			c.errorf(e.Position().Start, "duplicate field %s in struct %s", f.Name, decl.Name)
+			continue
+		}
+		seen[f.Name] = true
+		c.checkField(decl, decl.Fields[i], typ, f.Value.Position().Start)
+	}
+	return e.Name
+}
+
+// checkField reports a field value whose type does not match the annotation
+// of the field. Numbers are given the unit of fields annotated with one.
+func (c *Checker) checkField(decl *ast.StructDeclaration, field *ast.StructField, typ string, pos lexer.Pos) {
+	want := c.annotationType(field.Annotation)
+	if c.resolveAlias(field.Annotation).Unit != nil && c.isNumeric(want) && (typ == ast.NumberTypeName || typ == ast.ComplexTypeName) {
+		return
+	}
+
+	if _, ok := ast.UnifyTypes(want, typ); ok {
+		return
+	} else if wd, ok := c.dimension(want); ok {
+		if td, ok := c.dimension(typ); ok && !wd.Equal(td) {
+			c.errorf(pos, "%s.%s: cannot convert %s to %s: incompatible dimensions", decl.Name, field.Name, typ, field.Annotation)
+			return
+		}
+	}
+	c.errorf(pos, "%s.%s: cannot use %s as %s", decl.Name, field.Name, typ, field.Annotation)
+}
+
+// inferField returns the type of a field of a struct or a member of an enum.
+func (c *Checker) inferField(e *ast.FieldExpression) string {
+	if ident, ok := e.Expr.(*ast.Identifier); ok {
+		if sym, ok := c.scope.lookup(ident.Name); ok && sym.enum != nil {
+			if _, ok := sym.enum.Member(e.Name); !ok {
+				c.errorf(e.Position().Start, "unknown member %s in enum %s", e.Name, sym.enum.Name)
+				return ""
+			}
+			return sym.enum.Name
+		}
+	}
+
+	typ := c.infer(e.Expr)
+	if typ == "" {
+		return ""
+	}
+
+	sym, ok := c.scope.lookup(typ)
+	switch {
+	case ok && sym.strct != nil:
+		i := sym.strct.Field(e.Name)
+		if i < 0 {
+			c.errorf(e.Position().Start, "unknown field %s in struct %s", e.Name, typ)
+			return ""
+		}
+		return c.annotationType(sym.strct.Fields[i].Annotation)
+	case ok && sym.enum != nil:
+		switch e.Name {
+		case "Name":
+			return ast.StringTypeName
+		case "Ordinal":
+			return ast.NumberTypeName
+		}
+		c.errorf(e.Position().Start, "unknown field %s of %s", e.Name, typ)
+		return ""
+	case ok || !c.isBuiltin(typ):
+		return ""
+	}
+	c.errorf(e.Position().Start, "cannot access field %s of %s", e.Name, typ)
+	return ""
+}
+
+// isBuiltin returns true for types which are not declared by a worksheet.
+func (c *Checker) isBuiltin(typ string) bool {
+	switch typ {
+	case ast.TimestampTypeName, ast.FunctionTypeName, ast.NilTypeName:
+		return true
+	}
+	return isScalar(typ) || isArray(typ) || c.isNumeric(typ)
+}
+
+// inferMatch checks that the patterns of a match can equal its subject and
+// that a match on an enum without an else arm lists every member once.
+func (c *Checker) inferMatch(e *ast.MatchExpression) string {
+	subject := c.infer(e.Subject)
+	var enum *ast.EnumDeclaration
+	if sym, ok := c.scope.lookup(subject); ok && subject != "" {
+		enum = sym.enum
+	}
+
+	covered := make(map[string]bool)
+	typ, ok := "", true
+	for _, arm := range e.Arms {
+		for _, pattern := range arm.Patterns {
+			if p := c.infer(pattern); subject != "" && p != "" && p != subject {
+				c.errorf(pattern.Position().Start, "cannot match %s against %s", p, subject)
+			} else if member, isMember := enumMember(pattern, enum); isMember {
+				if covered[member] {
+					c.errorf(pattern.Position().Start, "duplicate arm for %s.%s", enum.Name, member)
+				}
+				covered[member] = true
+			}
+		}
+		if ok {
+			typ, ok = ast.UnifyTypes(typ, c.infer(arm.Body))
+		}
+	}
+
+	if e.Else != nil {
+		if body := c.infer(e.Else); ok {
+			typ, ok = ast.UnifyTypes(typ, body)
+		}
+	} else if enum != nil {
+		var missing []string
+		for _, member := range enum.Members {
+			if !covered[member] {
+				missing = append(missing, member)
+			}
+		}
+		if len(missing) > 0 {
+			c.errorf(e.Position().Start, "match on %s is not exhaustive: missing %s", enum.Name, strings.Join(missing, ", "))
+		}
+	}
+
+	if !ok {
+		return ""
+	}
+	return typ
+}
+
+// enumMember returns the name of the member of the enum a pattern refers
+// to as `Enum.MEMBER`.
+func enumMember(pattern ast.Expression, enum *ast.EnumDeclaration) (string, bool) {
+	field, ok := pattern.(*ast.FieldExpression)
+	if !ok || enum == nil {
+		return "", false
+	}
+	ident, ok := field.Expr.(*ast.Identifier)
+	if !ok || ident.Name != enum.Name {
+		return "", false
+	}
+	_, ok = enum.Member(field.Name)
+	return field.Name, ok
+}
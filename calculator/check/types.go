@@ -0,0 +1,534 @@
+package check
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
+)
+
+// infer checks an expression and returns the name of its type.
+func (c *Checker) infer(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case *ast.Identifier:
+		if sym, ok := c.scope.lookup(e.Name); ok {
+			if sym.fn != nil {
+				return ast.FunctionTypeName
+			}
+			return sym.typ
+		}
+		c.resolve(e.Name, e.Position().Start)
+		return ""
+	case *ast.GroupExpression:
+		return c.infer(e.Expr)
+	case *ast.UnaryExpression:
+		return c.inferUnary(e)
+	case *ast.BinaryExpression:
+		return c.inferBinary(e)
+	case *ast.AssignmentExpression:
+		typ := c.infer(e.Value)
+		sym, ok := c.scope.lookup(e.Name)
+		switch {
+		case !ok && c.bodies == 0:
+			// Builtins and mathematical constants are not variables
+			c.errorf(e.Position().Start, "undefined: %s", e.Name)
+		case ok && sym.constant:
+			c.errorf(e.Position().Start, "cannot assign to constant '%s'", e.Name)
+		case ok && sym.typ != typ:
+			// The assignment may not be reached so the type is not known
+			sym.typ = ""
+		}
+		return typ
+	case *ast.VariableDeclaration:
+		return c.inferVariable(e)
+	case *ast.ConstantDeclaration:
+		typ := c.infer(e.Value)
+		c.bind(c.scope, e.Position().Start, e.Name, &symbol{typ: typ, constant: true})
+		return typ
+	case *ast.FunctionDeclaration:
+		c.bind(c.scope, e.Position().Start, e.Name, &symbol{fn: e})
+		c.checkFunctionBody(e)
+		return ast.FunctionTypeName
+	case *ast.TypeDeclaration:
+		c.bind(c.scope, e.Position().Start, e.Name, &symbol{alias: e.Annotation})
+		return ""
+	case *ast.StructDeclaration:
+		c.bind(c.scope, e.Position().Start, e.Name, &symbol{isType: true, strct: e})
+		return ""
+	case *ast.EnumDeclaration:
+		c.bind(c.scope, e.Position().Start, e.Name, &symbol{isType: true, enum: e})
+		return ""
+	case *ast.UnitDeclaration:
+		c.declareUnit(e)
+		return ""
+	case *ast.ConversionDeclaration:
+		c.declareConversion(e)
+		return ""
+	case *ast.ImportExpression:
+		c.bind(c.scope, e.Position().Start, moduleName(e), &symbol{})
+		return ""
+	case *ast.CallExpression:
+		return c.inferCall(e)
+	case *ast.MethodCallExpression:
+		return c.inferMethodCall(e)
+	case *ast.BlockExpression:
+		defer c.push()()
+		typ := ""
+		for _, stmt := range e.Exprs {
+			typ = c.infer(stmt)
+		}
+		return typ
+	case *ast.IfExpression:
+		if typ := c.infer(e.Condition); typ != "" && typ != ast.BooleanTypeName {
+			c.errorf(e.Condition.Position().Start, "non-boolean condition %s: found %s", e.Condition, typ)
+		}
+		body := c.infer(e.Body)
+		if e.Else == nil {
+			return ""
+		}
+		typ, _ := ast.UnifyTypes(body, c.infer(e.Else))
+		return typ
+	case *ast.ElseExpression:
+		return c.infer(e.Body)
+	case *ast.ForExpression:
+		iterable := c.infer(e.Iterable)
+		if iterable != "" && !isArray(iterable) && c.isBuiltin(iterable) && iterable != ast.StringTypeName {
+			c.errorf(e.Iterable.Position().Start, "cannot iterate over %s", iterable)
+		}
+		defer c.push()()
+		c.declare(e.Name, &symbol{typ: elemType(iterable)})
+		return "[]" + c.infer(e.Body)
+	case *ast.MatchExpression:
+		return c.inferMatch(e)
+	case *ast.ArrayLiteral:
+		elem := ""
+		for _, el := range e.Elements {
+			if typ, ok := ast.UnifyTypes(elem, c.infer(el)); ok {
+				elem = typ
+			}
+		}
+		return "[]" + elem
+	case *ast.MatrixLiteral:
+		for _, row := range e.Rows {
+			for _, el := range row {
+				if typ := c.infer(el); typ != "" && typ != ast.NumberTypeName && typ != ast.ComplexTypeName {
+					c.errorf(el.Position().Start, "matrix elements must be numbers, found %s", typ)
+				}
+			}
+		}
+		return ast.MatrixTypeName
+	case *ast.IndexExpression:
+		typ := c.infer(e.Expr)
+		c.checkIndex(typ, e.Position().Start, e.Index)
+		return elemType(typ)
+	case *ast.SliceExpression:
+		typ := c.infer(e.Expr)
+		c.checkIndex(typ, e.Position().Start, e.Start, e.End)
+		return typ
+	case *ast.LambdaExpression:
+		c.inferLambda(e, nil)
+		return ast.FunctionTypeName
+	case *ast.StructExpression:
+		return c.inferStruct(e)
+	case *ast.FieldExpression:
+		return c.inferField(e)
+	case *ast.QuantityExpression:
+		return c.inferQuantity(e)
+	case *ast.ConversionExpression:
+		return c.inferConversion(e)
+	}
+
+	if ast.IsLiteral(expr) {
+		return ast.TypeOf(expr)
+	}
+	return ""
+}
+
+// inferVariable declares a variable. Variables declared with `let` are bound
+// to the current block and those declared with `var` to the enclosing
+// function, where they replace any variable of the same name.
+func (c *Checker) inferVariable(e *ast.VariableDeclaration) string {
+	typ := c.infer(e.Value)
+	s, sym := c.scope, &symbol{typ: typ}
+	if !e.Scoped {
+		s = s.functionScope()
+		if old, ok := s.symbols[e.Name]; ok && s != c.scope && old.typ != typ {
+			// The declaration may not be reached so the type is not known
+			sym.typ = ""
+		}
+	}
+	c.bind(s, e.Position().Start, e.Name, sym)
+	return typ
+}
+
+// resolve reports a name which is neither declared nor a builtin function or
+// mathematical constant. Names in function bodies are not reported.
+func (c *Checker) resolve(name string, pos lexer.Pos) {
+	if c.bodies == 0 && !predeclared(name) {
+		c.errorf(pos, "undefined: %s", name)
+	}
+}
+
+// moduleName returns the name an import declares, which is the name of the
+// module or the base name of its file.
+func moduleName(e *ast.ImportExpression) string {
+	if e.Path == "" {
+		return e.Name
+	}
+	return strings.TrimSuffix(filepath.Base(e.Path), filepath.Ext(e.Path))
+}
+
+// checkIndex reports indexing values other than arrays, matrices and strings
+// and indices which are not numbers.
+func (c *Checker) checkIndex(typ string, pos lexer.Pos, indices ...ast.Expression) {
+	if typ != "" && !isArray(typ) && typ != ast.StringTypeName && typ != ast.MatrixTypeName {
+		c.errorf(pos, "cannot index %s", typ)
+	}
+	for _, index := range indices {
+		if index == nil {
+			continue
+		} else if t := c.infer(index); t != "" && t != ast.NumberTypeName {
+			c.errorf(index.Position().Start, "index must be a number, found %s", t)
+		}
+	}
+}
+
+// inferCall checks the arguments of a call to a declared function.
+func (c *Checker) inferCall(e *ast.CallExpression) string {
+	types := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		types[i] = c.infer(arg)
+	}
+
+	ident, ok := e.Function.(*ast.Identifier)
+	if !ok {
+		c.infer(e.Function)
+		return ""
+	}
+	sym, ok := c.scope.lookup(ident.Name)
+	if !ok {
+		c.resolve(ident.Name, ident.Position().Start)
+		c.checkBuiltinArgs(ident.Name, e.Args, types)
+		return builtinType(ident.Name)
+	} else if sym.fn == nil {
+		return ""
+	}
+	return c.checkArgs(sym.fn, e.Args, types, e.Position().Start)
+}
+
+// inferMethodCall checks `recv.f(args)` as the call `f(recv, args)` when f
+// is a declared function.
+func (c *Checker) inferMethodCall(e *ast.MethodCallExpression) string {
+	args := append([]ast.Expression{e.Recv}, e.Args...)
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = c.infer(arg)
+	}
+
+	sym, ok := c.scope.lookup(e.Name)
+	if !ok {
+		c.checkBuiltinArgs(e.Name, args, types)
+		return ""
+	} else if sym.fn == nil {
+		return ""
+	}
+	return c.checkArgs(sym.fn, args, types, e.Position().Start)
+}
+
+// checkArgs validates the arguments of a call and returns the type of the
+// result. Type parameters are inferred from the arguments and must be bound
+// to a single type.
+func (c *Checker) checkArgs(fn *ast.FunctionDeclaration, args []ast.Expression, types []string, pos lexer.Pos) string {
+	if len(args) != len(fn.Params) {
+		c.errorf(pos, "%s expects %d argument(s), found %d", fn.Name, len(fn.Params), len(args))
+		return ""
+	}
+
+	bindings := make(map[string]string)
+	for i, param := range fn.Params {
+		c.unify(fn, param.Annotation, args[i], types[i], bindings, pos)
+	}
+	return c.substitute(fn, fn.Return, bindings)
+}
+
+// unify checks an argument against a parameter annotation and binds the type
+// parameters it refers to.
+func (c *Checker) unify(fn *ast.FunctionDeclaration, annotation *ast.TypeAnnotation, arg ast.Expression, typ string, bindings map[string]string, pos lexer.Pos) {
+	if annotation == nil {
+		return
+	} else if name, ok := fn.IsTypeParam(annotation); ok {
+		bound, ok := ast.UnifyTypes(bindings[name], typ)
+		if !ok {
+			c.errorf(pos, "type parameter %s of %s inferred as both %s and %s", name, fn.Name, bindings[name], typ)
+			return
+		}
+		bindings[name] = bound
+		return
+	}
+
+	annotation = c.resolveAlias(annotation)
+	switch {
+	case annotation.Elem != nil:
+		if typ == "" {
+			return
+		} else if !strings.HasPrefix(typ, "[]") {
+			c.errorf(pos, "cannot use %s as %s in argument to %s", typ, annotation, fn.Name)
+			return
+		}
+		c.unify(fn, annotation.Elem, nil, typ[2:], bindings, pos)
+	case annotation.Func != nil:
+		c.checkFunctionArg(fn, annotation.Func, arg, typ, pos)
+	default:
+		want := c.annotationType(annotation)
+		if _, ok := ast.UnifyTypes(want, typ); !ok {
+			c.errorf(pos, "cannot use %s as %s in argument to %s", typ, annotation, fn.Name)
+		}
+	}
+}
+
+// checkFunctionArg validates a function passed for a parameter with a
+// function type. Lambdas must accept as many parameters as the function
+// type and their body must return its result type.
+func (c *Checker) checkFunctionArg(fn *ast.FunctionDeclaration, ft *ast.FuncType, arg ast.Expression, typ string, pos lexer.Pos) {
+	if _, ok := ast.UnifyTypes(ast.FunctionTypeName, typ); !ok {
+		c.errorf(pos, "cannot use %s as %s in argument to %s", typ, ft, fn.Name)
+		return
+	}
+
+	switch a := arg.(type) {
+	case *ast.LambdaExpression:
+		if len(a.Params) != len(ft.Params) {
+			c.errorf(pos, "cannot use lambda with %d parameter(s) as %s in argument to %s", len(a.Params), ft, fn.Name)
+			return
+		}
+		result := c.inferLambda(a, ft)
+		want := c.annotationType(ft.Return)
+		if isTypeParam(ft.TypeParams, ft.Return) {
+			want = ""
+		}
+		if _, ok := ast.UnifyTypes(want, result); !ok {
+			c.errorf(pos, "cannot use lambda returning %s as %s in argument to %s", result, ft, fn.Name)
+		}
+	case *ast.Identifier:
+		if sym, ok := c.scope.lookup(a.Name); ok && sym.fn != nil && len(sym.fn.Params) != len(ft.Params) {
+			c.errorf(pos, "cannot use %s with %d parameter(s) as %s in argument to %s", a.Name, len(sym.fn.Params), ft, fn.Name)
+		}
+	}
+}
+
+// inferLambda checks the body of a lambda and returns its type. The
+// parameters take the types of the function type the lambda is passed as.
+func (c *Checker) inferLambda(e *ast.LambdaExpression, ft *ast.FuncType) string {
+	defer c.pushFunction()()
+	for i, p := range e.Params {
+		typ := ""
+		if ft != nil && !isTypeParam(ft.TypeParams, ft.Params[i].Annotation) {
+			typ = c.annotationType(ft.Params[i].Annotation)
+		}
+		c.declare(p.Name, &symbol{typ: typ})
+	}
+	return c.infer(e.Body)
+}
+
+// checkFunctionBody checks the body of a declared function with its
+// parameters bound to their annotated types. Parameters of a type parameter
+// have an unknown type. The body must have the type of the declared result,
+// and may only be returned as a type parameter if its type is unknown.
+func (c *Checker) checkFunctionBody(fn *ast.FunctionDeclaration) {
+	defer c.pushFunction()()
+	for _, p := range fn.Params {
+		c.declare(p.Name, &symbol{typ: c.substitute(fn, p.Annotation, map[string]string{})})
+	}
+
+	// Type parameters stand for themselves, so that a body of a known type
+	// cannot be returned as a type parameter which could be bound to another
+	params := make(map[string]string, len(fn.TypeParams))
+	for _, name := range fn.TypeParams {
+		params[name] = name
+	}
+	body := c.infer(fn.Body)
+	want := c.substitute(fn, fn.Return, params)
+	if _, ok := ast.UnifyTypes(want, body); !ok {
+		c.errorf(fn.Body.Position().Start, "cannot return %s from %s declared to return %s", body, fn.Name, fn.Return)
+	}
+}
+
+// substitute returns the type of an annotation with bound type parameters
+// replaced by their types.
+func (c *Checker) substitute(fn *ast.FunctionDeclaration, annotation *ast.TypeAnnotation, bindings map[string]string) string {
+	if annotation == nil {
+		return ""
+	} else if name, ok := fn.IsTypeParam(annotation); ok {
+		return bindings[name]
+	} else if annotation.Elem != nil {
+		return "[]" + c.substitute(fn, annotation.Elem, bindings)
+	}
+	return c.annotationType(annotation)
+}
+
+// annotationType returns the name of the type of an annotation. Units have
+// the type of quantities of their dimension.
+func (c *Checker) annotationType(annotation *ast.TypeAnnotation) string {
+	if annotation == nil {
+		return ""
+	}
+	annotation = c.resolveAlias(annotation)
+	switch {
+	case annotation.Elem != nil:
+		return "[]" + c.annotationType(annotation.Elem)
+	case annotation.Func != nil:
+		return ast.FunctionTypeName
+	case annotation.Unit != nil:
+		if name, ok := annotation.Symbol(); ok {
+			if sym, ok := c.scope.lookup(name); ok && sym.isType {
+				return name
+			}
+		}
+		if unit, unknown := c.lookupUnit(annotation.Unit); unknown == "" {
+			return c.quantity(unit.Dimension())
+		}
+		return ""
+	}
+
+	switch annotation.Name {
+	case "int", "float":
+		return ast.NumberTypeName
+	default:
+		return annotation.Name
+	}
+}
+
+// resolveAlias returns the annotation a type alias stands for.
+func (c *Checker) resolveAlias(annotation *ast.TypeAnnotation) *ast.TypeAnnotation {
+	seen := make(map[string]bool)
+	for {
+		name, ok := annotation.Symbol()
+		if !ok || seen[name] {
+			return annotation
+		}
+		sym, ok := c.scope.lookup(name)
+		if !ok || sym.alias == nil {
+			return annotation
+		}
+		seen[name] = true
+		annotation = sym.alias
+	}
+}
+
+// isTypeParam returns true if the annotation names one of the parameters.
+func isTypeParam(params []string, annotation *ast.TypeAnnotation) bool {
+	if annotation == nil {
+		return false
+	}
+	name, ok := annotation.Symbol()
+	if !ok {
+		return false
+	}
+	for _, p := range params {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// elemType returns the type of the elements of an array type or of the rows
+// of a matrix.
+func elemType(typ string) string {
+	if strings.HasPrefix(typ, "[]") {
+		return typ[2:]
+	} else if typ == ast.StringTypeName {
+		return typ
+	} else if typ == ast.MatrixTypeName {
+		return "[]"
+	}
+	return ""
+}
+
+// builtins are the names of the functions provided by the evaluator, which
+// are resolved after the declared names. Like keywords, they are case
+// insensitive.
+var builtins = map[string]bool{
+	"abs": true, "acos": true, "acosh": true, "all": true, "any": true, "append": true,
+	"arg": true, "asin": true, "asinh": true, "atan": true, "atanh": true, "bitlen": true,
+	"cbrt": true, "conj": true, "cos": true, "cosh": true, "det": true, "eigenvalues": true,
+	"exp": true, "filter": true, "identity": true, "imag": true, "inv": true, "len": true,
+	"ln": true, "log": true, "log10": true, "log2": true, "lu": true, "map": true,
+	"matrix": true, "polar": true, "popcount": true, "pow": true, "qr": true, "range": true,
+	"real": true, "rect": true, "reduce": true, "root": true, "sin": true, "sinh": true,
+	"solve": true, "sort_by": true, "sqrt": true, "tan": true, "tanh": true,
+	"transpose": true, "zip": true,
+}
+
+// predeclared returns true for the names of builtins and of the mathematical
+// constants.
+func predeclared(name string) bool {
+	switch name {
+	case "pi", "e":
+		return true
+	}
+	return builtins[strings.ToLower(name)]
+}
+
+// Kinds of arguments taken by builtins.
+const (
+	// numericArgs are numbers and quantities or arrays of them
+	numericArgs = iota + 1
+
+	// dimensionlessArgs are numbers without a dimension or arrays of them
+	dimensionlessArgs
+
+	// numberArgs are plain numbers
+	numberArgs
+)
+
+// builtinArgs maps builtins to the kind of arguments they take.
+var builtinArgs = map[string]int{
+	"sqrt": numericArgs, "cbrt": numericArgs, "root": numericArgs, "pow": numericArgs,
+	"abs": numericArgs, "arg": numericArgs, "conj": numericArgs, "real": numericArgs, "imag": numericArgs,
+
+	"exp": dimensionlessArgs, "ln": dimensionlessArgs, "log": dimensionlessArgs,
+	"log10": dimensionlessArgs, "log2": dimensionlessArgs,
+	"sin": dimensionlessArgs, "cos": dimensionlessArgs, "tan": dimensionlessArgs,
+	"asin": dimensionlessArgs, "acos": dimensionlessArgs, "atan": dimensionlessArgs,
+	"sinh": dimensionlessArgs, "cosh": dimensionlessArgs, "tanh": dimensionlessArgs,
+	"asinh": dimensionlessArgs, "acosh": dimensionlessArgs, "atanh": dimensionlessArgs,
+
+	"range": numberArgs, "popcount": numberArgs, "bitlen": numberArgs,
+}
+
+// checkBuiltinArgs reports arguments of a numeric builtin which are not of
+// the kind it takes.
+func (c *Checker) checkBuiltinArgs(name string, args []ast.Expression, types []string) {
+	kind := builtinArgs[strings.ToLower(name)]
+	if kind == 0 {
+		return
+	}
+	for i, typ := range types {
+		elem := typ
+		for kind != numberArgs && isArray(elem) {
+			elem = elemType(elem)
+		}
+
+		dim, ok := c.dimension(elem)
+		switch {
+		case elem == "":
+		case !ok || kind == numberArgs && elem != ast.NumberTypeName:
+			c.errorf(args[i].Position().Start, "%s expects a number, found %s", name, typ)
+		case kind == dimensionlessArgs && len(dim) > 0:
+			c.errorf(args[i].Position().Start, "%s expects a dimensionless number, found %s", name, typ)
+		}
+	}
+}
+
+// builtinType returns the type of the result of a builtin function.
+func builtinType(name string) string {
+	switch strings.ToLower(name) {
+	case "len":
+		return ast.NumberTypeName
+	case "range":
+		return "[]" + ast.NumberTypeName
+	}
+	return ""
+}
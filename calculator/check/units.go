@@ -0,0 +1,166 @@
+package check
+
+import (
+	"math/big"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/units"
+)
+
+// quantity returns the name of the type of quantities with the dimension.
+// Dimensionless quantities are numbers.
+func (c *Checker) quantity(dim units.Dimension) string {
+	if len(dim) == 0 {
+		return ast.NumberTypeName
+	}
+	name := "quantity(" + dim.String() + ")"
+	c.dims[name] = dim
+	return name
+}
+
+// dimension returns the dimension of a numeric type. Complex numbers are
+// dimensionless.
+func (c *Checker) dimension(typ string) (units.Dimension, bool) {
+	if typ == ast.NumberTypeName || typ == ast.ComplexTypeName {
+		return units.Dimension{}, true
+	}
+	dim, ok := c.dims[typ]
+	return dim, ok
+}
+
+// isNumeric returns true for numbers and quantities.
+func (c *Checker) isNumeric(typ string) bool {
+	_, ok := c.dimension(typ)
+	return ok
+}
+
+// declareConstants declares the physical constants and their uncertainties
+// as quantities under their names and symbols. Constants whose unit is not
+// known have an unknown type.
+func (c *Checker) declareConstants(constants []units.Constant) {
+	for _, k := range constants {
+		typ := ""
+		if unit, err := c.units.Parse(k.Unit); err == nil {
+			typ = c.quantity(unit.Dimension())
+		}
+		for _, name := range append([]string{k.Name}, k.Symbols...) {
+			c.declare(name, &symbol{typ: typ, constant: true})
+			c.declare(name+units.UncertaintySuffix, &symbol{typ: typ, constant: true})
+		}
+	}
+}
+
+// resolveUnit looks up every symbol of a unit expression. Unknown symbols
+// are reported unless they were declared by the worksheet with a dimension
+// the checker could not resolve.
+func (c *Checker) resolveUnit(expr *ast.UnitExpression) (units.Compound, bool) {
+	compound, unknown := c.lookupUnit(expr)
+	if unknown == "" {
+		return compound, true
+	} else if !c.opaque[unknown] {
+		c.errorf(expr.Position().Start, "unknown unit: %s", unknown)
+	}
+	return nil, false
+}
+
+// lookupUnit returns the unit of an expression or the first symbol which is
+// not a known unit.
+func (c *Checker) lookupUnit(expr *ast.UnitExpression) (units.Compound, string) {
+	var compound units.Compound
+	for _, t := range expr.Terms {
+		unit, ok := c.units.Lookup(t.Symbol)
+		if !ok {
+			return nil, t.Symbol
+		}
+		compound = compound.Mul(units.Compound{{Unit: unit, Exp: t.Exp}})
+	}
+	return compound, ""
+}
+
+// declareUnit registers a declared unit. Only dimensions matter to the
+// checker so derived units are defined with a unit scale factor.
+func (c *Checker) declareUnit(e *ast.UnitDeclaration) {
+	if len(e.Conversions) == 0 {
+		c.units.DefineBase(e.Name, e.Symbol)
+		return
+	}
+
+	var unit *units.Unit
+	for _, conv := range e.Conversions {
+		target, unknown := c.lookupUnit(conv.Unit)
+		if unknown == "" && unit == nil {
+			unit, _ = c.units.Define(e.Name, e.Symbol, big.NewRat(1, 1), target)
+		} else if unknown != "" && unit != nil {
+			c.defineUnknownUnit(unknown, conv.Unit, units.Of(unit))
+		} else if unknown != "" {
+			c.opaque[unknown] = true
+		}
+	}
+	if unit == nil {
+		c.opaque[e.Name] = true
+		c.opaque[e.Symbol] = true
+	}
+}
+
+// declareConversion registers the unit a conversion declares, if any, and
+// reports conversions between incompatible units.
+func (c *Checker) declareConversion(e *ast.ConversionDeclaration) {
+	lu, lunknown := c.lookupUnit(e.LValue.Unit)
+	ru, runknown := c.lookupUnit(e.RValue.Unit)
+	switch {
+	case lunknown == "" && runknown == "":
+		if !lu.Compatible(ru) {
+			c.errorf(e.RValue.Unit.Position().Start, "cannot convert %s to %s: incompatible dimensions", lu, ru)
+		}
+	case lunknown == "":
+		c.defineUnknownUnit(runknown, e.RValue.Unit, lu)
+	case runknown == "":
+		c.defineUnknownUnit(lunknown, e.LValue.Unit, ru)
+	default:
+		c.opaque[lunknown] = true
+		c.opaque[runknown] = true
+	}
+}
+
+// defineUnknownUnit defines a symbol with the dimension of a known unit. The
+// symbol is opaque unless it appears alone in its unit expression.
+func (c *Checker) defineUnknownUnit(symbol string, unit *ast.UnitExpression, known units.Compound) {
+	if len(unit.Terms) != 1 || unit.Terms[0].Exp != 1 {
+		c.opaque[symbol] = true
+		return
+	}
+	c.units.Define(symbol, symbol, big.NewRat(1, 1), known)
+}
+
+// inferQuantity returns the type of a number followed by a unit.
+func (c *Checker) inferQuantity(e *ast.QuantityExpression) string {
+	typ := c.infer(e.Value)
+	unit, ok := c.resolveUnit(e.Unit)
+	if !ok || typ != ast.NumberTypeName {
+		return ""
+	}
+	return c.quantity(unit.Dimension())
+}
+
+// inferConversion checks that the converted value has the dimension of the
+// target unit and returns the type of the result. Values other than
+// quantities are converted as dimensionless numbers.
+func (c *Checker) inferConversion(e *ast.ConversionExpression) string {
+	typ := c.infer(e.Expr)
+	unit, ok := c.resolveUnit(e.Unit)
+	if !ok || typ == "" {
+		return ""
+	}
+
+	dim, ok := c.dimension(typ)
+	if !ok {
+		dim = units.Dimension{}
+	}
+	if !dim.Equal(unit.Dimension()) {
+		c.errorf(e.Position().Start, "cannot convert %s to %s: incompatible dimensions", typ, e.Unit)
+		return ""
+	} else if !ok {
+		return ""
+	}
+	return c.quantity(unit.Dimension())
+}
@@ -0,0 +1,161 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
+)
+
+func init() {
+	registerBuiltins(
+		&Builtin{Name: "len", Arity: 1, Fn: builtinLen},
+		&Builtin{Name: "append", Arity: -1, Fn: builtinAppend},
+	)
+}
+
+func evalArrayLiteral(expr *ast.ArrayLiteral, env *Environment) (ast.Expression, error) {
+	elements := make([]ast.Expression, len(expr.Elements))
+	for i, el := range expr.Elements {
+		var err error
+		if elements[i], err = evalExpression(el, env); err != nil {
+			return nil, err
+		}
+	}
+	return &ast.ArrayLiteral{Elements: elements}, nil
+}
+
+// evalIndexExpression returns an element of an array, a row of a matrix or a
+// character of a string. Negative indices count from the end.
+func evalIndexExpression(expr *ast.IndexExpression, env *Environment) (ast.Expression, error) {
+	value, err := evalExpression(expr.Expr, env)
+	if err != nil {
+		return nil, err
+	}
+	elements, err := indexable(value)
+	if err != nil {
+		return nil, err
+	}
+	index, err := evalIndex(expr.Index, env)
+	if err != nil {
+		return nil, err
+	}
+
+	i := index
+	if i < 0 {
+		i += len(elements)
+	}
+	if i < 0 || i >= len(elements) {
+		return nil, &IndexOutOfRangeError{Index: index, Length: len(elements)}
+	}
+	return elements[i], nil
+}
+
+// evalSliceExpression returns the elements from start up to but excluding
+// end. Negative bounds count from the end and bounds are clamped to the
+// length of the array or string.
+func evalSliceExpression(expr *ast.SliceExpression, env *Environment) (ast.Expression, error) {
+	value, err := evalExpression(expr.Expr, env)
+	if err != nil {
+		return nil, err
+	}
+	elements, err := indexable(value)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := 0, len(elements)
+	if expr.Start != nil {
+		if start, err = evalIndex(expr.Start, env); err != nil {
+			return nil, err
+		}
+	}
+	if expr.End != nil {
+		if end, err = evalIndex(expr.End, env); err != nil {
+			return nil, err
+		}
+	}
+	start, end = clampIndex(start, len(elements)), clampIndex(end, len(elements))
+	if end < start {
+		end = start
+	}
+
+	if s, ok := value.(*ast.StringLiteral); ok {
+		return &ast.StringLiteral{Value: string([]rune(s.Value)[start:end])}, nil
+	}
+	return &ast.ArrayLiteral{Elements: append([]ast.Expression{}, elements[start:end]...)}, nil
+}
+
+// indexable returns the elements of an array, the rows of a matrix or the
+// characters of a string.
+func indexable(value ast.Expression) ([]ast.Expression, error) {
+	switch value.(type) {
+	case *ast.ArrayLiteral, *ast.MatrixLiteral, *ast.StringLiteral:
+		return iterate(value)
+	default:
+		return nil, fmt.Errorf("cannot index %s", value.String())
+	}
+}
+
+// evalIndex evaluates an integer index.
+func evalIndex(expr ast.Expression, env *Environment) (int, error) {
+	value, err := evalExpression(expr, env)
+	if err != nil {
+		return 0, err
+	}
+
+	i, ok := value.(*ast.IntegerLiteral)
+	if !ok || !i.Value.IsInt64() {
+		return 0, fmt.Errorf("invalid index %s", value.String())
+	}
+	return int(i.Value.Int64()), nil
+}
+
+// clampIndex resolves a negative slice bound and limits it to the length.
+func clampIndex(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	if i < 0 {
+		return 0
+	} else if i > n {
+		return n
+	}
+	return i
+}
+
+func evalUnaryArrayExpression(op lexer.Token, expr *ast.ArrayLiteral) (ast.Expression, error) {
+	elements := make([]ast.Expression, len(expr.Elements))
+	for i, el := range expr.Elements {
+		var err error
+		if elements[i], err = evalUnaryOperand(op, el); err != nil {
+			return nil, err
+		}
+	}
+	return &ast.ArrayLiteral{Elements: elements}, nil
+}
+
+// builtinLen returns the number of elements of an array, rows of a matrix or
+// characters of a string.
+func builtinLen(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	elements, err := indexable(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("len: %s", err)
+	}
+	return &ast.IntegerLiteral{Value: big.NewInt(int64(len(elements)))}, nil
+}
+
+// builtinAppend returns a new array with the values added to the end.
+func builtinAppend(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("append expects an array argument")
+	}
+	arr, ok := args[0].(*ast.ArrayLiteral)
+	if !ok {
+		return nil, fmt.Errorf("append expects an array, found %s", args[0].String())
+	}
+
+	elements := append([]ast.Expression{}, arr.Elements...)
+	return &ast.ArrayLiteral{Elements: append(elements, args[1:]...)}, nil
+}
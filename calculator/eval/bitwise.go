@@ -0,0 +1,74 @@
+package eval
+
+import (
+	"errors"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
+)
+
+// bitwiseOperands returns the error for a bitwise operation whose left
+// operand does not support it. Numbers which are not integers are named so
+// that `3.0 & 5` reports the decimal.
+func bitwiseOperands(operation string, expr *ast.BinaryExpression) error {
+	switch expr.LExpr.(type) {
+	case *ast.DecimalLiteral, *ast.RationalLiteral:
+		return ast.BitwiseOperandError(operation, expr.LExpr, expr.RExpr)
+	}
+	return unsupportedOperands(expr)
+}
+
+func evalAmpersandExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+	if expr.Op != lexer.AMPERSAND {
+		return nil, errors.New("Expected AMPERSAND operand")
+	}
+
+	if e, ok := expr.LExpr.(ast.AmpersandExpression); ok {
+		return e.Ampersand(expr.RExpr)
+	}
+	return nil, bitwiseOperands("bitwise AND", expr)
+}
+
+func evalXorExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+	if expr.Op != lexer.XOR {
+		return nil, errors.New("Expected XOR operand")
+	}
+
+	if e, ok := expr.LExpr.(ast.XorExpression); ok {
+		return e.Xor(expr.RExpr)
+	}
+	return nil, bitwiseOperands("bitwise XOR", expr)
+}
+
+func evalPipeExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+	if expr.Op != lexer.PIPE {
+		return nil, errors.New("Expected PIPE operand")
+	}
+
+	if e, ok := expr.LExpr.(ast.PipeExpression); ok {
+		return e.Pipe(expr.RExpr)
+	}
+	return nil, bitwiseOperands("bitwise OR", expr)
+}
+
+func evalLShiftExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+	if expr.Op != lexer.LSHIFT {
+		return nil, errors.New("Expected LSHIFT operand")
+	}
+
+	if e, ok := expr.LExpr.(ast.LShiftExpression); ok {
+		return e.LShift(expr.RExpr)
+	}
+	return nil, bitwiseOperands("left shift", expr)
+}
+
+func evalRShiftExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+	if expr.Op != lexer.RSHIFT {
+		return nil, errors.New("Expected RSHIFT operand")
+	}
+
+	if e, ok := expr.LExpr.(ast.RShiftExpression); ok {
+		return e.RShift(expr.RExpr)
+	}
+	return nil, bitwiseOperands("right shift", expr)
+}
@@ -0,0 +1,129 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
+)
+
+// evalLogicalExpression evaluates AND and OR expressions. The right hand
+// side is only evaluated if the left hand side does not decide the result.
+func evalLogicalExpression(expr *ast.BinaryExpression, env *Environment) (ast.Expression, error) {
+	lh, err := evalExpression(expr.LExpr, env)
+	if err != nil {
+		return nil, err
+	}
+
+	b, ok := lh.(*ast.BooleanLiteral)
+	if !ok {
+		return nil, fmt.Errorf("%s operand must be boolean, found %s", expr.Op, ast.OperandType(lh))
+	} else if expr.Op == lexer.AND && !b.Value {
+		return b, nil
+	} else if expr.Op == lexer.OR && b.Value {
+		return b, nil
+	}
+
+	rh, err := evalExpression(expr.RExpr, env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch expr.Op {
+	case lexer.AND:
+		return evalAndExpression(&ast.BinaryExpression{Op: expr.Op, LExpr: lh, RExpr: rh})
+	case lexer.OR:
+		return evalOrExpression(&ast.BinaryExpression{Op: expr.Op, LExpr: lh, RExpr: rh})
+	default:
+		return nil, errors.New("Unsupported logical expression")
+	}
+}
+
+func evalAndExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+	if expr.Op != lexer.AND {
+		return nil, errors.New("Expected AND operand")
+	}
+
+	if e, ok := expr.LExpr.(ast.AndExpression); ok {
+		return e.And(expr.RExpr)
+	}
+	return nil, unsupportedOperands(expr)
+}
+
+func evalOrExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+	if expr.Op != lexer.OR {
+		return nil, errors.New("Expected OR operand")
+	}
+
+	if e, ok := expr.LExpr.(ast.OrExpression); ok {
+		return e.Or(expr.RExpr)
+	}
+	return nil, unsupportedOperands(expr)
+}
+
+func evalEqualExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+	if expr.Op != lexer.EQEQ {
+		return nil, errors.New("Expected EQEQ operand")
+	}
+
+	if e, ok := expr.LExpr.(ast.EqualExpression); ok {
+		return e.Equal(expr.RExpr)
+	}
+	return nil, unsupportedOperands(expr)
+}
+
+func evalNotEqualExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+	if expr.Op != lexer.NEQ {
+		return nil, errors.New("Expected NEQ operand")
+	}
+
+	if e, ok := expr.LExpr.(ast.NotEqualExpression); ok {
+		return e.NotEqual(expr.RExpr)
+	}
+	return nil, unsupportedOperands(expr)
+}
+
+func evalLessThanExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+	if expr.Op != lexer.LT {
+		return nil, errors.New("Expected LT operand")
+	}
+
+	if e, ok := expr.LExpr.(ast.LessThanExpression); ok {
+		return e.LessThan(expr.RExpr)
+	}
+	return nil, unsupportedOperands(expr)
+}
+
+func evalLessThanEqualToExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+	if expr.Op != lexer.LTE {
+		return nil, errors.New("Expected LTE operand")
+	}
+
+	if e, ok := expr.LExpr.(ast.LessThanEqualToExpression); ok {
+		return e.LessThanOrEqualTo(expr.RExpr)
+	}
+	return nil, unsupportedOperands(expr)
+}
+
+func evalGreaterThanExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+	if expr.Op != lexer.GT {
+		return nil, errors.New("Expected GT operand")
+	}
+
+	if e, ok := expr.LExpr.(ast.GreaterThanExpression); ok {
+		return e.GreaterThan(expr.RExpr)
+	}
+	return nil, unsupportedOperands(expr)
+}
+
+func evalGreaterThanEqualToExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+	if expr.Op != lexer.GTE {
+		return nil, errors.New("Expected GTE operand")
+	}
+
+	if e, ok := expr.LExpr.(ast.GreaterThanEqualToExpression); ok {
+		return e.GreaterThanOrEqualTo(expr.RExpr)
+	}
+	return nil, unsupportedOperands(expr)
+}
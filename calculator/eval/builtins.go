@@ -0,0 +1,85 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+)
+
+// Builtin is a function implemented by the evaluator. Builtins are resolved
+// after all scopes of the environment so worksheets may redefine them.
+type Builtin struct {
+	ast.Span
+
+	Name string
+
+	// Arity is the number of arguments or -1 for variadic functions
+	Arity int
+	Fn    func(env *Environment, args []ast.Expression) (ast.Expression, error)
+}
+
+func (b Builtin) Type() ast.ExpressionType { return ast.BuiltinFunctionType }
+func (b Builtin) String() string           { return fmt.Sprintf("<builtin %s>", b.Name) }
+
+// Call checks the number of arguments and calls the builtin from the given
+// environment.
+func (b *Builtin) Call(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	if b.Arity >= 0 && len(args) != b.Arity {
+		return nil, argumentCount(b.Name, b.Arity, len(args))
+	}
+	return b.Fn(env, args)
+}
+
+// builtins contains the functions available to every environment.
+var builtins = map[string]*Builtin{}
+
+// registerBuiltins adds builtins to the registry.
+func registerBuiltins(fns ...*Builtin) {
+	for _, fn := range fns {
+		builtins[fn.Name] = fn
+	}
+}
+
+func init() {
+	registerBuiltins(
+		&Builtin{Name: "popcount", Arity: 1, Fn: builtinPopcount},
+		&Builtin{Name: "bitlen", Arity: 1, Fn: builtinBitlen},
+	)
+}
+
+// integerArgument returns the value of an integer argument.
+func integerArgument(name string, arg ast.Expression) (*big.Int, error) {
+	if i, ok := arg.(*ast.IntegerLiteral); ok {
+		return i.Value, nil
+	}
+	return nil, fmt.Errorf("%s expects an integer argument, found %s", name, ast.OperandType(arg))
+}
+
+// builtinPopcount returns the number of set bits of a non-negative integer.
+func builtinPopcount(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	i, err := integerArgument("popcount", args[0])
+	if err != nil {
+		return nil, err
+	} else if i.Sign() < 0 {
+		return nil, errors.New("popcount of negative integer")
+	}
+
+	count := 0
+	for _, word := range i.Bits() {
+		for ; word != 0; word &= word - 1 {
+			count++
+		}
+	}
+	return &ast.IntegerLiteral{Value: big.NewInt(int64(count))}, nil
+}
+
+// builtinBitlen returns the length of the absolute value in bits.
+func builtinBitlen(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	i, err := integerArgument("bitlen", args[0])
+	if err != nil {
+		return nil, err
+	}
+	return &ast.IntegerLiteral{Value: big.NewInt(int64(i.BitLen()))}, nil
+}
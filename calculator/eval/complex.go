@@ -0,0 +1,166 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/bigmath"
+)
+
+func init() {
+	registerBuiltins(
+		complexBuiltin("abs", true, complexAbs, realAbs),
+		complexBuiltin("arg", false, complexArg, realArg),
+		complexBuiltin("conj", true, complexConj, realPart),
+		complexBuiltin("real", true, complexReal, realPart),
+		complexBuiltin("imag", true, complexImag, realImag),
+		&Builtin{Name: "polar", Arity: 1, Fn: builtinPolar},
+		&Builtin{Name: "rect", Arity: 2, Fn: builtinRect},
+	)
+}
+
+// complexFunctions are the elementary functions extended to complex
+// arguments.
+var complexFunctions = map[string]func(re, im *big.Float) (*big.Float, *big.Float, error){
+	"exp": bigmath.CExp,
+	"ln":  bigmath.CLog,
+}
+
+// complexBuiltin returns a builtin which applies a function to a complex
+// number, to the value of a quantity or to each element of an array. Real
+// numbers are passed to real, which keeps exact numbers exact. Results keep
+// the unit of quantities if unit is true.
+func complexBuiltin(name string, unit bool, fn func(z *ast.ComplexLiteral) ast.Expression, real func(env *Environment, x ast.Expression) ast.Expression) *Builtin {
+	var apply func(env *Environment, arg ast.Expression) (ast.Expression, error)
+	apply = func(env *Environment, arg ast.Expression) (ast.Expression, error) {
+		switch v := arg.(type) {
+		case *ast.ArrayLiteral:
+			return mapElements(env, v, apply)
+		case *ast.QuantityLiteral:
+			value, err := apply(env, v.Value)
+			if err != nil || !unit {
+				return value, err
+			}
+			return &ast.QuantityLiteral{Value: value, Unit: v.Unit}, nil
+		case *ast.ComplexLiteral:
+			return env.Config().number(fn(v)), nil
+		}
+
+		if !ast.IsNumber(arg) {
+			return nil, fmt.Errorf("%s expects a number, found %s", name, ast.OperandType(arg))
+		}
+		return env.Config().number(real(env, arg)), nil
+	}
+
+	return &Builtin{Name: name, Arity: 1, Fn: func(env *Environment, args []ast.Expression) (ast.Expression, error) {
+		return apply(env, args[0])
+	}}
+}
+
+// realAbs returns the absolute value of a real number.
+func realAbs(env *Environment, x ast.Expression) ast.Expression {
+	switch v := x.(type) {
+	case *ast.IntegerLiteral:
+		return &ast.IntegerLiteral{Value: new(big.Int).Abs(v.Value)}
+	case *ast.RationalLiteral:
+		return &ast.RationalLiteral{Value: new(big.Rat).Abs(v.Value)}
+	case *ast.DecimalLiteral:
+		return &ast.DecimalLiteral{Value: new(big.Float).Abs(v.Value)}
+	}
+	return x
+}
+
+// realArg returns the argument of a real number, π for negative numbers and
+// zero otherwise.
+func realArg(env *Environment, x ast.Expression) ast.Expression {
+	var sign int
+	switch v := x.(type) {
+	case *ast.IntegerLiteral:
+		sign = v.Value.Sign()
+	case *ast.RationalLiteral:
+		sign = v.Value.Sign()
+	case *ast.DecimalLiteral:
+		sign = v.Value.Sign()
+	}
+	if sign < 0 {
+		pi, _ := mathConstant("pi", env)
+		return pi
+	}
+	return &ast.IntegerLiteral{Value: big.NewInt(0)}
+}
+
+// realPart returns a real number, which is its own real part and conjugate.
+func realPart(env *Environment, x ast.Expression) ast.Expression {
+	return x
+}
+
+// realImag returns the imaginary part of a real number.
+func realImag(env *Environment, x ast.Expression) ast.Expression {
+	return &ast.IntegerLiteral{Value: big.NewInt(0)}
+}
+
+func complexAbs(z *ast.ComplexLiteral) ast.Expression {
+	return &ast.DecimalLiteral{Value: z.Abs()}
+}
+
+func complexArg(z *ast.ComplexLiteral) ast.Expression {
+	return &ast.DecimalLiteral{Value: z.Arg()}
+}
+
+func complexConj(z *ast.ComplexLiteral) ast.Expression {
+	return z.Conj()
+}
+
+func complexReal(z *ast.ComplexLiteral) ast.Expression {
+	return &ast.DecimalLiteral{Value: z.Re}
+}
+
+func complexImag(z *ast.ComplexLiteral) ast.Expression {
+	return &ast.DecimalLiteral{Value: z.Im}
+}
+
+// builtinPolar returns the magnitude and the argument of a number as an
+// array. The magnitude keeps the unit of quantities.
+func builtinPolar(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	abs, err := builtins["abs"].Fn(env, args)
+	if err != nil {
+		return nil, err
+	}
+	arg, err := builtins["arg"].Fn(env, args)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.ArrayLiteral{Elements: []ast.Expression{abs, arg}}, nil
+}
+
+// builtinRect returns the complex number with the magnitude and argument
+// given by its arguments. The magnitude may be a quantity.
+func builtinRect(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	config := env.Config()
+	q, isQuantity := args[0].(*ast.QuantityLiteral)
+	abs := args[0]
+	if isQuantity {
+		abs = q.Value
+	}
+
+	r, ok := config.float(abs)
+	if !ok {
+		return nil, fmt.Errorf("rect expects a real magnitude, found %s", ast.OperandType(args[0]))
+	}
+	theta, ok := config.float(args[1])
+	if !ok {
+		return nil, fmt.Errorf("rect expects a dimensionless angle, found %s", ast.OperandType(args[1]))
+	}
+
+	z, err := ast.NewPolar(r, theta)
+	if err == bigmath.ErrDomain {
+		return nil, &DomainError{Name: "rect", Argument: "angle", Value: args[1]}
+	} else if err != nil {
+		return nil, err
+	}
+	if isQuantity {
+		return config.number(&ast.QuantityLiteral{Value: z, Unit: q.Unit}), nil
+	}
+	return config.number(z), nil
+}
@@ -0,0 +1,64 @@
+package eval
+
+import (
+	"fmt"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+)
+
+// evalIfExpression evaluates the first branch whose condition is true. The
+// value is nil when no branch is taken.
+func evalIfExpression(expr *ast.IfExpression, env *Environment) (ast.Expression, error) {
+	cond, err := evalExpression(expr.Condition, env)
+	if err != nil {
+		return nil, err
+	}
+
+	b, ok := cond.(*ast.BooleanLiteral)
+	if !ok {
+		return nil, &ConditionError{Condition: expr.Condition, Value: cond}
+	}
+
+	switch {
+	case b.Value:
+		return evalBlockExpression(expr.Body, env)
+	case expr.Else != nil:
+		return evalExpression(expr.Else, env)
+	default:
+		return &ast.NilLiteral{}, nil
+	}
+}
+
+// evalMatchExpression evaluates the body of the first arm with a pattern
+// equal to the subject, or the else arm if no pattern is equal.
+func evalMatchExpression(expr *ast.MatchExpression, env *Environment) (ast.Expression, error) {
+	subject, err := evalExpression(expr.Subject, env)
+	if err != nil {
+		return nil, err
+	}
+	eq, ok := subject.(ast.EqualExpression)
+	if !ok {
+		return nil, fmt.Errorf("cannot match %s", ast.OperandType(subject))
+	}
+
+	for _, arm := range expr.Arms {
+		for _, pattern := range arm.Patterns {
+			value, err := evalExpression(pattern, env)
+			if err != nil {
+				return nil, err
+			}
+			result, err := eq.Equal(value)
+			if err != nil {
+				return nil, withPosition(err, pattern)
+			}
+			if b, ok := result.(*ast.BooleanLiteral); ok && b.Value {
+				return evalExpression(arm.Body, env)
+			}
+		}
+	}
+
+	if expr.Else != nil {
+		return evalExpression(expr.Else, env)
+	}
+	return nil, &MatchError{Value: subject}
+}
@@ -0,0 +1,168 @@
+package eval
+
+import (
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+)
+
+// DivisionMode selects the result of inexact integer division.
+type DivisionMode int
+
+const (
+	// DecimalDivision converts inexact quotients to decimals.
+	DecimalDivision DivisionMode = iota
+
+	// RationalDivision keeps inexact quotients as exact fractions.
+	RationalDivision
+)
+
+// Config holds the evaluation settings shared by every scope of an
+// environment.
+type Config struct {
+	Division DivisionMode
+
+	// Format selects how results are displayed.
+	Format NumberFormat
+
+	// Precision is the mantissa precision in bits of decimal results and
+	// Rounding the rounding mode used to reach it.
+	Precision uint
+	Rounding  big.RoundingMode
+
+	// Digits is the number of digits displayed after the decimal point of
+	// decimals.
+	Digits int
+
+	// MaxSteps limits the loop iterations, function calls and generated
+	// range elements of a single evaluation. Zero disables the limit.
+	MaxSteps int
+}
+
+// DefaultMaxSteps is the default evaluation step limit.
+const DefaultMaxSteps = 1000000
+
+// DefaultPrecision is the default mantissa precision of decimals in bits.
+const DefaultPrecision = 64
+
+// DefaultDigits is the default number of digits displayed after the decimal
+// point.
+const DefaultDigits = 16
+
+// DefaultConfig returns the default evaluation settings.
+func DefaultConfig() *Config {
+	return &Config{
+		Division:  DecimalDivision,
+		Format:    DefaultFormat,
+		Precision: DefaultPrecision,
+		Rounding:  big.ToNearestEven,
+		Digits:    DefaultDigits,
+		MaxSteps:  DefaultMaxSteps,
+	}
+}
+
+// DigitsPrecision returns the precision in bits required to represent the
+// number of decimal digits.
+func DigitsPrecision(digits int) uint {
+	return uint(math.Ceil(float64(digits) * math.Log2(10)))
+}
+
+var roundingModes = map[string]big.RoundingMode{
+	"nearesteven":  big.ToNearestEven,
+	"nearestaway":  big.ToNearestAway,
+	"zero":         big.ToZero,
+	"awayfromzero": big.AwayFromZero,
+	"floor":        big.ToNegativeInf,
+	"ceiling":      big.ToPositiveInf,
+}
+
+// ParseRoundingMode returns the rounding mode with the given name. Modes are
+// named as by big.RoundingMode, such as ToNearestEven, or by the short names
+// nearest-even, nearest-away, zero, away-from-zero, floor and ceiling.
+func ParseRoundingMode(name string) (big.RoundingMode, bool) {
+	key := strings.ToLower(strings.Replace(name, "-", "", -1))
+	for mode := big.ToNearestEven; mode <= big.ToPositiveInf; mode++ {
+		if strings.ToLower(mode.String()) == key {
+			return mode, true
+		}
+	}
+	mode, ok := roundingModes[key]
+	return mode, ok
+}
+
+// precision returns the precision of decimals in bits.
+func (c *Config) precision() uint {
+	if c.Precision == 0 {
+		return DefaultPrecision
+	}
+	return c.Precision
+}
+
+// decimal rounds a decimal to the configured precision and rounding mode.
+func (c *Config) decimal(d *ast.DecimalLiteral) ast.Expression {
+	if d.Value.Prec() == c.precision() && d.Value.Mode() == c.Rounding {
+		return d
+	}
+	return &ast.DecimalLiteral{Span: d.Span, Value: new(big.Float).SetPrec(c.precision()).SetMode(c.Rounding).Set(d.Value)}
+}
+
+// literal applies the configuration to a literal parsed from source. Decimal
+// literals of a lower precision are read by their shortest representation so
+// that 0.1 is the nearest value to a tenth at every precision. Computed
+// results are rounded by number and never widened this way.
+func (c *Config) literal(expr ast.Expression) ast.Expression {
+	switch e := expr.(type) {
+	case *ast.DecimalLiteral:
+		return c.decimal(c.widen(e))
+	case *ast.ComplexLiteral:
+		re := c.widen(&ast.DecimalLiteral{Value: e.Re})
+		im := c.widen(&ast.DecimalLiteral{Value: e.Im})
+		return c.number(&ast.ComplexLiteral{Span: e.Span, Re: re.Value, Im: im.Value})
+	}
+	return c.number(expr)
+}
+
+// widen reads a decimal of a lower precision than configured by its shortest
+// representation.
+func (c *Config) widen(d *ast.DecimalLiteral) *ast.DecimalLiteral {
+	prec := c.precision()
+	if d.Value.Prec() >= prec || d.Value.IsInf() {
+		return d
+	}
+	if r, ok := shortestRat(d); ok {
+		return &ast.DecimalLiteral{Span: d.Span, Value: new(big.Float).SetPrec(prec).SetMode(c.Rounding).SetRat(r)}
+	}
+	return d
+}
+
+// number applies the division mode, precision and rounding mode to a value.
+func (c *Config) number(expr ast.Expression) ast.Expression {
+	switch e := expr.(type) {
+	case *ast.DecimalLiteral:
+		return c.decimal(e)
+	case *ast.RationalLiteral:
+		if c.Division == DecimalDivision {
+			return &ast.DecimalLiteral{Value: new(big.Float).SetPrec(c.precision()).SetMode(c.Rounding).SetRat(e.Value)}
+		}
+	case *ast.ComplexLiteral:
+		re := c.decimal(&ast.DecimalLiteral{Value: e.Re}).(*ast.DecimalLiteral)
+		im := c.decimal(&ast.DecimalLiteral{Value: e.Im}).(*ast.DecimalLiteral)
+		return &ast.ComplexLiteral{Span: e.Span, Re: re.Value, Im: im.Value}
+	case *ast.QuantityLiteral:
+		return &ast.QuantityLiteral{Value: c.number(e.Value), Unit: e.Unit}
+	case *ast.ArrayLiteral:
+		elements := make([]ast.Expression, len(e.Elements))
+		for i, el := range e.Elements {
+			elements[i] = c.number(el)
+		}
+		return &ast.ArrayLiteral{Elements: elements}
+	case *ast.MatrixLiteral:
+		m, _ := e.Map(func(el ast.Expression) (ast.Expression, error) {
+			return c.number(el), nil
+		})
+		return m
+	}
+	return expr
+}
@@ -0,0 +1,216 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/bigmath"
+)
+
+func init() {
+	registerBuiltins(
+		rootBuiltin("sqrt", 2),
+		rootBuiltin("cbrt", 3),
+		&Builtin{Name: "root", Arity: 2, Fn: builtinRoot},
+		&Builtin{Name: "pow", Arity: 2, Fn: builtinPow},
+		&Builtin{Name: "log", Arity: -1, Fn: builtinLog},
+		mathBuiltin("exp", bigmath.Exp),
+		mathBuiltin("ln", bigmath.Log),
+		mathBuiltin("log10", bigmath.Log10),
+		mathBuiltin("log2", bigmath.Log2),
+		mathBuiltin("sin", bigmath.Sin),
+		mathBuiltin("cos", bigmath.Cos),
+		mathBuiltin("tan", bigmath.Tan),
+		mathBuiltin("asin", bigmath.Asin),
+		mathBuiltin("acos", bigmath.Acos),
+		mathBuiltin("atan", bigmath.Atan),
+		mathBuiltin("sinh", bigmath.Sinh),
+		mathBuiltin("cosh", bigmath.Cosh),
+		mathBuiltin("tanh", bigmath.Tanh),
+		mathBuiltin("asinh", bigmath.Asinh),
+		mathBuiltin("acosh", bigmath.Acosh),
+		mathBuiltin("atanh", bigmath.Atanh),
+	)
+}
+
+// mathConstants are the mathematical constants computed to the configured
+// precision. Declarations of the same names shadow them.
+var mathConstants = map[string]func(prec uint) *big.Float{
+	"pi": bigmath.Pi,
+	"e":  bigmath.E,
+}
+
+// mathConstant returns the value of a mathematical constant rounded to the
+// configured precision and rounding mode.
+func mathConstant(name string, env *Environment) (ast.Expression, bool) {
+	fn, ok := mathConstants[name]
+	if !ok {
+		return nil, false
+	}
+	config := env.Config()
+	return config.number(&ast.DecimalLiteral{Value: fn(config.precision() + 64)}), true
+}
+
+// float converts a number to a decimal with the configured precision and
+// rounding mode.
+func (c *Config) float(value ast.Expression) (*big.Float, bool) {
+	z := new(big.Float).SetPrec(c.precision()).SetMode(c.Rounding)
+	switch v := value.(type) {
+	case *ast.IntegerLiteral:
+		return z.SetInt(v.Value), true
+	case *ast.RationalLiteral:
+		return z.SetRat(v.Value), true
+	case *ast.DecimalLiteral:
+		return c.decimal(v).(*ast.DecimalLiteral).Value, true
+	}
+	return nil, false
+}
+
+// mathBuiltin returns a builtin which applies a function to a number or to
+// each element of an array. Angles are in radians, `30 deg` is converted to
+// radians when the quantity is created.
+func mathBuiltin(name string, fn func(x *big.Float) (*big.Float, error)) *Builtin {
+	var apply func(env *Environment, arg ast.Expression) (ast.Expression, error)
+	apply = func(env *Environment, arg ast.Expression) (ast.Expression, error) {
+		if array, ok := arg.(*ast.ArrayLiteral); ok {
+			return mapElements(env, array, apply)
+		} else if z, ok := arg.(*ast.ComplexLiteral); ok {
+			return complexFunction(env, name, z)
+		}
+
+		x, ok := env.Config().float(arg)
+		if !ok {
+			return nil, fmt.Errorf("%s expects a dimensionless number, found %s", name, ast.OperandType(arg))
+		}
+		z, err := fn(x)
+		if err == bigmath.ErrDomain {
+			return nil, &DomainError{Name: name, Argument: "argument", Value: arg}
+		} else if err != nil {
+			return nil, err
+		}
+		return env.Config().number(&ast.DecimalLiteral{Value: z}), nil
+	}
+
+	return &Builtin{Name: name, Arity: 1, Fn: func(env *Environment, args []ast.Expression) (ast.Expression, error) {
+		return apply(env, args[0])
+	}}
+}
+
+// complexFunction applies the complex extension of an elementary function.
+func complexFunction(env *Environment, name string, z *ast.ComplexLiteral) (ast.Expression, error) {
+	fn, ok := complexFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("%s does not support complex arguments", name)
+	}
+	re, im, err := fn(z.Re, z.Im)
+	if err == bigmath.ErrDomain {
+		return nil, &DomainError{Name: name, Argument: "argument", Value: z}
+	} else if err != nil {
+		return nil, err
+	}
+	return env.Config().number(&ast.ComplexLiteral{Re: re, Im: im}), nil
+}
+
+// mapElements applies a function to each element of an array.
+func mapElements(env *Environment, array *ast.ArrayLiteral, fn func(env *Environment, arg ast.Expression) (ast.Expression, error)) (ast.Expression, error) {
+	elements := make([]ast.Expression, len(array.Elements))
+	for i, el := range array.Elements {
+		var err error
+		if elements[i], err = fn(env, el); err != nil {
+			return nil, err
+		}
+	}
+	return &ast.ArrayLiteral{Elements: elements}, nil
+}
+
+// rootBuiltin returns a builtin for the nth root.
+func rootBuiltin(name string, n int64) *Builtin {
+	return &Builtin{Name: name, Arity: 1, Fn: func(env *Environment, args []ast.Expression) (ast.Expression, error) {
+		return nthRoot(env, name, args[0], big.NewInt(n))
+	}}
+}
+
+// builtinRoot returns the nth root of a value.
+func builtinRoot(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	n, err := integerArgument("root", args[1])
+	if err != nil {
+		return nil, err
+	} else if n.Sign() <= 0 {
+		return nil, fmt.Errorf("root: degree must be positive, found %s", n)
+	}
+	return nthRoot(env, "root", args[0], n)
+}
+
+// nthRoot returns the nth root of a number, each element of an array or a
+// quantity whose dimension is divisible by n.
+func nthRoot(env *Environment, name string, arg ast.Expression, n *big.Int) (ast.Expression, error) {
+	exp := &ast.RationalLiteral{Value: new(big.Rat).SetFrac(big.NewInt(1), n)}
+	return power(env, name, arg, exp)
+}
+
+// builtinPow returns x**y for real exponents.
+func builtinPow(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	return power(env, "pow", args[0], args[1])
+}
+
+// power raises a value to a power. Numbers are converted to decimals with
+// the configured precision first so that the result is computed with it.
+func power(env *Environment, name string, base, exp ast.Expression) (ast.Expression, error) {
+	config, arg := env.Config(), base
+	switch b := base.(type) {
+	case *ast.ArrayLiteral:
+		return mapElements(env, b, func(env *Environment, el ast.Expression) (ast.Expression, error) {
+			return power(env, name, el, exp)
+		})
+	case *ast.QuantityLiteral:
+		if x, ok := config.float(b.Value); ok {
+			base = &ast.QuantityLiteral{Value: &ast.DecimalLiteral{Value: x}, Unit: b.Unit}
+		}
+	default:
+		if x, ok := config.float(b); ok {
+			base = &ast.DecimalLiteral{Value: x}
+		}
+	}
+
+	pow, ok := base.(ast.PowExpression)
+	if !ok {
+		return nil, fmt.Errorf("%s expects a number, found %s", name, ast.OperandType(base))
+	}
+	z, err := pow.Pow(exp)
+	if err == bigmath.ErrDomain {
+		return nil, &DomainError{Name: name, Argument: "argument", Value: arg}
+	} else if err != nil {
+		return nil, err
+	}
+	return config.number(z), nil
+}
+
+// builtinLog returns the natural logarithm of a value or its logarithm in
+// the base given by the second argument.
+func builtinLog(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, &ArgumentCountError{Name: "log", Expected: "1 or 2", Found: len(args)}
+	}
+	ln := builtins["ln"]
+	x, err := ln.Fn(env, args[:1])
+	if err != nil || len(args) == 1 {
+		return x, err
+	}
+
+	base, err := ln.Fn(env, args[1:])
+	if err != nil {
+		return nil, err
+	} else if z, ok := base.(*ast.DecimalLiteral); ok && z.Value.Sign() == 0 {
+		return nil, &DomainError{Name: "log", Argument: "base", Value: args[1]}
+	}
+	div, ok := x.(ast.DivExpression)
+	if !ok {
+		return nil, fmt.Errorf("log expects a number, found %s", ast.OperandType(x))
+	}
+	z, err := div.Div(base)
+	if err != nil {
+		return nil, err
+	}
+	return env.Config().number(z), nil
+}
@@ -0,0 +1,45 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+)
+
+// evalEnumDeclaration declares the enum type under its name.
+func evalEnumDeclaration(expr *ast.EnumDeclaration, env *Environment) (ast.Expression, error) {
+	seen := make(map[string]bool, len(expr.Members))
+	for _, m := range expr.Members {
+		if seen[m] {
+			return nil, fmt.Errorf("duplicate member %s in enum %s", m, expr.Name)
+		}
+		seen[m] = true
+	}
+	if err := env.Declare(expr.Name, expr, false); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// enumField returns a member of an enum, `Level.HIGH`, or the name or
+// ordinal of a member, `Level.HIGH.Ordinal`.
+func enumField(value ast.Expression, name string) (ast.Expression, error) {
+	switch v := value.(type) {
+	case *ast.EnumDeclaration:
+		if member, ok := v.Member(name); ok {
+			return member, nil
+		}
+		return nil, fmt.Errorf("unknown member %s in enum %s", name, v.Name)
+	case *ast.EnumLiteral:
+		switch name {
+		case "Name":
+			return &ast.StringLiteral{Value: v.Name()}, nil
+		case "Ordinal":
+			return &ast.IntegerLiteral{Value: big.NewInt(int64(v.Ordinal))}, nil
+		}
+		return nil, fmt.Errorf("unknown field %s of %s", name, v.String())
+	default:
+		return nil, fmt.Errorf("cannot access field %s of %s", name, value.String())
+	}
+}
@@ -0,0 +1,210 @@
+package eval
+
+import (
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/units"
+)
+
+// binding stores a declared value. Derived constants compute their value
+// with the settings in effect when they are referenced.
+type binding struct {
+	value    ast.Expression
+	constant bool
+	derive   func(c *Config) (ast.Expression, error)
+}
+
+// get returns the value of the binding referenced from the environment.
+func (b *binding) get(env *Environment) (ast.Expression, error) {
+	if b.derive != nil {
+		return b.derive(env.Config())
+	}
+	return b.value, nil
+}
+
+// Environment holds the declared values of a scope. Environments are nested
+// and lookups fall through to the enclosing scope.
+type Environment struct {
+	parent   *Environment
+	function bool
+	values   map[string]*binding
+	config   *Config
+	units    *units.Registry
+	loader   *Loader
+
+	// depth counts the active function calls of a root environment and
+	// steps the work done by the current evaluation
+	depth int
+	steps int
+}
+
+// NewEnvironment returns a new worksheet environment using the default
+// config, no declared units and a module loader without a search path. The
+// worksheet is a scope of a root library environment, which modules are
+// evaluated in, so that modules never see the worksheet's declarations.
+func NewEnvironment() *Environment {
+	return newLibrary(units.NewRegistry()).worksheet()
+}
+
+// newLibrary returns a root environment with the units.
+func newLibrary(registry *units.Registry) *Environment {
+	return &Environment{
+		function: true,
+		values:   make(map[string]*binding),
+		config:   DefaultConfig(),
+		units:    registry,
+		loader:   NewLoader(),
+	}
+}
+
+// worksheet returns a function scope of the library whose unit declarations
+// shadow the units of the library.
+func (e *Environment) worksheet() *Environment {
+	env := e.NewFunctionScope()
+	env.units = e.units.Extend()
+	return env
+}
+
+// NewScope returns a block scope nested inside the environment.
+func (e *Environment) NewScope() *Environment {
+	return &Environment{parent: e, values: make(map[string]*binding)}
+}
+
+// NewFunctionScope returns a function scope nested inside the environment.
+// `var` declarations inside of the scope are bound to it.
+func (e *Environment) NewFunctionScope() *Environment {
+	return &Environment{parent: e, function: true, values: make(map[string]*binding)}
+}
+
+// Parent returns the enclosing scope or nil for the root environment.
+func (e *Environment) Parent() *Environment { return e.parent }
+
+// Config returns the evaluation settings of the nearest environment which
+// has them, which is the root environment unless a scope overrides them.
+func (e *Environment) Config() *Config {
+	env := e
+	for env.config == nil && env.parent != nil {
+		env = env.parent
+	}
+	return env.config
+}
+
+// withConfig returns a block scope nested inside the environment which
+// evaluates with its own copy of the settings changed by fn.
+func (e *Environment) withConfig(fn func(c *Config)) *Environment {
+	config := *e.Config()
+	fn(&config)
+	scope := e.NewScope()
+	scope.config = &config
+	return scope
+}
+
+// Loader returns the module loader of the root environment.
+func (e *Environment) Loader() *Loader {
+	return e.root().loader
+}
+
+// Units returns the unit registry of the nearest environment which has one.
+func (e *Environment) Units() *units.Registry {
+	env := e
+	for env.units == nil && env.parent != nil {
+		env = env.parent
+	}
+	return env.units
+}
+
+// declaredUnits returns the registry which unit declarations are added to.
+// It belongs to the nearest function scope so that units declared in a
+// function body or a module are not visible once it returns.
+func (e *Environment) declaredUnits() *units.Registry {
+	env := e.functionScope()
+	if env.units == nil {
+		env.units = env.Units().Extend()
+	}
+	return env.units
+}
+
+// step counts a unit of evaluation work against the step limit.
+func (e *Environment) step() error {
+	root := e.root()
+	root.steps++
+	if max := root.config.MaxSteps; max > 0 && root.steps > max {
+		return ErrStepLimit
+	}
+	return nil
+}
+
+// root returns the outermost environment.
+func (e *Environment) root() *Environment {
+	env := e
+	for env.parent != nil {
+		env = env.parent
+	}
+	return env
+}
+
+// Get returns the value bound to the name in the nearest scope.
+func (e *Environment) Get(name string) (ast.Expression, bool) {
+	value, ok, err := e.lookup(name)
+	return value, ok && err == nil
+}
+
+// lookup returns the value bound to the name in the nearest scope along with
+// the error of computing a derived constant.
+func (e *Environment) lookup(name string) (ast.Expression, bool, error) {
+	for env := e; env != nil; env = env.parent {
+		if b, ok := env.values[name]; ok {
+			value, err := b.get(e)
+			return value, true, err
+		}
+	}
+	return nil, false, nil
+}
+
+// IsConstant returns true if the name resolves to a constant.
+func (e *Environment) IsConstant(name string) bool {
+	for env := e; env != nil; env = env.parent {
+		if b, ok := env.values[name]; ok {
+			return b.constant
+		}
+	}
+	return false
+}
+
+// Declare binds the value to the name in this scope. Names may be redeclared
+// unless they are bound to a constant.
+func (e *Environment) Declare(name string, value ast.Expression, constant bool) error {
+	return e.bind(name, &binding{value: value, constant: constant})
+}
+
+// bind adds the binding to this scope unless the name is bound to a
+// constant.
+func (e *Environment) bind(name string, b *binding) error {
+	if b, ok := e.values[name]; ok && b.constant {
+		return &ConstantAssignmentError{Name: name}
+	}
+	e.values[name] = b
+	return nil
+}
+
+// Assign updates the value of an existing binding in the nearest scope.
+func (e *Environment) Assign(name string, value ast.Expression) error {
+	for env := e; env != nil; env = env.parent {
+		if b, ok := env.values[name]; ok {
+			if b.constant {
+				return &ConstantAssignmentError{Name: name}
+			}
+			b.value = value
+			return nil
+		}
+	}
+	return &UndefinedError{Name: name}
+}
+
+// functionScope returns the nearest enclosing function scope.
+func (e *Environment) functionScope() *Environment {
+	env := e
+	for !env.function && env.parent != nil {
+		env = env.parent
+	}
+	return env
+}
@@ -0,0 +1,325 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/bigmath"
+	"github.com/eliquious/aechbar/calculator/units"
+)
+
+// ErrStepLimit is returned when an evaluation exceeds the configured number
+// of steps.
+var ErrStepLimit = errors.New("evaluation step limit exceeded")
+
+// ErrCallDepth is returned when function calls are nested too deeply.
+var ErrCallDepth = errors.New("maximum call depth exceeded")
+
+// ConstantAssignmentError is returned when a constant is reassigned or
+// redeclared.
+type ConstantAssignmentError struct {
+	Name string
+}
+
+// Error returns the string representation of the error.
+func (e *ConstantAssignmentError) Error() string {
+	return fmt.Sprintf("cannot assign to constant '%s'", e.Name)
+}
+
+// UndefinedError is returned when a name is not declared in the environment.
+type UndefinedError struct {
+	Name string
+}
+
+// Error returns the string representation of the error.
+func (e *UndefinedError) Error() string {
+	return fmt.Sprintf("undefined: %s", e.Name)
+}
+
+// UnknownUnitError is returned when a unit symbol has not been declared.
+type UnknownUnitError struct {
+	Symbol string
+}
+
+// Error returns the string representation of the error.
+func (e *UnknownUnitError) Error() string {
+	return fmt.Sprintf("unknown unit: %s", e.Symbol)
+}
+
+// ArgumentCountError is returned when a function is called with the wrong
+// number of arguments.
+type ArgumentCountError struct {
+	Name     string
+	Expected string
+	Found    int
+}
+
+// Error returns the string representation of the error.
+func (e *ArgumentCountError) Error() string {
+	return fmt.Sprintf("%s expects %s argument(s), found %d", e.Name, e.Expected, e.Found)
+}
+
+// argumentCount returns an error for a call with n arguments to a function
+// taking a fixed number of parameters.
+func argumentCount(name string, params, n int) error {
+	return &ArgumentCountError{Name: name, Expected: strconv.Itoa(params), Found: n}
+}
+
+// TypeMismatchError is returned when a value does not match the annotation
+// of a parameter, a return value or a field. Type is the type of the value.
+type TypeMismatchError struct {
+	Expected string
+	Found    string
+	Type     string
+}
+
+// Error returns the string representation of the error.
+func (e *TypeMismatchError) Error() string {
+	return fmt.Sprintf("expected %s, found %s", e.Expected, e.Found)
+}
+
+// typeMismatch returns an error for a value which is not of the expected
+// type.
+func typeMismatch(expected string, value ast.Expression) error {
+	return &TypeMismatchError{Expected: expected, Found: value.String(), Type: ast.OperandKind(value)}
+}
+
+// IndexOutOfRangeError is returned when an index is outside of an array or
+// string.
+type IndexOutOfRangeError struct {
+	Index  int
+	Length int
+}
+
+// Error returns the string representation of the error.
+func (e *IndexOutOfRangeError) Error() string {
+	return fmt.Sprintf("index %d out of range for length %d", e.Index, e.Length)
+}
+
+// ConditionError is returned when the condition of an if is not a boolean.
+type ConditionError struct {
+	Condition ast.Expression
+	Value     ast.Expression
+}
+
+// Error returns the string representation of the error.
+func (e *ConditionError) Error() string {
+	condition, value := e.Condition.String(), e.Value.String()
+	if condition == value {
+		return fmt.Sprintf("non-boolean condition %s", condition)
+	}
+	return fmt.Sprintf("non-boolean condition %s: found %s", condition, value)
+}
+
+// MatchError is returned when no arm of a match equals its subject.
+type MatchError struct {
+	Value ast.Expression
+}
+
+// Error returns the string representation of the error.
+func (e *MatchError) Error() string {
+	return fmt.Sprintf("match is not exhaustive: no arm for %s", e.Value.String())
+}
+
+// DomainError is returned when the argument of a function is outside of its
+// domain, such as `ln(-1)`. It wraps bigmath.ErrDomain.
+type DomainError struct {
+	Name     string
+	Argument string
+	Value    ast.Expression
+}
+
+// Error returns the string representation of the error.
+func (e *DomainError) Error() string {
+	return fmt.Sprintf("%s: %s %s out of domain", e.Name, e.Argument, e.Value.String())
+}
+
+// Unwrap returns bigmath.ErrDomain.
+func (e *DomainError) Unwrap() error { return bigmath.ErrDomain }
+
+// ErrorCode classifies evaluation errors.
+type ErrorCode int
+
+// Error codes
+const (
+	UnknownError ErrorCode = iota
+	UnsupportedOperation
+	IncompatibleDimensions
+	DivisionByZero
+	LengthMismatch
+	UndefinedName
+	UnknownUnit
+	ConstantAssignment
+	ImportCycle
+	ShapeMismatch
+	SingularMatrix
+	ArgumentCount
+	TypeMismatch
+	IndexOutOfRange
+	NonBooleanCondition
+	NonExhaustiveMatch
+	OutOfDomain
+	StepLimit
+	CallDepth
+)
+
+var errorCodes = map[ErrorCode]string{
+	UnknownError:           "UnknownError",
+	UnsupportedOperation:   "UnsupportedOperation",
+	IncompatibleDimensions: "IncompatibleDimensions",
+	DivisionByZero:         "DivisionByZero",
+	LengthMismatch:         "LengthMismatch",
+	UndefinedName:          "UndefinedName",
+	UnknownUnit:            "UnknownUnit",
+	ConstantAssignment:     "ConstantAssignment",
+	ImportCycle:            "ImportCycle",
+	ShapeMismatch:          "ShapeMismatch",
+	SingularMatrix:         "SingularMatrix",
+	ArgumentCount:          "ArgumentCount",
+	TypeMismatch:           "TypeMismatch",
+	IndexOutOfRange:        "IndexOutOfRange",
+	NonBooleanCondition:    "NonBooleanCondition",
+	NonExhaustiveMatch:     "NonExhaustiveMatch",
+	OutOfDomain:            "OutOfDomain",
+	StepLimit:              "StepLimit",
+	CallDepth:              "CallDepth",
+}
+
+// String returns the name of the error code.
+func (c ErrorCode) String() string {
+	return errorCodes[c]
+}
+
+// Error represents an error that occurred during evaluation. The span is the
+// innermost expression parsed from source which failed and the types are
+// those of the operands involved, if any.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Span    ast.Span
+	Types   []string
+	Hint    string
+	Err     error
+}
+
+// newError classifies an error returned while evaluating an expression.
+// Errors wrapped by the error of a function call or an import keep their
+// classification.
+func newError(err error) *Error {
+	e := &Error{Message: err.Error(), Err: err}
+	var (
+		operand   *ast.OperandError
+		integer   *ast.IntegerOperandError
+		dimension *units.DimensionError
+		length    *ast.LengthError
+		shape     *ast.ShapeError
+		square    *ast.SquareError
+		cycle     *ImportCycleError
+		undefined *UndefinedError
+		unknown   *UnknownUnitError
+		constant  *ConstantAssignmentError
+		arguments *ArgumentCountError
+		mismatch  *TypeMismatchError
+		index     *IndexOutOfRangeError
+		condition *ConditionError
+		match     *MatchError
+	)
+	switch {
+	case errors.As(err, &operand):
+		e.Code = UnsupportedOperation
+		e.Types = []string{operand.Left, operand.Right}
+	case errors.As(err, &integer):
+		e.Code = UnsupportedOperation
+		e.Types = []string{ast.OperandKind(integer.Value)}
+		e.Hint = "bitwise operators apply to integers only"
+	case errors.As(err, &dimension):
+		e.Code = IncompatibleDimensions
+		e.Types = []string{quantityType(dimension.Left), quantityType(dimension.Right)}
+		e.Hint = "both operands must measure the same dimension"
+	case errors.Is(err, ast.ErrDivisionByZero):
+		e.Code = DivisionByZero
+	case errors.As(err, &length):
+		e.Code = LengthMismatch
+	case errors.As(err, &shape):
+		e.Code = ShapeMismatch
+		if shape.Op == "Multiplication" {
+			e.Hint = "the number of columns of the left operand must match the number of rows of the right operand"
+		}
+	case errors.As(err, &square):
+		e.Code = ShapeMismatch
+	case errors.Is(err, ast.ErrSingularMatrix):
+		e.Code = SingularMatrix
+	case errors.As(err, &cycle):
+		e.Code = ImportCycle
+	case errors.As(err, &undefined):
+		e.Code = UndefinedName
+		e.Hint = fmt.Sprintf("declare %s with var or const before using it", undefined.Name)
+	case errors.As(err, &unknown):
+		e.Code = UnknownUnit
+		e.Hint = fmt.Sprintf("declare %s with a unit declaration", unknown.Symbol)
+	case errors.As(err, &constant):
+		e.Code = ConstantAssignment
+		e.Hint = "constants cannot be reassigned, declare a variable with var instead"
+	case errors.As(err, &arguments):
+		e.Code = ArgumentCount
+	case errors.As(err, &mismatch):
+		e.Code = TypeMismatch
+		e.Types = []string{mismatch.Expected, mismatch.Type}
+	case errors.As(err, &index):
+		e.Code = IndexOutOfRange
+		e.Hint = "negative indices count from the end"
+	case errors.As(err, &condition):
+		e.Code = NonBooleanCondition
+		e.Types = []string{ast.OperandKind(condition.Value)}
+		e.Hint = "compare the value to get a boolean, as in x != 0"
+	case errors.As(err, &match):
+		e.Code = NonExhaustiveMatch
+		e.Types = []string{ast.OperandKind(match.Value)}
+		e.Hint = "add an arm for the value or an else arm"
+	case errors.Is(err, bigmath.ErrDomain):
+		e.Code = OutOfDomain
+	case errors.Is(err, ErrStepLimit):
+		e.Code = StepLimit
+		e.Hint = "the evaluation may not terminate, or the step limit may be raised"
+	case errors.Is(err, ErrCallDepth):
+		e.Code = CallDepth
+		e.Hint = "the recursion may not terminate"
+	}
+	return e
+}
+
+// quantityType returns the type name of quantities of a unit.
+func quantityType(unit units.Compound) string {
+	if unit.IsEmpty() {
+		return ast.NumberTypeName
+	}
+	return "quantity(" + unit.Dimension().String() + ")"
+}
+
+// Error returns the string representation of the error.
+func (e *Error) Error() string {
+	if !e.Span.IsValid() {
+		return e.Message
+	}
+	return fmt.Sprintf("%s at line %d, char %d", e.Message, e.Span.Start.Line+1, e.Span.Start.Char+1)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error { return e.Err }
+
+// withPosition converts an error into an Error with the span of the
+// expression. Errors which already have a span are returned as they are, so
+// the step limit and the call depth are reported at the innermost loop or
+// call which exceeded them.
+func withPosition(err error, expr ast.Expression) error {
+	e, ok := err.(*Error)
+	if !ok {
+		e = newError(err)
+	}
+	if span := expr.Position(); !e.Span.IsValid() && span.IsValid() {
+		e.Span = span
+	}
+	return e
+}
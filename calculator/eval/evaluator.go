@@ -2,73 +2,300 @@ package eval
 
 import (
 	"errors"
+	"fmt"
 	"github.com/eliquious/aechbar/calculator/ast"
 	"github.com/eliquious/lexer"
 	"math/big"
+	"strings"
 )
 
-func Evaluate(expr ast.Expression) (string, error) {
-	exp, err := evalExpression(expr)
+// Evaluate evaluates the expression against the environment and returns the
+// string representation of the result in the configured number format.
+func Evaluate(expr ast.Expression, env *Environment) (string, error) {
+	exp, err := EvaluateValue(expr, env)
 	if err != nil {
 		return "", err
 	}
-	return exp.String(), nil
+	return FormatValue(exp, env.Config().Format, env.Config().Digits), nil
 }
 
-func evalExpression(expr ast.Expression) (ast.Expression, error) {
+// EvaluateValue evaluates the expression against the environment and returns
+// the resulting value.
+func EvaluateValue(expr ast.Expression, env *Environment) (ast.Expression, error) {
+	env.root().steps = 0
+	return evalExpression(expr, env)
+}
+
+// evalExpression evaluates an expression. Errors are annotated with the span
+// of the innermost expression parsed from source which failed.
+func evalExpression(expr ast.Expression, env *Environment) (ast.Expression, error) {
+	value, err := evalNode(expr, env)
+	if err != nil {
+		return nil, withPosition(err, expr)
+	}
+	return value, nil
+}
+
+func evalNode(expr ast.Expression, env *Environment) (ast.Expression, error) {
 	switch expr.Type() {
-	case ast.IntegerLiteralType, ast.DecimalLiteralType,
-		ast.StringLiteralType, ast.DurationLiteralType,
-		ast.TimestampLiteralType, ast.BooleanLiteralType:
+	case ast.DecimalLiteralType, ast.ComplexLiteralType:
+		return env.Config().literal(expr), nil
+	case ast.IntegerLiteralType, ast.RationalLiteralType, ast.QuantityLiteralType,
+		ast.FunctionType, ast.BuiltinFunctionType, ast.NilLiteralType, ast.StringLiteralType, ast.DurationLiteralType,
+		ast.TimestampLiteralType, ast.BooleanLiteralType, ast.StructLiteralType, ast.EnumLiteralType, ast.ModuleType:
 		return expr, nil
+	case ast.IdentifierExpressionType:
+		return evalIdentifier(expr.(*ast.Identifier), env)
+	case ast.GroupExpressionType:
+		return evalExpression(expr.(*ast.GroupExpression).Expr, env)
+	case ast.AssignmentExpressionType:
+		return evalAssignmentExpression(expr.(*ast.AssignmentExpression), env)
+	case ast.CallFunctionExpressionType:
+		return evalCallExpression(expr.(*ast.CallExpression), env)
 	case ast.UnaryExpressionType:
-		return evalUnaryExpression(expr.(*ast.UnaryExpression))
+		return evalUnaryExpression(expr.(*ast.UnaryExpression), env)
 	case ast.BinaryExpressionType:
-		return evalBinaryExpression(expr.(*ast.BinaryExpression))
+		return evalBinaryExpression(expr.(*ast.BinaryExpression), env)
+	case ast.VariableDeclarationType, ast.ScopedVariableDeclarationType:
+		return evalVariableDeclaration(expr.(*ast.VariableDeclaration), env)
+	case ast.ConstantDeclarationType:
+		return evalConstantDeclaration(expr.(*ast.ConstantDeclaration), env)
+	case ast.FunctionDeclarationType:
+		return evalFunctionDeclaration(expr.(*ast.FunctionDeclaration), env)
+	case ast.StructDeclarationType:
+		return evalStructDeclaration(expr.(*ast.StructDeclaration), env)
+	case ast.TypeDeclarationType:
+		return evalTypeDeclaration(expr.(*ast.TypeDeclaration), env)
+	case ast.EnumDeclarationType:
+		return evalEnumDeclaration(expr.(*ast.EnumDeclaration), env)
+	case ast.StructExpressionType:
+		return evalStructExpression(expr.(*ast.StructExpression), env)
+	case ast.FieldExpressionType:
+		return evalFieldExpression(expr.(*ast.FieldExpression), env)
+	case ast.MethodCallExpressionType:
+		return evalMethodCallExpression(expr.(*ast.MethodCallExpression), env)
+	case ast.ImportExpressionType:
+		return evalImportExpression(expr.(*ast.ImportExpression), env)
+	case ast.LambdaExpressionType:
+		return evalLambdaExpression(expr.(*ast.LambdaExpression), env)
+	case ast.BlockExpressionType:
+		return evalBlockExpression(expr.(*ast.BlockExpression), env)
+	case ast.IfExpressionType:
+		return evalIfExpression(expr.(*ast.IfExpression), env)
+	case ast.ArrayLiteralType:
+		return evalArrayLiteral(expr.(*ast.ArrayLiteral), env)
+	case ast.MatrixLiteralType:
+		return evalMatrixLiteral(expr.(*ast.MatrixLiteral), env)
+	case ast.IndexExpressionType:
+		return evalIndexExpression(expr.(*ast.IndexExpression), env)
+	case ast.SliceExpressionType:
+		return evalSliceExpression(expr.(*ast.SliceExpression), env)
+	case ast.ForExpressionType:
+		return evalForExpression(expr.(*ast.ForExpression), env)
+	case ast.MatchExpressionType:
+		return evalMatchExpression(expr.(*ast.MatchExpression), env)
+	case ast.ElseExpressionType:
+		return evalBlockExpression(expr.(*ast.ElseExpression).Body, env)
+	case ast.QuantityExpressionType:
+		return evalQuantityExpression(expr.(*ast.QuantityExpression), env)
+	case ast.ConversionExpressionType:
+		return evalConversionExpression(expr.(*ast.ConversionExpression), env)
+	case ast.UnitDeclarationType:
+		return evalUnitDeclaration(expr.(*ast.UnitDeclaration), env)
+	case ast.ConversionDeclarationType:
+		return evalConversionDeclaration(expr.(*ast.ConversionDeclaration), env)
 	default:
 		return nil, errors.New("Unsupported expression")
 	}
 }
 
-func evalUnaryExpression(expr *ast.UnaryExpression) (ast.Expression, error) {
-	switch expr.Expr.Type() {
+func evalIdentifier(expr *ast.Identifier, env *Environment) (ast.Expression, error) {
+	if value, ok, err := env.lookup(expr.Name); err != nil {
+		return nil, err
+	} else if ok {
+		return env.Config().number(value), nil
+	} else if fn, ok := builtins[expr.Name]; ok {
+		return fn, nil
+	} else if fn, ok := builtins[strings.ToLower(expr.Name)]; ok {
+		// Builtins named by keywords are case insensitive like the keywords
+		return fn, nil
+	} else if value, ok := mathConstant(expr.Name, env); ok {
+		return value, nil
+	}
+	return nil, &UndefinedError{Name: expr.Name}
+}
+
+func evalCallExpression(expr *ast.CallExpression, env *Environment) (ast.Expression, error) {
+	fn, err := evalExpression(expr.Function, env)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]ast.Expression, len(expr.Args))
+	for i, arg := range expr.Args {
+		if args[i], err = evalExpression(arg, env); err != nil {
+			return nil, err
+		}
+	}
+
+	if !isFunction(fn) {
+		return nil, fmt.Errorf("cannot call non-function %s", expr.Function.String())
+	}
+	return callFunction(env, fn, args)
+}
+
+// evalMethodCallExpression calls a function of a module, `physics.force(m)`,
+// or calls the named function with the receiver as the first argument, so
+// `a.filter(f)` is `filter(a, f)`.
+func evalMethodCallExpression(expr *ast.MethodCallExpression, env *Environment) (ast.Expression, error) {
+	recv, err := evalExpression(expr.Recv, env)
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]ast.Expression, len(expr.Args))
+	for i, arg := range expr.Args {
+		if args[i], err = evalExpression(arg, env); err != nil {
+			return nil, err
+		}
+	}
+
+	var fn ast.Expression
+	if m, ok := recv.(*Module); ok {
+		if fn, ok = m.Get(expr.Name); !ok {
+			return nil, &UndefinedError{Name: m.Name + "." + expr.Name}
+		}
+	} else {
+		if fn, err = evalIdentifier(&ast.Identifier{Name: expr.Name}, env); err != nil {
+			return nil, err
+		}
+		args = append([]ast.Expression{recv}, args...)
+	}
+
+	if !isFunction(fn) {
+		return nil, fmt.Errorf("cannot call non-function %s", expr.Name)
+	}
+	return callFunction(env, fn, args)
+}
+
+func evalAssignmentExpression(expr *ast.AssignmentExpression, env *Environment) (ast.Expression, error) {
+	value, err := evalExpression(expr.Value, env)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := env.Assign(expr.Name, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func evalUnaryExpression(expr *ast.UnaryExpression, env *Environment) (ast.Expression, error) {
+	exp, err := evalExpression(expr.Expr, env)
+	if err != nil {
+		return nil, err
+	}
+	result, err := evalUnaryOperand(expr.Op, exp)
+	if err != nil {
+		return nil, err
+	}
+	return env.Config().number(result), nil
+}
+
+func evalUnaryOperand(op lexer.Token, exp ast.Expression) (ast.Expression, error) {
+	switch exp.Type() {
 	case ast.IntegerLiteralType:
-		return evalUnaryIntegerExpression(expr.Op, expr.Expr.(*ast.IntegerLiteral))
+		return evalUnaryIntegerExpression(op, exp.(*ast.IntegerLiteral))
 	case ast.DecimalLiteralType:
-		return evalUnaryDecimalExpression(expr.Op, expr.Expr.(*ast.DecimalLiteral))
+		return evalUnaryDecimalExpression(op, exp.(*ast.DecimalLiteral))
+	case ast.RationalLiteralType:
+		return evalUnaryRationalExpression(op, exp.(*ast.RationalLiteral))
+	case ast.ComplexLiteralType:
+		return evalUnaryComplexExpression(op, exp.(*ast.ComplexLiteral))
+	case ast.QuantityLiteralType:
+		return evalUnaryQuantityExpression(op, exp.(*ast.QuantityLiteral))
+	case ast.ArrayLiteralType:
+		return evalUnaryArrayExpression(op, exp.(*ast.ArrayLiteral))
+	case ast.MatrixLiteralType:
+		return evalUnaryMatrixExpression(op, exp.(*ast.MatrixLiteral))
 	default:
 		return nil, errors.New("Unsupported unary expression")
 	}
 }
 
 func evalUnaryIntegerExpression(op lexer.Token, expr *ast.IntegerLiteral) (ast.Expression, error) {
-	if op == lexer.MINUSMINUS {
-		return &ast.IntegerLiteral{expr.Value.Add(expr.Value, big.NewInt(-1))}, nil
+	if op == lexer.MINUS {
+		return &ast.IntegerLiteral{Value: new(big.Int).Neg(expr.Value)}, nil
+	} else if op == lexer.XOR {
+		return &ast.IntegerLiteral{Value: new(big.Int).Not(expr.Value)}, nil
+	} else if op == lexer.PLUS {
+		return expr, nil
+	} else if op == lexer.MINUSMINUS {
+		return &ast.IntegerLiteral{Value: new(big.Int).Add(expr.Value, big.NewInt(-1))}, nil
 	} else if op == lexer.PLUSPLUS {
-		return &ast.IntegerLiteral{expr.Value.Add(expr.Value, big.NewInt(1))}, nil
+		return &ast.IntegerLiteral{Value: new(big.Int).Add(expr.Value, big.NewInt(1))}, nil
 	}
 	return nil, errors.New("Unsupported integer unary expression")
 }
 
 func evalUnaryDecimalExpression(op lexer.Token, expr *ast.DecimalLiteral) (ast.Expression, error) {
-	if op == lexer.MINUSMINUS {
-		return &ast.DecimalLiteral{expr.Value.Add(expr.Value, big.NewFloat(-1))}, nil
+	if op == lexer.MINUS {
+		return &ast.DecimalLiteral{Value: new(big.Float).Neg(expr.Value)}, nil
+	} else if op == lexer.XOR {
+		return nil, &ast.IntegerOperandError{Operation: "bitwise complement", Value: expr}
+	} else if op == lexer.PLUS {
+		return expr, nil
+	} else if op == lexer.MINUSMINUS {
+		return &ast.DecimalLiteral{Value: new(big.Float).Add(expr.Value, big.NewFloat(-1))}, nil
 	} else if op == lexer.PLUSPLUS {
-		return &ast.DecimalLiteral{expr.Value.Add(expr.Value, big.NewFloat(1))}, nil
+		return &ast.DecimalLiteral{Value: new(big.Float).Add(expr.Value, big.NewFloat(1))}, nil
 	}
 	return nil, errors.New("Unsupported decimal unary expression")
 }
 
-func evalBinaryExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+func evalUnaryRationalExpression(op lexer.Token, expr *ast.RationalLiteral) (ast.Expression, error) {
+	if op == lexer.MINUS {
+		return &ast.RationalLiteral{Value: new(big.Rat).Neg(expr.Value)}, nil
+	} else if op == lexer.XOR {
+		return nil, &ast.IntegerOperandError{Operation: "bitwise complement", Value: expr}
+	} else if op == lexer.PLUS {
+		return expr, nil
+	} else if op == lexer.MINUSMINUS {
+		return &ast.RationalLiteral{Value: new(big.Rat).Sub(expr.Value, big.NewRat(1, 1))}, nil
+	} else if op == lexer.PLUSPLUS {
+		return &ast.RationalLiteral{Value: new(big.Rat).Add(expr.Value, big.NewRat(1, 1))}, nil
+	}
+	return nil, errors.New("Unsupported rational unary expression")
+}
+
+func evalUnaryComplexExpression(op lexer.Token, expr *ast.ComplexLiteral) (ast.Expression, error) {
+	if op == lexer.MINUS {
+		return &ast.ComplexLiteral{Re: new(big.Float).Neg(expr.Re), Im: new(big.Float).Neg(expr.Im)}, nil
+	} else if op == lexer.PLUS {
+		return expr, nil
+	}
+	return nil, errors.New("Unsupported complex unary expression")
+}
+
+func evalBinaryExpression(expr *ast.BinaryExpression, env *Environment) (ast.Expression, error) {
+	// Logical operators short-circuit so the operands are evaluated lazily
+	if expr.Op == lexer.AND || expr.Op == lexer.OR {
+		return evalLogicalExpression(expr, env)
+	}
+
 	// Reduce the binary expression to it's lowest parts
-	exp, err := reduceBinaryExpression(expr)
+	exp, err := reduceBinaryExpression(expr, env)
 	if err != nil {
 		return nil, err
 	}
 
 	switch expr.Op {
 	case lexer.PLUS, lexer.MINUS, lexer.MUL, lexer.DIV, lexer.POW:
-		return evalBinaryMathExpression(exp)
+		result, err := evalBinaryMathExpression(exp, env.Config())
+		if err != nil {
+			return nil, err
+		}
+		return env.Config().number(result), nil
 	case lexer.AMPERSAND, lexer.XOR, lexer.PIPE, lexer.LSHIFT, lexer.RSHIFT:
 		return evalBinaryBitwiseExpression(exp)
 	case lexer.AND, lexer.OR, lexer.EQEQ, lexer.NEQ, lexer.LT, lexer.LTE, lexer.GT, lexer.GTE:
@@ -78,7 +305,7 @@ func evalBinaryExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
 	}
 }
 
-func evalBinaryMathExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+func evalBinaryMathExpression(expr *ast.BinaryExpression, config *Config) (ast.Expression, error) {
 	switch expr.Op {
 	case lexer.PLUS:
 		return evalPlusExpression(expr)
@@ -89,7 +316,7 @@ func evalBinaryMathExpression(expr *ast.BinaryExpression) (ast.Expression, error
 	case lexer.DIV:
 		return evalDivExpression(expr)
 	case lexer.POW:
-		return evalPowExpression(expr)
+		return evalPowExpression(expr, config)
 	default:
 		return nil, errors.New("Unsupported binary expression")
 	}
@@ -98,44 +325,84 @@ func evalBinaryMathExpression(expr *ast.BinaryExpression) (ast.Expression, error
 func evalBinaryBooleanExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
 	switch expr.Op {
 	case lexer.AND:
+		return evalAndExpression(expr)
 	case lexer.OR:
+		return evalOrExpression(expr)
 	case lexer.EQEQ:
+		return evalEqualExpression(expr)
 	case lexer.NEQ:
+		return evalNotEqualExpression(expr)
 	case lexer.LT:
+		return evalLessThanExpression(expr)
 	case lexer.LTE:
+		return evalLessThanEqualToExpression(expr)
 	case lexer.GT:
+		return evalGreaterThanExpression(expr)
 	case lexer.GTE:
+		return evalGreaterThanEqualToExpression(expr)
 	default:
 		return nil, errors.New("Unsupported boolean expression")
 	}
-	return nil, errors.New("Unsupported boolean expression")
 }
 
 func evalBinaryBitwiseExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
 	switch expr.Op {
 	case lexer.AMPERSAND:
+		return evalAmpersandExpression(expr)
 	case lexer.XOR:
+		return evalXorExpression(expr)
 	case lexer.PIPE:
+		return evalPipeExpression(expr)
 	case lexer.LSHIFT:
+		return evalLShiftExpression(expr)
 	case lexer.RSHIFT:
+		return evalRShiftExpression(expr)
 	default:
-		return nil, errors.New("Unsupported boolean expression")
+		return nil, errors.New("Unsupported bitwise expression")
 	}
-	return nil, errors.New("Unsupported boolean expression")
 }
 
-func reduceBinaryExpression(expr *ast.BinaryExpression) (*ast.BinaryExpression, error) {
+func reduceBinaryExpression(expr *ast.BinaryExpression, env *Environment) (*ast.BinaryExpression, error) {
 
 	// Eval left hand side
-	lh, err := evalExpression(expr.LExpr)
+	lh, err := evalExpression(expr.LExpr, env)
 	if err != nil {
 		return nil, err
 	}
 
 	// Eval right hand side
-	rh, err := evalExpression(expr.RExpr)
+	rh, err := evalExpression(expr.RExpr, env)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.BinaryExpression{Op: expr.Op, LExpr: lh, RExpr: rh, OpPos: expr.OpPos}, nil
+}
+
+func evalVariableDeclaration(expr *ast.VariableDeclaration, env *Environment) (ast.Expression, error) {
+	value, err := evalExpression(expr.Value, env)
 	if err != nil {
 		return nil, err
 	}
-	return &ast.BinaryExpression{expr.Op, lh, rh}, nil
+
+	// `let` is bound to the current block, `var` to the enclosing function
+	scope := env
+	if !expr.Scoped {
+		scope = env.functionScope()
+	}
+	if err := scope.Declare(expr.Name, value, false); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func evalConstantDeclaration(expr *ast.ConstantDeclaration, env *Environment) (ast.Expression, error) {
+	value, err := evalExpression(expr.Value, env)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := env.Declare(expr.Name, value, true); err != nil {
+		return nil, err
+	}
+	return value, nil
 }
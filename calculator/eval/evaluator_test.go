@@ -0,0 +1,1393 @@
+package eval
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/check"
+	"github.com/eliquious/aechbar/calculator/parser"
+)
+
+// evalString parses and evaluates each statement against the environment and
+// returns the result of the last one.
+func evalString(t *testing.T, env *Environment, input string) (string, error) {
+	t.Helper()
+	expr, err := parser.ParseExpression(input)
+	if err != nil {
+		t.Fatalf("parse %q: %s", input, err)
+	}
+	return Evaluate(expr, env)
+}
+
+func TestDeclarations(t *testing.T) {
+	env := NewEnvironment()
+	if out, err := evalString(t, env, "let a = 5"); err != nil || out != "5" {
+		t.Fatalf("let a = 5: got %q, %v", out, err)
+	}
+	if out, err := evalString(t, env, "var x = 2 + 3"); err != nil || out != "5" {
+		t.Fatalf("var x = 2 + 3: got %q, %v", out, err)
+	}
+	if _, err := evalString(t, env, "const G = 6.674E-11"); err != nil {
+		t.Fatalf("const G: %v", err)
+	}
+
+	if v, ok := env.Get("a"); !ok || v.String() != "5" {
+		t.Fatalf("expected a = 5, got %v", v)
+	}
+	if !env.IsConstant("G") {
+		t.Fatal("expected G to be constant")
+	}
+
+	_, err := evalString(t, env, "var G = 1")
+	var cerr *ConstantAssignmentError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("expected ConstantAssignmentError, got %v", err)
+	}
+}
+
+func TestEnvironmentScopes(t *testing.T) {
+	root := NewEnvironment()
+	root.Declare("a", nil, true)
+
+	fn := root.NewFunctionScope()
+	block := fn.NewScope()
+	if err := block.Declare("a", nil, false); err != nil {
+		t.Fatalf("shadowing a constant in a nested scope: %v", err)
+	}
+	if block.functionScope() != fn {
+		t.Fatal("expected var declarations to bind to the function scope")
+	}
+
+	if err := fn.Assign("a", nil); err == nil {
+		t.Fatal("expected error assigning to constant")
+	}
+	if _, ok := root.NewScope().Assign("b", nil).(*UndefinedError); !ok {
+		t.Fatal("expected UndefinedError")
+	}
+}
+
+func TestIdentifiersAndGrouping(t *testing.T) {
+	env := NewEnvironment()
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"let a = 5", "5"},
+		{"a", "5"},
+		{"a + 1", "6"},
+		{"(1 + 2) + 3", "6"},
+		{"(a + 1) + (a + 2)", "13"},
+		{"a++", "6"},
+		{"a", "5"},
+		{"a = 7", "7"},
+		{"a", "7"},
+	}
+
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Fatalf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Fatalf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	if _, err := evalString(t, env, "b + 1"); err == nil {
+		t.Fatal("expected undefined error")
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"7 - 10", "-3"},
+		{"6 * 7", "42"},
+		{"2 * 3 + 4 * 5", "26"},
+		{"1 - 2 - 3", "-4"},
+		{"2 ** 3 ** 2", "512"},
+		{"-2 ** 2", "-4"},
+		{"6 / 3", "2"},
+		{"1 / 4", "2.5000000000000000E-01"},
+		{"2 ** -2", "2.5000000000000000E-01"},
+		{"2 ** 100", "1267650600228229401496703205376"},
+		{"1.5 * 2", "3.0000000000000000E+00"},
+		{"3 - 0.5", "2.5000000000000000E+00"},
+		{"1.5 / 0.5", "3.0000000000000000E+00"},
+		{"1.5 ** 2", "2.2500000000000000E+00"},
+		{"4 ** 0.5", "2.0000000000000000E+00"},
+		{"2.0 ** 0.5", "1.4142135623730950E+00"},
+		{`"t=" + "5 s"`, `"t=5 s"`},
+	}
+
+	for _, test := range tests {
+		out, err := evalString(t, NewEnvironment(), test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	for _, input := range []string{"1 / 0", "1.5 / 0", "0 ** -1", "(-8.0) ** 0.5", "2 ** 100000000", "2.0 ** 10000000000", "0.5 ** -10000000000.0", `"a" * 2`, `"t=" + 5`} {
+		if out, err := evalString(t, NewEnvironment(), input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, out)
+		}
+	}
+}
+
+func TestRationalDivision(t *testing.T) {
+	env := NewEnvironment()
+	env.Config().Division = RationalDivision
+
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"1 / 3", "1/3"},
+		{"1 / 3 * 3", "1"},
+		{"2 ** -2", "1/4"},
+		{"(2 / 3) ** 2", "4/9"},
+		{"1 / 3 + 1 / 6", "1/2"},
+		{"8 ** (1 / 3)", "2"},
+		{"(4 / 9) ** (1 / 2)", "2/3"},
+		{"(4 / 9) ** (3 / 2)", "8/27"},
+		{"2 ** (1 / 2)", "1.4142135623730950E+00"},
+		{"0.1 == 1 / 10", "true"},
+		{"1 / 10 == 0.1", "true"},
+		{"1 / 3 == 0.3333333333333333", "false"},
+		{"1 / 3 + 1", "4/3"},
+		{"1 / 4 + 0.5", "7.5000000000000000E-01"},
+		{"0.5 * (2 / 3)", "3.3333333333333333E-01"},
+		{"1 / 3 < 0.34", "true"},
+		{"[1, 2] / 4", "[1/4, 1/2]"},
+	}
+
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+}
+
+func TestNumberFormats(t *testing.T) {
+	env := NewStandardEnvironment()
+	env.Config().Division = RationalDivision
+
+	tests := []struct {
+		input  string
+		format NumberFormat
+		output string
+	}{
+		{"7 / 3", DefaultFormat, "7/3"},
+		{"7 / 3", FractionFormat, "7/3"},
+		{"7 / 3", MixedFormat, "2 1/3"},
+		{"-7 / 3", MixedFormat, "-2 1/3"},
+		{"2 / 3", MixedFormat, "2/3"},
+		{"6 / 3", MixedFormat, "2"},
+		{"7 / 4", DecimalFormat, "1.7500000000000000E+00"},
+		{"0.75", FractionFormat, "3/4"},
+		{"1.25", MixedFormat, "1 1/4"},
+		{"5 m / 2", MixedFormat, "(2 1/2) m"},
+		{"[1 / 2, 3 / 2]", MixedFormat, "[1/2, 1 1/2]"},
+		{`"1/2"`, DecimalFormat, `"1/2"`},
+		{"2 ** (1 / 2)", FractionFormat, "1.4142135623730950E+00"},
+		{"[1.5, 3 ** (1 / 2)]", MixedFormat, "[1 1/2, 1.7320508075688773E+00]"},
+	}
+	for _, test := range tests {
+		env.Config().Format = test.format
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	if format, ok := ParseNumberFormat("Mixed"); !ok || format != MixedFormat {
+		t.Errorf("expected mixed format, got %v", format)
+	}
+}
+
+func TestPrecision(t *testing.T) {
+	tests := []struct {
+		precision uint
+		rounding  string
+		digits    int
+		input     string
+		output    string
+	}{
+		{0, "nearest-even", 16, "0.1 + 0.2", "3.0000000000000000E-01"},
+		{256, "nearest-even", 40, "1 / 3", "3.3333333333333333333333333333333333333333E-01"},
+		{256, "nearest-even", 40, "0.1 * 3", "3.0000000000000000000000000000000000000000E-01"},
+		{256, "nearest-even", 30, "1.000000000000000000000000000001 - 1", "1.000000000000000000000000000000E-30"},
+		{256, "nearest-even", 20, "-(2 / 3)", "-6.66666666666666666667E-01"},
+		{DigitsPrecision(4), "floor", 6, "2 / 3", "6.666260E-01"},
+		{DigitsPrecision(4), "ceiling", 6, "2 / 3", "6.666870E-01"},
+		{DigitsPrecision(4), "ToZero", 6, "-2 / 3", "-6.666260E-01"},
+		{DigitsPrecision(4), "nearest-even", 4, "[1.5, 2 / 3] * 1 m", "[1.5000E+00 m, 6.6669E-01 m]"},
+	}
+	for _, test := range tests {
+		env := NewStandardEnvironment()
+		config := env.Config()
+		rounding, ok := ParseRoundingMode(test.rounding)
+		if !ok {
+			t.Fatalf("unknown rounding mode %s", test.rounding)
+		}
+		config.Precision, config.Rounding, config.Digits = test.precision, rounding, test.digits
+
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	// Fractional powers of exact numbers are computed at the current precision
+	env := NewStandardEnvironment()
+	env.Config().Division = RationalDivision
+	env.Config().Precision, env.Config().Digits = 256, 60
+	for _, test := range [][2]string{
+		{"2 ** (1 / 2)", "1.414213562373095048801688724209698078569671875376948073176680E+00"},
+		{"10 ** (1 / 3)", "2.154434690031883721759293566519350495259344942192108582489236E+00"},
+		{"(1 / 2) ** (1 / 2)", "7.071067811865475244008443621048490392848359376884740365883399E-01"},
+	} {
+		if out, err := evalString(t, env, test[0]); err != nil {
+			t.Errorf("%q: %s", test[0], err)
+		} else if out != test[1] {
+			t.Errorf("%q: expected %s, got %s", test[0], test[1], out)
+		}
+	}
+
+	// Literals are read at the current precision but variables keep the
+	// value computed at the precision they were declared with
+	env = NewStandardEnvironment()
+	evalString(t, env, "var x = 0.1")
+	env.Config().Precision, env.Config().Digits = 256, 30
+	if out, _ := evalString(t, env, "0.1 * 3"); out != "3.000000000000000000000000000000E-01" {
+		t.Errorf("expected 0.1 to be read at 256 bits, got %s", out)
+	}
+	if out, _ := evalString(t, env, "x * 3"); out != "3.000000000000000000040657581468E-01" {
+		t.Errorf("expected x to keep its 64 bit value, got %s", out)
+	}
+
+	// Constants and units involving π are derived at the current precision
+	env = NewStandardEnvironment()
+	env.Config().Precision, env.Config().Digits = 300, 80
+	for _, pair := range [][2]string{
+		{"1 deg / 1 rad", "pi / 180"},
+		{"hbar", "h / (2 * pi)"},
+		{"sigma_SB", "2 * pi**5 * k_B**4 / (15 * h**3 * c**2) to W/m^2/K^4"},
+	} {
+		lh, err := evalString(t, env, pair[0])
+		if err != nil {
+			t.Fatalf("%q: %s", pair[0], err)
+		}
+		rh, err := evalString(t, env, pair[1])
+		if err != nil {
+			t.Fatalf("%q: %s", pair[1], err)
+		}
+		if lh != rh {
+			t.Errorf("%q: expected %s, got %s", pair[0], rh, lh)
+		}
+	}
+
+	if _, ok := ParseRoundingMode("sideways"); ok {
+		t.Errorf("expected unknown rounding mode")
+	}
+}
+
+func TestElementaryFunctions(t *testing.T) {
+	env := NewStandardEnvironment()
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"sqrt(2)", "1.4142135623730950E+00"},
+		{"SQRT(16)", "4.0000000000000000E+00"},
+		{"cbrt(-27)", "-3.0000000000000000E+00"},
+		{"root(16, 4)", "2.0000000000000000E+00"},
+		{"sqrt(4 m^2)", "2.0000000000000000E+00 m"},
+		{"root(8 m^3, 3)", "2.0000000000000000E+00 m"},
+		{"pow(2, 0.5)", "1.4142135623730950E+00"},
+		{"exp(1)", "2.7182818284590452E+00"},
+		{"ln(e)", "1.0000000000000000E+00"},
+		{"LOG(e)", "1.0000000000000000E+00"},
+		{"log(8, 2)", "3.0000000000000000E+00"},
+		{"log10(1000)", "3.0000000000000000E+00"},
+		{"sin(pi / 6)", "5.0000000000000000E-01"},
+		{"cos(60 deg)", "5.0000000000000000E-01"},
+		{"atan(1) * 4", "3.1415926535897932E+00"},
+		{"acos(0)", "1.5707963267948966E+00"},
+		{"atanh(0.5)", "5.4930614433405485E-01"},
+		{"sin([0, pi / 2])", "[0.0000000000000000E+00, 1.0000000000000000E+00]"},
+	}
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	errs := []struct {
+		input string
+		err   string
+	}{
+		{"sqrt(-1)", "sqrt: argument -1 out of domain"},
+		{"ln(0)", "ln: argument 0 out of domain"},
+		{"log(2, 1)", "log: base 1 out of domain"},
+		{"sin(1 m)", "sin expects a dimensionless number, found quantity(m)"},
+		{"sqrt(2 m)", "cannot raise m to the power of 1/2"},
+		{"root(2, 0)", "root: degree must be positive, found 0"},
+	}
+	for _, test := range errs {
+		if _, err := evalString(t, env, test.input); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: expected error %q, got %v", test.input, test.err, err)
+		}
+	}
+
+	// Constants follow the configured precision and may be shadowed
+	env.Config().Precision, env.Config().Digits = 256, 60
+	if out, _ := evalString(t, env, "pi"); out != "3.141592653589793238462643383279502884197169399375105820974945E+00" {
+		t.Errorf("expected pi to 60 digits, got %s", out)
+	}
+	evalString(t, env, "var e = 3")
+	if out, _ := evalString(t, env, "e"); out != "3" {
+		t.Errorf("expected e to be shadowed, got %s", out)
+	}
+}
+
+func TestComplexNumbers(t *testing.T) {
+	env := NewStandardEnvironment()
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"2 + 4.5i", "2.0000000000000000E+00 + 4.5000000000000000E+00i"},
+		{"-3i", "0.0000000000000000E+00 - 3.0000000000000000E+00i"},
+		{"(1 + 2i) * (3 - 4i)", "1.1000000000000000E+01 + 2.0000000000000000E+00i"},
+		{"(1 + 2i) / (3 - 4i)", "-2.0000000000000000E-01 + 4.0000000000000000E-01i"},
+		{"1i ** 2", "-1.0000000000000000E+00 + 0.0000000000000000E+00i"},
+		{"1i ** 1i", "2.0787957635076191E-01 + 0.0000000000000000E+00i"},
+		{"-(1 + 2i)", "-1.0000000000000000E+00 - 2.0000000000000000E+00i"},
+		{"sqrt(-4 + 0i)", "0.0000000000000000E+00 + 2.0000000000000000E+00i"},
+		{"(-4 + 0i) ** 0.5", "0.0000000000000000E+00 + 2.0000000000000000E+00i"},
+		{"sqrt(3 + 4i)", "2.0000000000000000E+00 + 1.0000000000000000E+00i"},
+		{"ln(-1 + 0i)", "0.0000000000000000E+00 + 3.1415926535897932E+00i"},
+		{"exp(ln(2 + 1i))", "2.0000000000000000E+00 + 1.0000000000000000E+00i"},
+		{"abs(3 + 4i)", "5.0000000000000000E+00"},
+		{"abs(-3)", "3"},
+		{"arg(1i)", "1.5707963267948966E+00"},
+		{"arg(-2)", "3.1415926535897932E+00"},
+		{"conj(1 + 2i)", "1.0000000000000000E+00 - 2.0000000000000000E+00i"},
+		{"real(1 + 2i)", "1.0000000000000000E+00"},
+		{"imag(1 + 2i)", "2.0000000000000000E+00"},
+		{"polar(1i)", "[1.0000000000000000E+00, 1.5707963267948966E+00]"},
+		{"rect(2, 0)", "2.0000000000000000E+00 + 0.0000000000000000E+00i"},
+		{"(2 + 0i) == 2", "true"},
+		{"1i != 1", "true"},
+		{"[1i, 2] * 2i", "[-2.0000000000000000E+00 + 0.0000000000000000E+00i, 0.0000000000000000E+00 + 4.0000000000000000E+00i]"},
+
+		// Impedances
+		{"3 ohm + 4i ohm", "3.0000000000000000E+00 + 4.0000000000000000E+00i Ω"},
+		{"abs((3 + 4i) * 1 kohm)", "5.0000000000000000E+00 kΩ"},
+		{"polar(3 ohm + 4i ohm)", "[5.0000000000000000E+00 Ω, 9.2729521800161223E-01]"},
+		{"10 V / (50 ohm + 25i ohm) to A", "1.6000000000000000E-01 - 8.0000000000000000E-02i A"},
+		{"rect(2 A, 0)", "2.0000000000000000E+00 + 0.0000000000000000E+00i A"},
+	}
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	errs := []struct {
+		input string
+		err   string
+	}{
+		{"1i < 2", "operator < of complex and integer unsupported"},
+		{"sin(1i)", "sin does not support complex arguments"},
+		{"0i ** -1", "division by zero"},
+		{"ln(0i)", "ln: argument"},
+		{"1i + 1 m", "cannot add"},
+		{`abs("a")`, "abs expects a number, found string"},
+	}
+	for _, test := range errs {
+		if _, err := evalString(t, env, test.input); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: expected error %q, got %v", test.input, test.err, err)
+		}
+	}
+}
+
+func TestMatrices(t *testing.T) {
+	env := NewStandardEnvironment()
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"[1, 2; 3, 4]", "[1, 2; 3, 4]"},
+		{"[1, 2; 3, 4] * [5, 6; 7, 8]", "[19, 22; 43, 50]"},
+		{"[1, 2, 3; 4, 5, 6] * [1; 2; 3]", "[14; 32]"},
+		{"[1, 2; 3, 4] * [1, 1]", "[3, 7]"},
+		{"[1, 1] * [1, 2; 3, 4]", "[4, 6]"},
+		{"2 * [1, 2; 3, 4] - [1, 1; 1, 1]", "[1, 3; 5, 7]"},
+		{"-[1, 2; 3, 4]", "[-1, -2; -3, -4]"},
+		{"[1, 1; 1, 0] ** 10", "[89, 55; 55, 34]"},
+		{"[1, 2; 3, 4] == [1, 2; 3, 4]", "true"},
+		{"[1, 2; 3, 4][1]", "[3, 4]"},
+		{"len([1, 2; 3, 4; 5, 6])", "3"},
+		{"matrix([[1, 2], [3, 4]])", "[1, 2; 3, 4]"},
+		{"transpose([1, 2, 3; 4, 5, 6])", "[1, 4; 2, 5; 3, 6]"},
+		{"identity(2) * 1i", "[0.0000000000000000E+00 + 1.0000000000000000E+00i, 0.0000000000000000E+00 + 0.0000000000000000E+00i; 0.0000000000000000E+00 + 0.0000000000000000E+00i, 0.0000000000000000E+00 + 1.0000000000000000E+00i]"},
+		{"det([1, 2; 3, 4])", "-2"},
+		{"det([2, 0, 1; 1, 3, 2; 1, 1, 1])", "0"},
+		{"inv([2, 1; 1, 1])", "[1, -1; -1, 2]"},
+		{"solve([2, 1; 1, 3], [3, 5])", "[8.0000000000000000E-01, 1.4000000000000000E+00]"},
+		{"lu([1, 2; 4, 4])", "[[1, 0; 2.5000000000000000E-01, 1], [4, 4; 0, 1], [0, 1; 1, 0]]"},
+		{"qr([3, 0; 4, 5])", "[[6.0000000000000000E-01, -8.0000000000000000E-01; 8.0000000000000000E-01, 6.0000000000000000E-01], [5.0000000000000000E+00, 4.0000000000000000E+00; 0.0000000000000000E+00, 3.0000000000000000E+00]]"},
+		{"eigenvalues([2, 1; 1, 2])", "[1.0000000000000000E+00, 3.0000000000000000E+00]"},
+		{"eigenvalues([0, -1; 1, 0])", "[0.0000000000000000E+00 - 1.0000000000000000E+00i, 0.0000000000000000E+00 + 1.0000000000000000E+00i]"},
+		{"eigenvalues([4, 1, 2; 1, 3, 0; 2, 0, 5])", "[1.8548973087995776E+00, 3.4760236029181340E+00, 6.6690790882822884E+00]"},
+	}
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	env.Config().Division = RationalDivision
+	if out, err := evalString(t, env, "inv([1, 2; 3, 4]) * [1, 2; 3, 4]"); err != nil || out != "[1, 0; 0, 1]" {
+		t.Errorf("exact inverse: got %q, %v", out, err)
+	}
+	if out, err := evalString(t, env, "solve([3, 1; 1, 2], [9, 8])"); err != nil || out != "[2, 3]" {
+		t.Errorf("exact solve: got %q, %v", out, err)
+	}
+
+	errs := []struct {
+		input string
+		err   string
+	}{
+		{"[1, 2, 3; 4, 5, 6] * [1, 2; 3, 4]", "Multiplication of matrices with mismatched shapes 2x3 and 2x2"},
+		{"[1, 2; 3, 4] * [1, 2, 3]", "mismatched shapes 2x2 and 3x1"},
+		{"[1, 2; 3, 4] + [1, 2, 3; 4, 5, 6]", "Addition of matrices with mismatched shapes 2x2 and 2x3"},
+		{"[1, 2, 3; 4, 5, 6] ** 2", "requires a square matrix, found 2x3"},
+		{"det([1, 2])", "det expects a matrix, found []number"},
+		{"inv([1, 2; 2, 4])", "matrix is singular"},
+		{`[1, "a"; 2, 3]`, "matrix elements must be numbers, found string"},
+		{"qr([1i, 2; 3, 4])", "qr expects a real matrix"},
+	}
+	for _, test := range errs {
+		if _, err := evalString(t, env, test.input); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: expected error %q, got %v", test.input, test.err, err)
+		}
+	}
+
+	expr, err := parser.ParseExpression("[1, -2; 100, 3 / 2]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := EvaluateValue(expr, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "[   1   -2 ]\n[ 100  3/2 ]"
+	if out := FormatMatrix(value.(*ast.MatrixLiteral), DefaultFormat, 0); out != expected {
+		t.Errorf("FormatMatrix: expected\n%s\ngot\n%s", expected, out)
+	}
+}
+
+func TestComparisons(t *testing.T) {
+	env := NewEnvironment()
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"1 < 2", "true"},
+		{"2 <= 2", "true"},
+		{"3 > 4", "false"},
+		{"3 >= 4", "false"},
+		{"1 == 1.0", "true"},
+		{"1 != 1.5", "true"},
+		{"0.5 == 1 / 2", "true"},
+		{"1 / 3 < 0.34", "true"},
+		{"2 ** 64 > 1.8E19", "true"},
+		{`"abc" < "abd"`, "true"},
+		{`"abc" == "abc"`, "true"},
+		{"1h > 30m", "true"},
+		{"1s == 1000ms", "true"},
+		{"true == false", "false"},
+		{"true != false", "true"},
+		{"1 < 2 and 2 < 3", "true"},
+		{"1 < 2 and 3 < 2", "false"},
+		{"1 > 2 or 2 < 3", "true"},
+		{"1 + 1 == 2 and 2 * 2 == 4", "true"},
+
+		// The right hand side is not evaluated
+		{"false and undefined", "false"},
+		{"true or undefined", "true"},
+	}
+
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	for _, input := range []string{`1 < "a"`, "true < false", "1 and true", "true and 1", `"a" == 1h`} {
+		if out, err := evalString(t, env, input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, out)
+		}
+	}
+}
+
+func TestBitwise(t *testing.T) {
+	env := NewEnvironment()
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"12 & 10", "8"},
+		{"12 | 10", "14"},
+		{"12 ^ 10", "6"},
+		{"^0", "-1"},
+		{"^5", "-6"},
+		{"1 << 100", "1267650600228229401496703205376"},
+		{"(1 << 100) >> 98", "4"},
+		{"-9 >> 1", "-5"},
+		{"1 >> 10**20", "0"},
+		{"-9 >> 10**20", "-1"},
+		{"1 | 2 & 3", "3"},
+		{"popcount(255)", "8"},
+		{"popcount(1 << 200 | 1)", "2"},
+		{"bitlen(255)", "8"},
+		{"bitlen(-256)", "9"},
+		{"bitlen(0)", "0"},
+	}
+
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	for _, input := range []string{"1.5 & 1", "1 & 1.5", "^1.5", "1 << -1", "1 << 10**20", "popcount(-1)", "popcount(1.5)", "bitlen(1, 2)", "nothing(1)"} {
+		if out, err := evalString(t, env, input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, out)
+		}
+	}
+
+	// Decimal operands are named in the error and only left shifts are bounded
+	for _, test := range []struct{ input, message string }{
+		{"5 & 3.0", "bitwise AND requires integers, found decimal 3.0"},
+		{"3.0 & 5", "bitwise AND requires integers, found decimal 3.0"},
+		{"1 << 2.5", "left shift requires integers, found decimal 2.5"},
+		{"^0.5", "bitwise complement requires integers, found decimal 0.5"},
+		{"1 << 10**20", "shift amount too large"},
+	} {
+		_, err := evalString(t, env, test.input)
+		var eerr *Error
+		if !errors.As(err, &eerr) || eerr.Message != test.message {
+			t.Errorf("%q: expected %q, got %v", test.input, test.message, err)
+		}
+	}
+}
+
+func TestUnits(t *testing.T) {
+	env := NewEnvironment()
+	for _, input := range []string{
+		"unit Meter (m)",
+		"unit Kilogram (kg)",
+		"unit Second (s)",
+		"unit Newton (N) { 1 = 1 kg * m / s^2 }",
+		"unit Kilometer (km) { 1 = 1000 m }",
+		"unit Hour (h) { 1 = 3600 s }",
+		"conversion 1 m = 3.28084 ft",
+		"unit Foot2 (ft2) { 1 = 1 ft }",
+		"unit Yard (yd) { 1 = 3 ft; 1 = 36 in }",
+		"unit Hand (hand) { 1 = 1/3 ft }",
+	} {
+		if _, err := evalString(t, env, input); err != nil {
+			t.Fatalf("%q: %s", input, err)
+		}
+	}
+	if env.Config().Division != DecimalDivision {
+		t.Fatal("expected unit declarations to leave the division mode unchanged")
+	}
+	env.Config().Division = RationalDivision
+
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"5 kg", "5 kg"},
+		{"-5 kg", "-5 kg"},
+		{"2 m + 3 m", "5 m"},
+		{"1 km + 1 m", "(1001/1000) km"},
+		{"2 m * 3 m", "6 m^2"},
+		{"10 m / 2 s", "5 m/s"},
+		{"(4 m^2) ** 0.5", "2.0000000000000000E+00 m"},
+		{"3 * 2 kg", "6 kg"},
+		{"6 / 2 s", "3 1/s"},
+		{"1 km / 1 m", "1000"},
+		{"1 N to kg*m/s^2", "1 kg*m/s^2"},
+		{"2 kg * 3 m / 1 s^2 to N", "6 N"},
+		{"36 km/h to m/s", "10 m/s"},
+		{"1 m to ft", "(82021/25000) ft"},
+		{"1 yd to in", "36 in"},
+		{"3 hand to ft", "1 ft"},
+		{"1 m > 3 ft", "true"},
+		{"1 km == 1000 m", "true"},
+		{"5 kg*2", "10 kg"},
+		{"5 kg/2", "(5/2) kg"},
+
+		// Units declared in a function body are local to the call
+		{"func f() -> { unit Furlong (fur) { 1 = 201168/1000 m }; 2 fur }", "<func f()>"},
+		{"f() to m", "(50292/125) m"},
+	}
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	for _, input := range []string{
+		"1 m + 1 s",
+		"1 m + 1",
+		"1 m < 1 kg",
+		"1 m to s",
+		"1 furlong",
+		"1 fur",
+		"unit Meter (m)",
+		"conversion 1 m = 1 s",
+		"conversion 1 m = 3 ft",
+		"(2 m) ** 0.5",
+	} {
+		if out, err := evalString(t, env, input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, out)
+		}
+	}
+}
+
+func TestStandardEnvironment(t *testing.T) {
+	env := NewStandardEnvironment()
+	env.Config().Division = RationalDivision
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"1 km to m", "1000 m"},
+		{"1 mi to km", "(25146/15625) km"},
+		{"c", "299792458 m/s"},
+		{"speed_of_light * 1 s to km", "(149896229/500) km"},
+		{"1 kW*h to MJ", "(18/5) MJ"},
+		{"1 eV to J", "(801088317/5000000000000000000000000000) J"},
+		{"2 N * 3 m to J", "6 J"},
+		{"c_uncertainty", "0 m/s"},
+		{"speed_of_light_uncertainty", "0 m/s"},
+		{"20 degC to K", "(5863/20) K"},
+		{"20 degC to K == 293.15 K", "true"},
+		{"300 K to degC", "(537/20) degC"},
+		{"20 degC + 5 K", "25 degC"},
+		{"30 degC > 300 K", "true"},
+		{"2 J/degC * 10 K to J", "20 J"},
+
+		// User declarations shadow the library
+		{"unit Foot (ft) { 1 = 0.3 m }", "1 ft"},
+		{"1 ft to m", "(3/10) m"},
+		{"1 / 3 m", "(1/3) 1/m"},
+		{"const c = 3", "3"},
+		{"c * 2", "6"},
+	}
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	if out, err := evalString(t, env, "G * 5.972E24 kg / (6371 km)**2 to m/s^2"); err != nil {
+		t.Errorf("surface gravity: %s", err)
+	} else if !strings.HasPrefix(out, "9.8199") {
+		t.Errorf("surface gravity: expected 9.82 m/s^2, got %s", out)
+	}
+	if out, err := evalString(t, env, "G_uncertainty to m^3/kg/s^2"); err != nil {
+		t.Errorf("uncertainty of G: %s", err)
+	} else if !strings.HasPrefix(out, "1.5000000000000000E-15") {
+		t.Errorf("uncertainty of G: expected 1.5E-15 m^3/kg/s^2, got %s", out)
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	env := NewStandardEnvironment()
+	for _, input := range []string{
+		"func in2m(x float) -> x * 0.0254",
+		"func square(x) -> x * x",
+		"func sumSquares(a, b) -> square(a) + square(b)",
+		"func fNewton (M kg, m kg, r m) N = {\n\tG * m * M / r**2\n}",
+		"func speed(d km, t h) m/s = d / t",
+		"func twice(x int) int = { let y = x * 2; y }",
+		"func bad(x m) s -> x",
+		"func forever(n) -> forever(n + 1)",
+		"var k = 10",
+		"func addK(x) -> x + k",
+	} {
+		if _, err := evalString(t, env, input); err != nil {
+			t.Fatalf("%q: %s", input, err)
+		}
+	}
+
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"in2m(100)", "2.5400000000000000E+00"},
+		{"square(12)", "144"},
+		{"sumSquares(3, 4)", "25"},
+		{"twice(21)", "42"},
+		{"speed(36 km, 1 h)", "10 m/s"},
+		{"speed(36000 m, 3600 s)", "10 m/s"},
+		{"addK(1)", "11"},
+		{"k = 20", "20"},
+		{"addK(1)", "21"},
+		{"in2m", "<func in2m(x float)>"},
+	}
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	if out, err := evalString(t, env, "fNewton(5.972E24 kg, 1 kg, 6371 km)"); err != nil {
+		t.Errorf("fNewton: %s", err)
+	} else if !strings.HasPrefix(out, "9.8199") || !strings.HasSuffix(out, " N") {
+		t.Errorf("fNewton: expected 9.82 N, got %s", out)
+	}
+
+	for _, input := range []string{
+		"in2m(1 m)",
+		`in2m("a")`,
+		"twice(1.5)",
+		"square(1, 2)",
+		"fNewton(1 kg, 1 kg, 1 s)",
+		"fNewton(1, 1 kg, 1 m)",
+		"bad(1 m)",
+		"k(1)",
+		"y",
+	} {
+		if out, err := evalString(t, env, input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, out)
+		}
+	}
+
+	_, err := evalString(t, env, "forever(0)")
+	var eerr *Error
+	if !errors.Is(err, ErrCallDepth) || !errors.As(err, &eerr) || eerr.Code != CallDepth || !eerr.Span.IsValid() {
+		t.Errorf("expected ErrCallDepth at a call, got %v", err)
+	}
+	if _, err := evalString(t, env, "square(3)"); err != nil {
+		t.Errorf("call after exceeding depth: %s", err)
+	}
+}
+
+func TestConditionals(t *testing.T) {
+	env := NewStandardEnvironment()
+	for _, input := range []string{
+		"func sign(x) -> if x > 0 { 1 } else x < 0 { -1 } else { 0 }",
+		"func fact(n int) int = {\n\tif n <= 1 {\n\t\t1\n\t} else {\n\t\tn * fact(n - 1)\n\t}\n}",
+		"func fib(n) -> if n < 2 { n } else { fib(n - 1) + fib(n - 2) }",
+		"func fNewtonMod (fN N, Vs m/s, Vo m/s) N = {\n\tif Vs/Vo > 0 {\n\t\t- fN * (Vs/Vo - 1)\n\t} else Vs/Vo < 0 {\n\t\tfN * (1 - Vs/Vo)\n\t} else {\n\t\t0 N\n\t}\n}",
+	} {
+		if _, err := evalString(t, env, input); err != nil {
+			t.Fatalf("%q: %s", input, err)
+		}
+	}
+
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"if true { 1 } else { 2 }", "1"},
+		{"if false { 1 } else { 2 }", "2"},
+		{"if false { 1 }", "nil"},
+		{"if 1 > 2 { 1 } else if 2 > 1 { 2 } else { 3 }", "2"},
+		{"sign(5)", "1"},
+		{"sign(-5)", "-1"},
+		{"sign(0)", "0"},
+		{"fact(20)", "2432902008176640000"},
+		{"fib(15)", "610"},
+		{"fNewtonMod(10 N, 2 m/s, 1 m/s)", "-10 N"},
+		{"fNewtonMod(10 N, -1 m/s, 1 m/s)", "20 N"},
+		{"fNewtonMod(10 N, 0 m/s, 1 m/s)", "0 N"},
+		{"var x = 1", "1"},
+		{"if true { let x = 2; x }", "2"},
+		{"x", "1"},
+	}
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	for _, input := range []string{"if 1 { 2 }", `if "a" { 1 } else { 2 }`, "if false { 1 } else 5 { 2 }", "fact(1.5)"} {
+		if out, err := evalString(t, env, input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, out)
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	env := NewEnvironment()
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"range(5)", "[0, 1, 2, 3, 4]"},
+		{"range(2, 5)", "[2, 3, 4]"},
+		{"range(10, 0, -3)", "[10, 7, 4, 1]"},
+		{"range(5, 0)", "[]"},
+		{"range(100000000000000000000, 100000000000000000002)", "[100000000000000000000, 100000000000000000001]"},
+		{"range(0, 0.3, 0.1)", "[0.0000000000000000E+00, 1.0000000000000000E-01, 2.0000000000000000E-01]"},
+		{"var squares = for i in range(5) {\n\ti**2\n}", "[0, 1, 4, 9, 16]"},
+		{"for x in squares { x + 1 }", "[1, 2, 5, 10, 17]"},
+		{"for x in squares { if x > 2 { x } }", "[4, 9, 16]"},
+		{"for i in range(1, 3) { for j in range(1, 3) { i * j } }", "[[1, 2], [2, 4]]"},
+		{`for c in "abc" { c }`, `["a", "b", "c"]`},
+		{"for i in range(3) { let y = i * 2; y }", "[0, 2, 4]"},
+	}
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	for _, input := range []string{"for x in 5 { x }", "range(1, 5, 0)", `range("a")`, "range()", "for i in range(3) { y }"} {
+		if out, err := evalString(t, env, input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, out)
+		}
+	}
+
+	env.Config().MaxSteps = 1000
+	evalString(t, env, "func spin(n) -> spin(n) + spin(n)")
+	for _, input := range []string{
+		"range(10000)",
+		"for i in range(500) { for j in range(500) { j } }",
+		"spin(1)",
+	} {
+		_, err := evalString(t, env, input)
+		var eerr *Error
+		if !errors.Is(err, ErrStepLimit) || !errors.As(err, &eerr) || eerr.Code != StepLimit || !eerr.Span.IsValid() {
+			t.Errorf("%q: expected step limit, got %v", input, err)
+		}
+	}
+	if out, err := evalString(t, env, "for i in range(10) { i }"); err != nil {
+		t.Errorf("step limit should reset between evaluations: %s (%s)", err, out)
+	}
+}
+
+func TestArrays(t *testing.T) {
+	env := NewStandardEnvironment()
+	evalString(t, env, "var a = [1, 2, 3, 4]")
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"[1, 2 + 3, [4]]", "[1, 5, [4]]"},
+		{"a[0]", "1"},
+		{"a[-1]", "4"},
+		{"a[1:3]", "[2, 3]"},
+		{"a[:-1]", "[1, 2, 3]"},
+		{"a[2:]", "[3, 4]"},
+		{"a[3:1]", "[]"},
+		{"a[-10:10]", "[1, 2, 3, 4]"},
+		{`"hello"[1]`, `"e"`},
+		{`"hello"[1:-1]`, `"ell"`},
+		{"len(a)", "4"},
+		{`len("abc")`, "3"},
+		{"append(a, 5, 6)", "[1, 2, 3, 4, 5, 6]"},
+		{"a", "[1, 2, 3, 4]"},
+		{"[1, 2, 3] * 2", "[2, 4, 6]"},
+		{"2 * [1, 2, 3]", "[2, 4, 6]"},
+		{"[1, 2] + [3, 4]", "[4, 6]"},
+		{"10 - [1, 2]", "[9, 8]"},
+		{"[1, 2] - 1", "[0, 1]"},
+		{"[2, 4] / 2", "[1, 2]"},
+		{"[1, 2] / 4", "[2.5000000000000000E-01, 5.0000000000000000E-01]"},
+		{"2 ** [1, 2, 3]", "[2, 4, 8]"},
+		{"[1, 2] ** 2", "[1, 4]"},
+		{"-[1, 2]", "[-1, -2]"},
+		{"[[1, 2], [3, 4]] * 2", "[[2, 4], [6, 8]]"},
+		{"[1, 2] * 1 m", "[1 m, 2 m]"},
+		{"[1 m, 2 m] + 1 m", "[2 m, 3 m]"},
+		{"(for i in range(3) { i })[-1]", "2"},
+		{"[1, 2] == [1, 2]", "true"},
+		{"a == [1, 2, 3, 4]", "true"},
+		{"[1, 2] == [1, 2, 3]", "false"},
+		{"[1, 2] != [2, 1]", "true"},
+		{`[[1, "a"], [1 m]] == [[1.0, "a"], [100 cm]]`, "true"},
+		{"[] == []", "true"},
+		{"match [1, 2] { [2, 1] -> 0; [1, 2] -> 1 }", "1"},
+	}
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	_, err := evalString(t, env, "[1, 2] + [1, 2, 3]")
+	var lerr *ast.LengthError
+	if !errors.As(err, &lerr) {
+		t.Errorf("expected LengthError, got %v", err)
+	}
+	for _, input := range []string{"a[4]", "a[-5]", "a[1.5]", "5[0]", "len(5)", "[1, 2] + [1 m, 2 m]", `[1] + "a"`, "[1] == 1", `[1] == ["a"]`} {
+		if out, err := evalString(t, env, input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, out)
+		}
+	}
+}
+
+func TestLambdas(t *testing.T) {
+	env := NewStandardEnvironment()
+	evalString(t, env, "var array = [3, 8, 1, 6, 10]")
+	evalString(t, env, "var limit = 5")
+	evalString(t, env, "func above(n) = (x) -> x > n")
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"((x) -> x * 2)(4)", "8"},
+		{"array.filter((a, i) -> { a > 5 })", "[8, 6, 10]"},
+		{"array.filter((a) -> a > limit)", "[8, 6, 10]"},
+		{"array.filter((a, i) -> i < 2)", "[3, 8]"},
+		{"filter(array, above(7))", "[8, 10]"},
+		{"array.map((a) -> a * a)", "[9, 64, 1, 36, 100]"},
+		{"array.map((a, i) -> i)", "[0, 1, 2, 3, 4]"},
+		{"array.reduce((acc, a) -> acc + a)", "28"},
+		{"array.reduce((acc, a) -> acc + a, 100)", "128"},
+		{"array.sort_by((a) -> -a)", "[10, 8, 6, 3, 1]"},
+		{`["bb", "a", "cc"].sort_by((s) -> len(s))`, `["a", "bb", "cc"]`},
+		{"array.any((a) -> a > 9)", "true"},
+		{"array.all((a) -> a > 1)", "false"},
+		{"[].all((a) -> false)", "true"},
+		{"zip([1, 2, 3], [4, 5])", "[[1, 4], [2, 5]]"},
+		{"array.filter((a) -> a > 5).map((a) -> a / 2).len()", "3"},
+		{"array.len()", "5"},
+	}
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	for _, input := range []string{"array.filter((a) -> a)", "array.map(5)", `"abc".filter((a) -> true)`, "[].reduce((a, b) -> a)", "[1, true].sort_by((a) -> a)", "limit(1)"} {
+		if out, err := evalString(t, env, input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, out)
+		}
+	}
+}
+
+func TestStructs(t *testing.T) {
+	env := NewStandardEnvironment()
+	for _, input := range []string{
+		"struct Planet = { Name string; Mass (kg); Radius (m) }",
+		"struct SolarSystem = { Name string; Planets []Planet; Star Planet }",
+		"struct Point = { X float; N int }",
+		`const Earth = Planet{Name: "Earth", Mass: 5.972E24, Radius: 6371 km}`,
+		`var sol = SolarSystem{Name: "Sol", Planets: [Earth, Planet{Name: "Mars"}]}`,
+	} {
+		if _, err := evalString(t, env, input); err != nil {
+			t.Fatalf("%q: %s", input, err)
+		}
+	}
+
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"Earth.Name", `"Earth"`},
+		{"Earth.Radius", "6371000 m"},
+		{`Planet{Name: "Moon"}`, `Planet{Name: "Moon", Mass: 0 kg, Radius: 0 m}`},
+		{"sol.Planets[1].Name", `"Mars"`},
+		{"len(sol.Planets)", "2"},
+		{"sol.Star", "nil"},
+		{"sol.Planets.map((p) -> p.Name)", `["Earth", "Mars"]`},
+		{"for f in (Planet{}) { f[0] }", `["Name", "Mass", "Radius"]`},
+		{"Earth.Radius to km", "6371 km"},
+		{"Point{}", "Point{X: 0.0000000000000000E+00, N: 0}"},
+		{"for p in [Point{X: 1.5}] { p.X }", "[1.5000000000000000E+00]"},
+		{"Point{X: 1}.X", "1.0000000000000000E+00"},
+		{"struct Flag = { On bool }", "struct Flag = { On bool }"},
+	}
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	for _, input := range []string{
+		`Planet{Name: "X", Moons: 2}`,
+		`Planet{Name: "X", Name: "Y"}`,
+		"Planet{Name: 5}",
+		"Planet{Mass: 5 m}",
+		"SolarSystem{Planets: [1, 2]}",
+		"SolarSystem{Star: Earth.Name}",
+		"Earth.Moons",
+		"Earth.Name.Length",
+		"Unknown{}",
+		"Earth{}",
+		"struct Twice = { A int; A int }",
+		"for f in Planet { f }",
+	} {
+		if out, err := evalString(t, env, input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, out)
+		}
+	}
+}
+
+func TestEnums(t *testing.T) {
+	env := NewStandardEnvironment()
+	for _, input := range []string{
+		"enum Level = { LOW MEDIUM HIGH }",
+		"enum Color = { RED GREEN }",
+		"struct Alarm = { Name string; Level Level }",
+		`var alarm = Alarm{Name: "pressure", Level: Level.HIGH}`,
+	} {
+		if _, err := evalString(t, env, input); err != nil {
+			t.Fatalf("%q: %s", input, err)
+		}
+	}
+
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"Level.MEDIUM", "Level.MEDIUM"},
+		{"Level.MEDIUM.Ordinal", "1"},
+		{"Level.MEDIUM.Name", `"MEDIUM"`},
+		{"Level.LOW < Level.HIGH", "true"},
+		{"Level.HIGH >= Level.MEDIUM", "true"},
+		{"Level.LOW == Level.LOW", "true"},
+		{"Level.LOW != Level.HIGH", "true"},
+		{"for l in Level { l.Ordinal }", "[0, 1, 2]"},
+		{"for l in Level { if l > Level.LOW { l } }", "[Level.MEDIUM, Level.HIGH]"},
+		{"alarm.Level", "Level.HIGH"},
+		{"alarm.Level == Level.HIGH", "true"},
+		{`Alarm{Name: "idle"}`, `Alarm{Name: "idle", Level: Level.LOW}`},
+		{"match alarm.Level { Level.LOW -> 0; Level.MEDIUM, Level.HIGH -> 1 }", "1"},
+		{"for l in Level { match l { Level.HIGH -> l.Name; else -> \"-\" } }", `["-", "-", "HIGH"]`},
+		{"match 2 + 1 { 1, 2 -> \"small\"; 3 -> { let x = 3; x * 2 } }", "6"},
+	}
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	for _, input := range []string{
+		"Level.EXTREME",
+		"Level.LOW.Value",
+		"Level.LOW < Color.RED",
+		"Level.LOW == 0",
+		"Alarm{Level: Color.RED}",
+		`Alarm{Level: "HIGH"}`,
+		"enum Twice = { A A }",
+		"match Level.HIGH { Level.LOW -> 0 }",
+		"match Level.HIGH { 0 -> 0 }",
+	} {
+		if out, err := evalString(t, env, input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, out)
+		}
+	}
+}
+
+func TestImports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "modules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"geometry.calc":   "const tau = 2 * 3\nfunc area(r m) -> tau * r * r / 2\n",
+		"lib/shapes.calc": "import \"square.calc\"\nvar one = square.side(1)\n",
+		"lib/square.calc": "func side(x) -> x * x\n",
+		"a.calc":          "import b\nvar x = 1\n",
+		"b.calc":          "import a\nvar y = 2\n",
+		"counter.calc":    "var count = 0\ncount = count + 1\n",
+		"broken.calc":     "var z = undefined_name\n",
+	}
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	env := NewStandardEnvironment()
+	env.Loader().SearchPath = []string{dir}
+	env.Loader().Register("physics", "const g0 = 9.80665 m/s^2\nfunc weight(m kg) -> m * g0\nunit Furlong (fur) { 1 = 201.168 m }\nconst furlong = 1 fur\n")
+
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"import physics", "<module physics>"},
+		{"physics.weight(2 kg) to N", "1.9613300000000000E+01 N"},
+		{"physics.furlong to m", "2.0116800000000000E+02 m"},
+		{"import geometry", "<module geometry>"},
+		{"geometry.tau", "6"},
+		{"geometry.area(2 m)", "12 m^2"},
+		{`import "` + filepath.Join(dir, "lib", "shapes.calc") + `"`, "<module shapes>"},
+		{"shapes.one", "1"},
+		{"import counter", "<module counter>"},
+		{"import counter", "<module counter>"},
+		{"counter.count", "1"},
+		{"[1, 2].len()", "2"},
+	}
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	if _, err := evalString(t, env, "import a"); err == nil || !strings.Contains(err.Error(), "import cycle") {
+		t.Errorf("expected import cycle, got %v", err)
+	}
+	for _, input := range []string{"import missing", `import "nowhere.calc"`, "import broken", "physics.unknown", "physics.g0(1)", "geometry.tau.x", "1 fur"} {
+		if out, err := evalString(t, env, input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, out)
+		}
+	}
+
+	// Modules do not see the declarations of the importing worksheet
+	env.Loader().Register("peek", "var seen = secret\n")
+	evalString(t, env, "var secret = 1")
+	if _, err := evalString(t, env, "import peek"); err == nil {
+		t.Errorf("expected module to be isolated from the worksheet")
+	}
+
+	// Worksheets without the standard library are isolated in the same way
+	env = NewEnvironment()
+	env.Loader().Register("peek", "var seen = secret\n")
+	env.Loader().Register("builtins", "var n = len([1, 2])\n")
+	env.Loader().Register("span", "unit Span (span) { 1 = 9 in }\n")
+	for _, input := range []string{"var secret = 1", "unit Inch (in)"} {
+		if _, err := evalString(t, env, input); err != nil {
+			t.Fatalf("%q: %s", input, err)
+		}
+	}
+	_, err = evalString(t, env, "import peek")
+	var eerr *Error
+	if !errors.As(err, &eerr) || eerr.Code != UndefinedName {
+		t.Errorf("expected module to be isolated from the worksheet, got %v", err)
+	}
+	evalString(t, env, "import builtins")
+	if out, err := evalString(t, env, "builtins.n"); err != nil || out != "2" {
+		t.Errorf("expected builtins in modules, got %s (%v)", out, err)
+	}
+	if _, err := evalString(t, env, "import span"); err == nil {
+		t.Errorf("expected units declared by the worksheet to be hidden from modules")
+	}
+}
+
+func TestGenerics(t *testing.T) {
+	env := NewStandardEnvironment()
+	for _, input := range []string{
+		"func same<A>(a A, b A) A = a",
+		"func first<A>(xs []A) A -> xs[0]",
+		"func convert<A, B, C>(a A, b B) C -> b",
+		"type Filter = func [A] (a A, i int) -> bool",
+		"type Length = (m)",
+		"func keep<A>(xs []A, f Filter) []A -> filter(xs, f)",
+		"func double(x Length) -> x * 2",
+		"struct Rod = { Size Length }",
+	} {
+		if _, err := evalString(t, env, input); err != nil {
+			t.Fatalf("%q: %s", input, err)
+		}
+	}
+
+	tests := []struct {
+		input  string
+		output string
+	}{
+		{"same(1, 2)", "1"},
+		{"same(1, 2.5)", "1"},
+		{`same("a", "b")`, `"a"`},
+		{"same(1 m, 2 ft)", "1 m"},
+		{"same([1], [])", "[1]"},
+		{"first([3, 4])", "3"},
+		{`first(["a"])`, `"a"`},
+		{"convert(1, true)", "true"},
+		{"keep([1, 5, 10], (a, i) -> a > 4)", "[5, 10]"},
+		{"double(100 cm)", "2 m"},
+		{"Rod{Size: 50 cm}.Size", "5.0000000000000000E-01 m"},
+		{"Rod{}", "Rod{Size: 0 m}"},
+	}
+	for _, test := range tests {
+		out, err := evalString(t, env, test.input)
+		if err != nil {
+			t.Errorf("%q: %s", test.input, err)
+		} else if out != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, out)
+		}
+	}
+
+	for _, input := range []string{
+		`same(1, "a")`,
+		"same(1 m, 1 s)",
+		`first([1, "a"])`,
+		"first(1)",
+		"keep([1], (a) -> true)",
+		"keep([1], 5)",
+		"double(1 s)",
+		"type Loop = Loop",
+	} {
+		if out, err := evalString(t, env, input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, out)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	env := NewStandardEnvironment()
+	env.Loader().Register("broken", "var a = 1\nvar b = 1 + \"x\"\n")
+	for _, input := range []string{
+		"const G = 1",
+		"func half(x float) -> x / 2",
+		"func len2(s string) -> len(s)",
+		"func name(x float) string -> x",
+		"func f(x float) -> sqrt(x, x)",
+	} {
+		evalString(t, env, input)
+	}
+
+	tests := []struct {
+		input   string
+		code    ErrorCode
+		types   string
+		message string
+	}{
+		{`1 + "a"`, UnsupportedOperation, "integer string", "Integer addition of integer and string unsupported at line 1, char 1"},
+		{`2 * (1 + "a")`, UnsupportedOperation, "integer string", "at line 1, char 6"},
+		{`"a" - 1`, UnsupportedOperation, "string integer", "operator - of string and integer unsupported"},
+		{`"a" * 1.5`, UnsupportedOperation, "string decimal", "operator * of string and decimal unsupported"},
+		{`"t=" + 5 s`, UnsupportedOperation, "string quantity(s)", "String concatenation of string and quantity(s) unsupported"},
+		{"1 m + 1 s", IncompatibleDimensions, "quantity(m) quantity(s)", "cannot add m and s: incompatible dimensions at line 1, char 1"},
+		{"1 ft + 1 s", IncompatibleDimensions, "quantity(m) quantity(s)", "cannot add ft and s: incompatible dimensions m and s"},
+		{"[1, 2] + [1]", LengthMismatch, "", "Addition of arrays with mismatched lengths 2 and 1"},
+		{"[1, 2; 3, 4] * [1, 2, 3]", ShapeMismatch, "", "Multiplication of matrices with mismatched shapes 2x2 and 3x1"},
+		{"inv([1, 1; 1, 1])", SingularMatrix, "", "matrix is singular"},
+		{"1 / 0", DivisionByZero, "", "division by zero at line 1, char 1"},
+		{"var x = undefined_name", UndefinedName, "", "undefined: undefined_name at line 1, char 9"},
+		{"1 furlong", UnknownUnit, "", "unknown unit: furlong"},
+		{"G = 2", ConstantAssignment, "", "cannot assign to constant 'G'"},
+		{"if 1 m + 1 s > 0 { 1 }", IncompatibleDimensions, "quantity(m) quantity(s)", "at line 1, char 4"},
+		{"import broken", UnsupportedOperation, "integer string", "import broken:2:9: Integer addition of integer and string unsupported at line 1, char 1"},
+		{"1.5 & 1", UnsupportedOperation, "decimal", "bitwise AND requires integers, found decimal 1.5"},
+		{"sqrt(1, 2)", ArgumentCount, "", "sqrt expects 1 argument(s), found 2"},
+		{"half()", ArgumentCount, "", "half expects 1 argument(s), found 0"},
+		{"len2(2)", TypeMismatch, "string integer", "len2: parameter s: expected string, found 2"},
+		{"name(1.5)", TypeMismatch, "string decimal", "name: return value: expected string"},
+		{"[1, 2][5]", IndexOutOfRange, "", "index 5 out of range for length 2"},
+		{"if 1 { 2 }", NonBooleanCondition, "integer", "non-boolean condition 1 at line 1, char 1"},
+		{"if G { 2 }", NonBooleanCondition, "integer", "non-boolean condition G: found 1"},
+		{"match 3 { 1 -> 0 }", NonExhaustiveMatch, "integer", "match is not exhaustive: no arm for 3"},
+		{"ln(-1)", OutOfDomain, "", "ln: argument -1 out of domain"},
+		{"f(1)", ArgumentCount, "", "sqrt expects 1 argument(s), found 2"},
+		{"undefined_name()", UndefinedName, "", "undefined: undefined_name"},
+	}
+	for _, test := range tests {
+		_, err := evalString(t, env, test.input)
+		var e *Error
+		if !errors.As(err, &e) {
+			t.Errorf("%q: expected Error, got %v", test.input, err)
+			continue
+		}
+		if e.Code != test.code {
+			t.Errorf("%q: expected code %s, got %s", test.input, test.code, e.Code)
+		}
+		if types := strings.Join(e.Types, " "); types != test.types {
+			t.Errorf("%q: expected types %q, got %q", test.input, test.types, types)
+		}
+		if !strings.Contains(e.Error(), test.message) {
+			t.Errorf("%q: expected %q, got %q", test.input, test.message, e)
+		}
+	}
+
+	_, err := evalString(t, env, `[1] + "a"`)
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("expected Error, got %v", err)
+	} else if e.Span.Start.Char != 0 || e.Span.End.Char != 9 {
+		t.Errorf("expected span of the binary expression, got %v", e.Span)
+	}
+
+	_, err = evalString(t, env, "undefined_name")
+	if !errors.As(err, &e) || !strings.Contains(e.Hint, "declare undefined_name") {
+		t.Errorf("expected a hint, got %v", err)
+	}
+}
+
+func TestCheckerBuiltins(t *testing.T) {
+	// The checker reports names which are not builtins as undefined
+	checker := check.New()
+	for name := range builtins {
+		if errs := checker.Check(&ast.Identifier{Name: name}); len(errs) != 0 {
+			t.Errorf("%s: unexpected errors %v", name, errs)
+		}
+	}
+}
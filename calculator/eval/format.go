@@ -0,0 +1,168 @@
+package eval
+
+import (
+	"math/big"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+)
+
+// NumberFormat selects how numeric results are displayed.
+type NumberFormat int
+
+const (
+	// DefaultFormat displays numbers by their type. Integers and fractions
+	// are exact and decimals use scientific notation.
+	DefaultFormat NumberFormat = iota
+
+	// FractionFormat displays numbers as reduced fractions such as 7/3.
+	FractionFormat
+
+	// MixedFormat displays numbers as mixed numbers such as 2 1/3.
+	MixedFormat
+
+	// DecimalFormat displays every number as a decimal.
+	DecimalFormat
+)
+
+var numberFormats = map[string]NumberFormat{
+	"default":  DefaultFormat,
+	"fraction": FractionFormat,
+	"mixed":    MixedFormat,
+	"decimal":  DecimalFormat,
+}
+
+// ParseNumberFormat returns the format with the given name.
+func ParseNumberFormat(name string) (NumberFormat, bool) {
+	format, ok := numberFormats[strings.ToLower(name)]
+	return format, ok
+}
+
+// FormatValue returns the string representation of a value with its numbers,
+// including the values of quantities and the elements of arrays, matrices and
+// structs, displayed in the format. Fractional values of quantities are
+// parenthesized. Decimals are displayed with the number of
+// digits after the decimal point and are read by their shortest
+// representation when displayed as fractions so that 0.75 is displayed as 3/4.
+// Decimals which are not short, such as the result of sqrt(2), are inexact
+// and are displayed as decimals in every format.
+func FormatValue(value ast.Expression, format NumberFormat, digits int) string {
+	if digits <= 0 {
+		digits = DefaultDigits
+	}
+
+	switch v := value.(type) {
+	case *ast.QuantityLiteral:
+		// Fractions are parenthesized so that `(1/3) 1/m` is not read as 1/(3 m)
+		magnitude := FormatValue(v.Value, format, digits)
+		if strings.Contains(magnitude, "/") {
+			magnitude = "(" + magnitude + ")"
+		}
+		return magnitude + " " + v.Unit.String()
+	case *ast.ArrayLiteral:
+		elements := make([]string, len(v.Elements))
+		for i, el := range v.Elements {
+			elements[i] = FormatValue(el, format, digits)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *ast.MatrixLiteral:
+		rows := make([]string, len(v.Rows))
+		for i, row := range formatElements(v, format, digits) {
+			rows[i] = strings.Join(row, ", ")
+		}
+		return "[" + strings.Join(rows, "; ") + "]"
+	case *ast.StructLiteral:
+		fields := make([]string, len(v.Values))
+		for i, el := range v.Values {
+			fields[i] = v.Decl.Fields[i].Name + ": " + FormatValue(el, format, digits)
+		}
+		return v.Decl.Name + "{" + strings.Join(fields, ", ") + "}"
+	case *ast.ComplexLiteral:
+		return v.Text(digits)
+	case *ast.DecimalLiteral:
+		if format == DefaultFormat || format == DecimalFormat || v.Value.IsInf() || !isShort(v, digits) {
+			return v.Value.Text('E', digits)
+		}
+	case *ast.IntegerLiteral, *ast.RationalLiteral:
+		if format == DefaultFormat {
+			return value.String()
+		}
+	default:
+		return value.String()
+	}
+
+	r, _ := shortestRat(value)
+	switch format {
+	case FractionFormat:
+		return r.RatString()
+	case MixedFormat:
+		return mixedNumber(r)
+	}
+	prec := DigitsPrecision(digits) + 8
+	return new(big.Float).SetPrec(prec).SetRat(r).Text('E', digits)
+}
+
+// FormatMatrix returns the matrix on multiple lines, one row per line, with
+// the elements of each column aligned on their right.
+func FormatMatrix(m *ast.MatrixLiteral, format NumberFormat, digits int) string {
+	if digits <= 0 {
+		digits = DefaultDigits
+	}
+
+	elements := formatElements(m, format, digits)
+	_, cols := m.Shape()
+	widths := make([]int, cols)
+	for _, row := range elements {
+		for j, el := range row {
+			if n := utf8.RuneCountInString(el); n > widths[j] {
+				widths[j] = n
+			}
+		}
+	}
+
+	lines := make([]string, len(elements))
+	for i, row := range elements {
+		for j, el := range row {
+			row[j] = strings.Repeat(" ", widths[j]-utf8.RuneCountInString(el)) + el
+		}
+		lines[i] = "[ " + strings.Join(row, "  ") + " ]"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatElements returns the formatted elements of each row of a matrix.
+func formatElements(m *ast.MatrixLiteral, format NumberFormat, digits int) [][]string {
+	rows := make([][]string, len(m.Rows))
+	for i, row := range m.Rows {
+		rows[i] = make([]string, len(row))
+		for j, el := range row {
+			rows[i][j] = FormatValue(el, format, digits)
+		}
+	}
+	return rows
+}
+
+// isShort returns true if the shortest representation of a decimal has at
+// most the number of digits displayed.
+func isShort(d *ast.DecimalLiteral, digits int) bool {
+	n := 0
+	for _, c := range d.Value.Text('e', -1) {
+		if c == 'e' {
+			break
+		} else if c >= '0' && c <= '9' {
+			n++
+		}
+	}
+	return n <= digits
+}
+
+// mixedNumber returns the fraction as a whole number followed by a proper
+// fraction.
+func mixedNumber(r *big.Rat) string {
+	whole, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if whole.Sign() == 0 || rem.Sign() == 0 {
+		return r.RatString()
+	}
+	return whole.String() + " " + new(big.Rat).SetFrac(rem.Abs(rem), r.Denom()).RatString()
+}
@@ -0,0 +1,167 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/units"
+)
+
+// maxCallDepth limits the depth of nested function calls so that runaway
+// recursion is reported as an error.
+const maxCallDepth = 2048
+
+// Function is a user-defined function along with the environment in which it
+// was declared.
+type Function struct {
+	ast.Span
+
+	Decl *ast.FunctionDeclaration
+	Env  *Environment
+}
+
+func (f Function) Type() ast.ExpressionType { return ast.FunctionType }
+func (f Function) String() string           { return fmt.Sprintf("<func %s>", f.Decl.Signature()) }
+
+// Call checks the arguments against the parameter annotations and evaluates
+// the body in a new function scope. Quantities are converted to the declared
+// units of the parameters and the return value. Type parameters are bound to
+// the types of the arguments.
+func (f *Function) Call(args []ast.Expression) (ast.Expression, error) {
+	decl := f.Decl
+	if len(args) != len(decl.Params) {
+		return nil, argumentCount(decl.Name, len(decl.Params), len(args))
+	}
+
+	if err := f.Env.step(); err != nil {
+		return nil, err
+	}
+
+	root := f.Env.root()
+	if root.depth >= maxCallDepth {
+		return nil, ErrCallDepth
+	}
+	root.depth++
+	defer func() { root.depth-- }()
+
+	scope := f.Env.NewFunctionScope()
+	bindings := make(map[string]string)
+	for i, param := range decl.Params {
+		arg, err := f.checkGeneric(param.Annotation, args[i], bindings)
+		if err != nil {
+			return nil, fmt.Errorf("%s: parameter %s: %w", decl.Name, param.Name, err)
+		}
+		scope.Declare(param.Name, arg, false)
+	}
+
+	result, err := evalExpression(decl.Body, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err = f.checkGeneric(decl.Return, result, bindings)
+	if err != nil {
+		return nil, fmt.Errorf("%s: return value: %w", decl.Name, err)
+	}
+	return result, nil
+}
+
+// checkAnnotation verifies that the value matches the annotation. Values are
+// converted to the unit of unit annotations and array annotations check each
+// element.
+func checkAnnotation(annotation *ast.TypeAnnotation, value ast.Expression, env *Environment) (ast.Expression, error) {
+	if annotation == nil {
+		return value, nil
+	} else if annotation.Elem != nil {
+		arr, ok := value.(*ast.ArrayLiteral)
+		if !ok {
+			return nil, typeMismatch(annotation.String(), value)
+		}
+		elements := make([]ast.Expression, len(arr.Elements))
+		for i, el := range arr.Elements {
+			var err error
+			if elements[i], err = checkAnnotation(annotation.Elem, el, env); err != nil {
+				return nil, err
+			}
+		}
+		return &ast.ArrayLiteral{Elements: elements}, nil
+	} else if annotation.Func != nil {
+		return checkFunctionType(annotation.Func, value)
+	} else if decl, ok := declaredType(annotation, env); ok {
+		if alias, ok := decl.(*ast.TypeDeclaration); ok {
+			return checkAnnotation(alias.Annotation, value, env)
+		}
+		if err := checkDeclaredType(decl, value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	} else if annotation.Unit != nil {
+		unit, err := resolveUnit(annotation.Unit, env)
+		if err != nil {
+			return nil, err
+		}
+
+		q, ok := value.(*ast.QuantityLiteral)
+		if !ok {
+			if !ast.IsNumeric(value) {
+				return nil, typeMismatch(annotation.String(), value)
+			}
+			q = &ast.QuantityLiteral{Value: value}
+		}
+		if !q.Unit.Compatible(unit) {
+			return nil, &units.DimensionError{Op: "convert", Left: q.Unit, Right: unit}
+		}
+		converted, err := ast.ConvertQuantity(q, unit)
+		if err != nil {
+			return nil, err
+		} else if unit.IsEmpty() {
+			return converted.(*ast.QuantityLiteral).Value, nil
+		}
+		return env.Config().number(converted), nil
+	}
+
+	var ok bool
+	switch annotation.Name {
+	case "int":
+		ok = value.Type() == ast.IntegerLiteralType
+	case "float":
+		ok = ast.IsNumber(value)
+	case "string":
+		ok = value.Type() == ast.StringLiteralType
+	case "boolean":
+		ok = value.Type() == ast.BooleanLiteralType
+	case "duration":
+		ok = value.Type() == ast.DurationLiteralType
+	case "timestamp":
+		ok = value.Type() == ast.TimestampLiteralType
+	}
+	if !ok {
+		return nil, typeMismatch(annotation.String(), value)
+	}
+	return value, nil
+}
+
+func evalFunctionDeclaration(expr *ast.FunctionDeclaration, env *Environment) (ast.Expression, error) {
+	fn := &Function{Decl: expr, Env: env}
+	if err := env.Declare(expr.Name, fn, false); err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
+
+func evalBlockExpression(expr *ast.BlockExpression, env *Environment) (ast.Expression, error) {
+	if len(expr.Exprs) == 0 {
+		return nil, errors.New("empty block")
+	}
+
+	scope := env.NewScope()
+	var result ast.Expression
+	for _, stmt := range expr.Exprs {
+		var err error
+		if result, err = evalExpression(stmt, scope); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
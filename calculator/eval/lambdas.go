@@ -0,0 +1,265 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+)
+
+func init() {
+	registerBuiltins(
+		&Builtin{Name: "filter", Arity: 2, Fn: builtinFilter},
+		&Builtin{Name: "map", Arity: 2, Fn: builtinMap},
+		&Builtin{Name: "reduce", Arity: -1, Fn: builtinReduce},
+		&Builtin{Name: "sort_by", Arity: 2, Fn: builtinSortBy},
+		&Builtin{Name: "any", Arity: 2, Fn: builtinAny},
+		&Builtin{Name: "all", Arity: 2, Fn: builtinAll},
+		&Builtin{Name: "zip", Arity: -1, Fn: builtinZip},
+	)
+}
+
+// evalLambdaExpression returns an anonymous function which closes over the
+// environment in which it was evaluated.
+func evalLambdaExpression(expr *ast.LambdaExpression, env *Environment) (ast.Expression, error) {
+	decl := &ast.FunctionDeclaration{Name: "lambda", Params: expr.Params, Body: expr.Body}
+	return &Function{Decl: decl, Env: env}, nil
+}
+
+// isFunction returns true if the value may be called.
+func isFunction(value ast.Expression) bool {
+	switch value.(type) {
+	case *Function, *Builtin:
+		return true
+	default:
+		return false
+	}
+}
+
+// callFunction calls a user-defined function or a builtin.
+func callFunction(env *Environment, fn ast.Expression, args []ast.Expression) (ast.Expression, error) {
+	switch f := fn.(type) {
+	case *Function:
+		return f.Call(args)
+	case *Builtin:
+		return f.Call(env, args)
+	default:
+		return nil, fmt.Errorf("cannot call non-function %s", fn.String())
+	}
+}
+
+// arity returns the number of parameters of a function or -1 if the function
+// is variadic.
+func arity(fn ast.Expression) int {
+	switch f := fn.(type) {
+	case *Function:
+		return len(f.Decl.Params)
+	case *Builtin:
+		return f.Arity
+	default:
+		return -1
+	}
+}
+
+// callbackArguments validates the array and callback arguments of a higher
+// order builtin.
+func callbackArguments(name string, args []ast.Expression) ([]ast.Expression, ast.Expression, error) {
+	arr, ok := args[0].(*ast.ArrayLiteral)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s expects an array, found %s", name, args[0].String())
+	} else if !isFunction(args[1]) {
+		return nil, nil, fmt.Errorf("%s expects a function, found %s", name, args[1].String())
+	}
+	return arr.Elements, args[1], nil
+}
+
+// callElement calls the callback with an element and, if the callback accepts
+// two parameters, its index.
+func callElement(env *Environment, fn, el ast.Expression, i int) (ast.Expression, error) {
+	if arity(fn) == 2 {
+		return callFunction(env, fn, []ast.Expression{el, &ast.IntegerLiteral{Value: big.NewInt(int64(i))}})
+	}
+	return callFunction(env, fn, []ast.Expression{el})
+}
+
+// predicate calls the callback and requires a boolean result.
+func predicate(env *Environment, name string, fn, el ast.Expression, i int) (bool, error) {
+	result, err := callElement(env, fn, el, i)
+	if err != nil {
+		return false, err
+	}
+	b, ok := result.(*ast.BooleanLiteral)
+	if !ok {
+		return false, fmt.Errorf("%s expects a boolean result, found %s", name, result.String())
+	}
+	return b.Value, nil
+}
+
+// builtinFilter returns the elements for which the callback returns true.
+func builtinFilter(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	elements, fn, err := callbackArguments("filter", args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []ast.Expression{}
+	for i, el := range elements {
+		ok, err := predicate(env, "filter", fn, el, i)
+		if err != nil {
+			return nil, err
+		} else if ok {
+			result = append(result, el)
+		}
+	}
+	return &ast.ArrayLiteral{Elements: result}, nil
+}
+
+// builtinMap returns the results of calling the callback on every element.
+func builtinMap(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	elements, fn, err := callbackArguments("map", args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ast.Expression, len(elements))
+	for i, el := range elements {
+		if result[i], err = callElement(env, fn, el, i); err != nil {
+			return nil, err
+		}
+	}
+	return &ast.ArrayLiteral{Elements: result}, nil
+}
+
+// builtinReduce folds the elements with the callback `(acc, el) -> ...`.
+// Without an initial value the first element is used.
+func builtinReduce(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, &ArgumentCountError{Name: "reduce", Expected: "2 or 3", Found: len(args)}
+	}
+	elements, fn, err := callbackArguments("reduce", args)
+	if err != nil {
+		return nil, err
+	}
+
+	var acc ast.Expression
+	if len(args) == 3 {
+		acc = args[2]
+	} else if len(elements) == 0 {
+		return nil, fmt.Errorf("reduce of an empty array requires an initial value")
+	} else {
+		acc, elements = elements[0], elements[1:]
+	}
+
+	for _, el := range elements {
+		if acc, err = callFunction(env, fn, []ast.Expression{acc, el}); err != nil {
+			return nil, err
+		}
+	}
+	return acc, nil
+}
+
+// builtinSortBy returns the elements stably sorted by the keys returned by
+// the callback.
+func builtinSortBy(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	elements, fn, err := callbackArguments("sort_by", args)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]ast.Expression, len(elements))
+	for i, el := range elements {
+		if keys[i], err = callElement(env, fn, el, i); err != nil {
+			return nil, err
+		}
+	}
+
+	order := make([]int, len(elements))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if err != nil {
+			return false
+		}
+		lh, ok := keys[order[i]].(ast.LessThanExpression)
+		if !ok {
+			err = fmt.Errorf("sort_by cannot compare %s", keys[order[i]].String())
+			return false
+		}
+		less, cerr := lh.LessThan(keys[order[j]])
+		if cerr != nil {
+			err = cerr
+			return false
+		}
+		return less.(*ast.BooleanLiteral).Value
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ast.Expression, len(elements))
+	for i, j := range order {
+		result[i] = elements[j]
+	}
+	return &ast.ArrayLiteral{Elements: result}, nil
+}
+
+// builtinAny returns true if the callback returns true for any element.
+func builtinAny(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	elements, fn, err := callbackArguments("any", args)
+	if err != nil {
+		return nil, err
+	}
+	for i, el := range elements {
+		if ok, err := predicate(env, "any", fn, el, i); err != nil || ok {
+			return &ast.BooleanLiteral{Value: ok}, err
+		}
+	}
+	return &ast.BooleanLiteral{Value: false}, nil
+}
+
+// builtinAll returns true if the callback returns true for every element.
+func builtinAll(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	elements, fn, err := callbackArguments("all", args)
+	if err != nil {
+		return nil, err
+	}
+	for i, el := range elements {
+		if ok, err := predicate(env, "all", fn, el, i); err != nil || !ok {
+			return &ast.BooleanLiteral{Value: ok}, err
+		}
+	}
+	return &ast.BooleanLiteral{Value: true}, nil
+}
+
+// builtinZip pairs the elements of arrays by index. The result is as long as
+// the shortest array.
+func builtinZip(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	if len(args) == 0 {
+		return &ast.ArrayLiteral{Elements: []ast.Expression{}}, nil
+	}
+
+	arrays := make([][]ast.Expression, len(args))
+	length := -1
+	for i, arg := range args {
+		arr, ok := arg.(*ast.ArrayLiteral)
+		if !ok {
+			return nil, fmt.Errorf("zip expects arrays, found %s", arg.String())
+		}
+		arrays[i] = arr.Elements
+		if length < 0 || len(arr.Elements) < length {
+			length = len(arr.Elements)
+		}
+	}
+
+	result := make([]ast.Expression, length)
+	for i := range result {
+		tuple := make([]ast.Expression, len(arrays))
+		for j, arr := range arrays {
+			tuple[j] = arr[i]
+		}
+		result[i] = &ast.ArrayLiteral{Elements: tuple}
+	}
+	return &ast.ArrayLiteral{Elements: result}, nil
+}
@@ -0,0 +1,131 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+)
+
+func init() {
+	registerBuiltins(&Builtin{Name: "range", Arity: -1, Fn: builtinRange})
+}
+
+// evalForExpression evaluates the body once for each element of the iterable
+// and collects the values into an array. Iterations whose body evaluates to
+// nil, such as an if without an else, are left out.
+func evalForExpression(expr *ast.ForExpression, env *Environment) (ast.Expression, error) {
+	iterable, err := evalExpression(expr.Iterable, env)
+	if err != nil {
+		return nil, err
+	}
+	elements, err := iterate(iterable)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ast.Expression, 0, len(elements))
+	for _, el := range elements {
+		if err := env.step(); err != nil {
+			return nil, err
+		}
+
+		scope := env.NewScope()
+		scope.Declare(expr.Name, el, false)
+		value, err := evalBlockExpression(expr.Body, scope)
+		if err != nil {
+			return nil, err
+		} else if value.Type() != ast.NilLiteralType {
+			results = append(results, value)
+		}
+	}
+	return &ast.ArrayLiteral{Elements: results}, nil
+}
+
+// iterate returns the elements of an array, the rows of a matrix, the
+// characters of a string, the `[name, value]` pairs of the fields of a struct
+// or the members of an enum.
+func iterate(value ast.Expression) ([]ast.Expression, error) {
+	switch v := value.(type) {
+	case *ast.ArrayLiteral:
+		return v.Elements, nil
+	case *ast.MatrixLiteral:
+		rows := make([]ast.Expression, len(v.Rows))
+		for i, row := range v.Rows {
+			rows[i] = &ast.ArrayLiteral{Elements: row}
+		}
+		return rows, nil
+	case *ast.StringLiteral:
+		var chars []ast.Expression
+		for _, r := range v.Value {
+			chars = append(chars, &ast.StringLiteral{Value: string(r)})
+		}
+		return chars, nil
+	case *ast.StructLiteral:
+		return structFields(v), nil
+	case *ast.EnumDeclaration:
+		return v.Values(), nil
+	default:
+		return nil, fmt.Errorf("cannot iterate over %s", value.String())
+	}
+}
+
+// builtinRange returns the numbers from start up to but excluding stop,
+// `range(stop)`, `range(start, stop)` or `range(start, stop, step)`.
+// Integer arguments produce integers and decimal arguments produce decimals.
+func builtinRange(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	var start, stop, step ast.Expression = &ast.IntegerLiteral{Value: big.NewInt(0)}, nil, &ast.IntegerLiteral{Value: big.NewInt(1)}
+	switch len(args) {
+	case 1:
+		stop = args[0]
+	case 2:
+		start, stop = args[0], args[1]
+	case 3:
+		start, stop, step = args[0], args[1], args[2]
+	default:
+		return nil, &ArgumentCountError{Name: "range", Expected: "1 to 3", Found: len(args)}
+	}
+
+	for _, arg := range []ast.Expression{start, stop, step} {
+		if !ast.IsNumber(arg) {
+			return nil, fmt.Errorf("range expects numeric arguments, found %s", arg.String())
+		}
+	}
+
+	first, _ := shortestRat(start)
+	last, _ := shortestRat(stop)
+	delta, _ := shortestRat(step)
+	if delta.Sign() == 0 {
+		return nil, errors.New("range step must not be zero")
+	}
+
+	// The number of elements is the ceiling of (stop - start) / step
+	span := new(big.Rat).Sub(last, first)
+	span.Quo(span, delta)
+	count := new(big.Int)
+	if span.Sign() > 0 {
+		count.Add(span.Num(), span.Denom())
+		count.Sub(count, big.NewInt(1))
+		count.Quo(count, span.Denom())
+	}
+
+	// Each element is computed from the start to avoid accumulating errors
+	var elements []ast.Expression
+	for i := new(big.Int); i.Cmp(count) < 0; i.Add(i, big.NewInt(1)) {
+		if err := env.step(); err != nil {
+			return nil, err
+		}
+
+		offset, err := step.(ast.MultExpression).Mult(&ast.IntegerLiteral{Value: new(big.Int).Set(i)})
+		if err != nil {
+			return nil, err
+		}
+		value, err := start.(ast.AddExpression).Add(offset)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, env.Config().number(value))
+	}
+	return &ast.ArrayLiteral{Elements: elements}, nil
+}
@@ -5,9 +5,19 @@ import (
 	"fmt"
 	"github.com/eliquious/aechbar/calculator/ast"
 	"github.com/eliquious/lexer"
-	// "math/big"
+	"math/big"
 )
 
+// unsupportedOperands returns an error for operands which do not implement
+// the operator of a binary expression.
+func unsupportedOperands(expr *ast.BinaryExpression) error {
+	return &ast.OperandError{
+		Operation: fmt.Sprintf("operator %s", expr.Op),
+		Left:      ast.OperandKind(expr.LExpr),
+		Right:     ast.OperandKind(expr.RExpr),
+	}
+}
+
 func evalPlusExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
 	if expr.Op != lexer.PLUS {
 		return nil, errors.New("Expected PLUS operand")
@@ -16,7 +26,7 @@ func evalPlusExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
 	if add, ok := expr.LExpr.(ast.AddExpression); ok {
 		return add.Add(expr.RExpr)
 	}
-	return nil, errors.New(fmt.Sprintf("PLUS operand not supported for %T and %T", expr.LExpr, expr.RExpr))
+	return nil, unsupportedOperands(expr)
 
 	// if lh.Type() == IntegerLiteralType && rh.Type() == IntegerLiteralType {
 	// 	i := new(big.Int)
@@ -47,7 +57,7 @@ func evalMinusExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
 	if e, ok := expr.LExpr.(ast.SubExpression); ok {
 		return e.Sub(expr.RExpr)
 	}
-	return nil, errors.New(fmt.Sprintf("MINUS operand not supported for %T and %T", expr.LExpr, expr.RExpr))
+	return nil, unsupportedOperands(expr)
 }
 
 func evalMultExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
@@ -58,7 +68,7 @@ func evalMultExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
 	if e, ok := expr.LExpr.(ast.MultExpression); ok {
 		return e.Mult(expr.RExpr)
 	}
-	return nil, errors.New(fmt.Sprintf("MUL operand not supported for %T and %T", expr.LExpr, expr.RExpr))
+	return nil, unsupportedOperands(expr)
 }
 
 func evalDivExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
@@ -69,16 +79,28 @@ func evalDivExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
 	if e, ok := expr.LExpr.(ast.DivExpression); ok {
 		return e.Div(expr.RExpr)
 	}
-	return nil, errors.New(fmt.Sprintf("DIV operand not supported for %T and %T", expr.LExpr, expr.RExpr))
+	return nil, unsupportedOperands(expr)
 }
 
-func evalPowExpression(expr *ast.BinaryExpression) (ast.Expression, error) {
+// evalPowExpression raises the left operand to the power of the right one.
+// Fractional powers of integers and fractions which are not exact are
+// computed with the configured precision.
+func evalPowExpression(expr *ast.BinaryExpression, config *Config) (ast.Expression, error) {
 	if expr.Op != lexer.POW {
 		return nil, errors.New("Expected POW operand")
 	}
 
+	if exp, ok := expr.RExpr.(*ast.RationalLiteral); ok {
+		switch x := expr.LExpr.(type) {
+		case *ast.IntegerLiteral:
+			return ast.PowFraction(new(big.Rat).SetInt(x.Value), exp.Value, config.precision())
+		case *ast.RationalLiteral:
+			return ast.PowFraction(x.Value, exp.Value, config.precision())
+		}
+	}
+
 	if e, ok := expr.LExpr.(ast.PowExpression); ok {
 		return e.Pow(expr.RExpr)
 	}
-	return nil, errors.New(fmt.Sprintf("Pow operand not supported for %T and %T", expr.LExpr, expr.RExpr))
+	return nil, unsupportedOperands(expr)
 }
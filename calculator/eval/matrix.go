@@ -0,0 +1,227 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/bigmath"
+	"github.com/eliquious/lexer"
+)
+
+func init() {
+	registerBuiltins(
+		&Builtin{Name: "matrix", Arity: 1, Fn: builtinMatrix},
+		&Builtin{Name: "identity", Arity: 1, Fn: builtinIdentity},
+		&Builtin{Name: "transpose", Arity: 1, Fn: builtinTranspose},
+		&Builtin{Name: "det", Arity: 1, Fn: builtinDet},
+		&Builtin{Name: "inv", Arity: 1, Fn: builtinInv},
+		&Builtin{Name: "solve", Arity: 2, Fn: builtinSolve},
+		&Builtin{Name: "lu", Arity: 1, Fn: builtinLU},
+		&Builtin{Name: "qr", Arity: 1, Fn: builtinQR},
+		&Builtin{Name: "eigenvalues", Arity: 1, Fn: builtinEigenvalues},
+	)
+}
+
+// evalMatrixLiteral evaluates the elements of a matrix, which must be real
+// or complex numbers.
+func evalMatrixLiteral(expr *ast.MatrixLiteral, env *Environment) (ast.Expression, error) {
+	rows, cols := expr.Shape()
+	return ast.NewMatrix(rows, cols, func(i, j int) (ast.Expression, error) {
+		value, err := evalExpression(expr.Rows[i][j], env)
+		if err != nil {
+			return nil, err
+		} else if !ast.IsNumeric(value) {
+			return nil, fmt.Errorf("matrix elements must be numbers, found %s", ast.OperandType(value))
+		}
+		return value, nil
+	})
+}
+
+func evalUnaryMatrixExpression(op lexer.Token, expr *ast.MatrixLiteral) (ast.Expression, error) {
+	return expr.Map(func(el ast.Expression) (ast.Expression, error) {
+		return evalUnaryOperand(op, el)
+	})
+}
+
+// matrixArg returns the argument of a builtin which expects a matrix.
+func matrixArg(name string, arg ast.Expression) (*ast.MatrixLiteral, error) {
+	m, ok := arg.(*ast.MatrixLiteral)
+	if !ok {
+		return nil, fmt.Errorf("%s expects a matrix, found %s", name, ast.OperandType(arg))
+	}
+	return m, nil
+}
+
+// floatMatrix returns the real and imaginary parts of the elements of a
+// matrix at the configured precision.
+func floatMatrix(env *Environment, m *ast.MatrixLiteral) (re, im [][]*big.Float) {
+	config := env.Config()
+	re = make([][]*big.Float, len(m.Rows))
+	im = make([][]*big.Float, len(m.Rows))
+	for i, row := range m.Rows {
+		re[i] = make([]*big.Float, len(row))
+		im[i] = make([]*big.Float, len(row))
+		for j, el := range row {
+			if z, ok := config.number(el).(*ast.ComplexLiteral); ok {
+				re[i][j], im[i][j] = z.Re, z.Im
+				continue
+			}
+			re[i][j], _ = config.float(el)
+			im[i][j] = new(big.Float).SetPrec(config.precision()).SetMode(config.Rounding)
+		}
+	}
+	return re, im
+}
+
+// decimalMatrix returns a matrix of decimals.
+func decimalMatrix(a [][]*big.Float) *ast.MatrixLiteral {
+	m, _ := ast.NewMatrix(len(a), len(a[0]), func(i, j int) (ast.Expression, error) {
+		return &ast.DecimalLiteral{Value: a[i][j]}, nil
+	})
+	return m
+}
+
+// builtinMatrix returns the matrix with the rows of an array of arrays.
+func builtinMatrix(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	if m, ok := args[0].(*ast.MatrixLiteral); ok {
+		return m, nil
+	}
+
+	arr, ok := args[0].(*ast.ArrayLiteral)
+	if !ok || len(arr.Elements) == 0 {
+		return nil, fmt.Errorf("matrix expects an array of rows, found %s", ast.OperandType(args[0]))
+	}
+	rows := make([][]ast.Expression, len(arr.Elements))
+	for i, el := range arr.Elements {
+		row, ok := el.(*ast.ArrayLiteral)
+		if !ok || len(row.Elements) == 0 {
+			return nil, fmt.Errorf("matrix expects an array of rows, found row %s", ast.OperandType(el))
+		} else if i > 0 && len(row.Elements) != len(rows[0]) {
+			return nil, fmt.Errorf("matrix rows must have the same length, found %d and %d", len(rows[0]), len(row.Elements))
+		}
+		rows[i] = row.Elements
+	}
+	return evalMatrixLiteral(&ast.MatrixLiteral{Rows: rows}, env)
+}
+
+// builtinIdentity returns the n×n identity matrix.
+func builtinIdentity(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	n, ok := args[0].(*ast.IntegerLiteral)
+	if !ok || n.Value.Sign() <= 0 || !n.Value.IsInt64() {
+		return nil, fmt.Errorf("identity expects a positive integer, found %s", args[0].String())
+	}
+	return ast.Identity(int(n.Value.Int64())), nil
+}
+
+// builtinTranspose returns the transpose of a matrix.
+func builtinTranspose(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	m, err := matrixArg("transpose", args[0])
+	if err != nil {
+		return nil, err
+	}
+	return m.Transpose(), nil
+}
+
+// builtinDet returns the determinant of a square matrix.
+func builtinDet(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	m, err := matrixArg("det", args[0])
+	if err != nil {
+		return nil, err
+	}
+	det, err := ast.Determinant(m)
+	if err != nil {
+		return nil, err
+	}
+	return env.Config().number(det), nil
+}
+
+// builtinInv returns the inverse of a square matrix.
+func builtinInv(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	m, err := matrixArg("inv", args[0])
+	if err != nil {
+		return nil, err
+	}
+	inv, err := ast.Inverse(m)
+	if err != nil {
+		return nil, err
+	}
+	return env.Config().number(inv), nil
+}
+
+// builtinSolve returns the solution x of A·x = b for a square matrix A and a
+// vector or matrix b.
+func builtinSolve(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	m, err := matrixArg("solve", args[0])
+	if err != nil {
+		return nil, err
+	}
+	x, err := ast.Solve(m, args[1])
+	if err != nil {
+		return nil, err
+	}
+	return env.Config().number(x), nil
+}
+
+// builtinLU returns the matrices [L, U, P] of the decomposition P·A = L·U of
+// a square matrix with partial pivoting.
+func builtinLU(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	m, err := matrixArg("lu", args[0])
+	if err != nil {
+		return nil, err
+	}
+	l, u, p, err := ast.LU(m)
+	if err != nil {
+		return nil, err
+	}
+	return env.Config().number(&ast.ArrayLiteral{Elements: []ast.Expression{l, u, p}}), nil
+}
+
+// builtinQR returns the matrices [Q, R] of the reduced decomposition A = Q·R
+// of a real matrix.
+func builtinQR(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	m, err := matrixArg("qr", args[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range m.Rows {
+		for _, el := range row {
+			if !ast.IsNumber(el) {
+				return nil, fmt.Errorf("qr expects a real matrix, found element %s", ast.OperandType(el))
+			}
+		}
+	}
+
+	re, _ := floatMatrix(env, m)
+	q, r := bigmath.QR(re)
+	return &ast.ArrayLiteral{Elements: []ast.Expression{decimalMatrix(q), decimalMatrix(r)}}, nil
+}
+
+// builtinEigenvalues returns the eigenvalues of a square matrix sorted by
+// their real and then imaginary parts. Eigenvalues with an imaginary part
+// are complex.
+func builtinEigenvalues(env *Environment, args []ast.Expression) (ast.Expression, error) {
+	m, err := matrixArg("eigenvalues", args[0])
+	if err != nil {
+		return nil, err
+	}
+	if rows, cols := m.Shape(); rows != cols {
+		return nil, &ast.SquareError{Op: "eigenvalues", Rows: rows, Cols: cols}
+	}
+
+	re, im := floatMatrix(env, m)
+	vre, vim, err := bigmath.Eigenvalues(re, im)
+	if err != nil {
+		return nil, fmt.Errorf("eigenvalues: %s", err)
+	}
+
+	values := make([]ast.Expression, len(vre))
+	for i := range values {
+		if vim[i].Sign() == 0 {
+			values[i] = &ast.DecimalLiteral{Value: vre[i]}
+		} else {
+			values[i] = &ast.ComplexLiteral{Re: vre[i], Im: vim[i]}
+		}
+	}
+	return &ast.ArrayLiteral{Elements: values}, nil
+}
@@ -0,0 +1,197 @@
+package eval
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/parser"
+)
+
+// ModuleExtension is the file extension of worksheets imported by name.
+const ModuleExtension = ".calc"
+
+// Module is an imported worksheet. Its declarations are accessed through the
+// name it was imported as, `physics.G`.
+type Module struct {
+	ast.Span
+
+	Name string
+	Path string
+	Env  *Environment
+}
+
+func (m Module) Type() ast.ExpressionType { return ast.ModuleType }
+func (m Module) String() string           { return fmt.Sprintf("<module %s>", m.Name) }
+
+// Get returns a top level declaration of the module.
+func (m *Module) Get(name string) (ast.Expression, bool) {
+	if b, ok := m.Env.values[name]; ok {
+		value, err := b.get(m.Env)
+		return value, err == nil
+	}
+	return nil, false
+}
+
+// Loader resolves, evaluates and caches the modules imported by the
+// environments sharing a root. Each module is evaluated once.
+type Loader struct {
+	// SearchPath lists the directories searched for modules imported by name
+	// and for relative paths which are not found next to the importing file
+	SearchPath []string
+
+	sources map[string]string
+	modules map[string]*Module
+	loading []string
+}
+
+// NewLoader returns a loader which searches the given directories.
+func NewLoader(searchPath ...string) *Loader {
+	return &Loader{
+		SearchPath: searchPath,
+		sources:    make(map[string]string),
+		modules:    make(map[string]*Module),
+	}
+}
+
+// Register adds an in-memory module. Imports of the name, either as
+// `import name` or `import "name"`, evaluate the source instead of reading a
+// file.
+func (l *Loader) Register(name, source string) {
+	l.sources[name] = source
+	delete(l.modules, name)
+}
+
+// ImportCycleError is returned when a module imports itself directly or
+// through other modules.
+type ImportCycleError struct {
+	Cycle []string
+}
+
+// Error returns the string representation of the error.
+func (e *ImportCycleError) Error() string {
+	return "import cycle: " + strings.Join(e.Cycle, " -> ")
+}
+
+// evalImportExpression loads the module and declares it under its name.
+func evalImportExpression(expr *ast.ImportExpression, env *Environment) (ast.Expression, error) {
+	module, err := env.Loader().load(expr, env)
+	if err != nil {
+		return nil, err
+	}
+	if err := env.Declare(module.Name, module, false); err != nil {
+		return nil, err
+	}
+	return module, nil
+}
+
+// load returns the cached module or evaluates it in a new scope of the root
+// library environment, so modules see the builtins and the standard library
+// but not the worksheet which imports them.
+func (l *Loader) load(expr *ast.ImportExpression, env *Environment) (*Module, error) {
+	key, name, err := l.resolve(expr)
+	if err != nil {
+		return nil, err
+	} else if m, ok := l.modules[key]; ok {
+		return m, nil
+	}
+
+	for i, k := range l.loading {
+		if k == key {
+			cycle := append(append([]string{}, l.loading[i:]...), key)
+			return nil, &ImportCycleError{Cycle: cycle}
+		}
+	}
+
+	source, ok := l.sources[key]
+	if !ok {
+		data, err := ioutil.ReadFile(key)
+		if err != nil {
+			return nil, err
+		}
+		source = string(data)
+	}
+
+	l.loading = append(l.loading, key)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	module := &Module{Name: name, Path: key, Env: env.root().NewFunctionScope()}
+	p := parser.NewParser(strings.NewReader(source))
+	for {
+		stmt, err := p.ParseExpression()
+		if err == parser.EOL {
+			continue
+		} else if err == parser.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("import %s: %s", key, err)
+		}
+
+		if _, err := evalExpression(stmt, module.Env); err != nil {
+			var cycle *ImportCycleError
+			if errors.As(err, &cycle) {
+				return nil, cycle
+			} else if e, ok := err.(*Error); ok && e.Span.IsValid() {
+				// The span refers to the module source so it is moved into
+				// the message and the error takes the span of the import
+				start := e.Span.Start
+				return nil, &Error{
+					Code:    e.Code,
+					Message: fmt.Sprintf("import %s:%d:%d: %s", key, start.Line+1, start.Char+1, e.Message),
+					Types:   e.Types,
+					Hint:    e.Hint,
+					Err:     e,
+				}
+			}
+			return nil, fmt.Errorf("import %s: %w", key, err)
+		}
+	}
+
+	l.modules[key] = module
+	return module, nil
+}
+
+// resolve returns the cache key and the name of an imported module. Keys
+// are the names of registered modules or absolute file paths.
+func (l *Loader) resolve(expr *ast.ImportExpression) (key, name string, err error) {
+	target, path := expr.Path, expr.Path
+	if path == "" {
+		target, name = expr.Name, expr.Name
+		path = name + ModuleExtension
+	} else {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	if _, ok := l.sources[target]; ok {
+		return target, name, nil
+	}
+
+	var dirs []string
+	if filepath.IsAbs(path) {
+		dirs = []string{""}
+	} else if expr.Path != "" {
+		// Relative paths are first resolved next to the importing file
+		dir := ""
+		if n := len(l.loading); n > 0 && filepath.IsAbs(l.loading[n-1]) {
+			dir = filepath.Dir(l.loading[n-1])
+		}
+		dirs = append([]string{dir}, l.SearchPath...)
+	} else {
+		dirs = l.SearchPath
+	}
+
+	for _, dir := range dirs {
+		candidate, err := filepath.Abs(filepath.Join(dir, path))
+		if err != nil {
+			return "", "", err
+		}
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, name, nil
+		}
+	}
+	return "", "", fmt.Errorf("module not found: %s", target)
+}
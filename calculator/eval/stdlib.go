@@ -0,0 +1,100 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/parser"
+	"github.com/eliquious/aechbar/calculator/units"
+)
+
+// NewStandardEnvironment returns an environment with the standard units and
+// physical constants preloaded. The library is declared in an enclosing
+// scope so that user declarations of the same units or names shadow it.
+func NewStandardEnvironment() *Environment {
+	lib := newLibrary(units.Standard())
+	if err := LoadConstants(lib, units.Constants); err != nil {
+		panic(err)
+	}
+	return lib.worksheet()
+}
+
+// LoadConstants declares each constant as a quantity under its name and
+// symbols, and its standard uncertainty under the same names followed by
+// units.UncertaintySuffix. The units of the constants must be known to the
+// environment. Constants given by an expression are derived from the
+// preceding constants whenever they are referenced, so that irrational
+// values are computed to the configured precision.
+func LoadConstants(env *Environment, constants []units.Constant) error {
+	for _, c := range constants {
+		unit, err := env.Units().Parse(c.Unit)
+		if err != nil {
+			return err
+		}
+
+		value, err := constantBinding(c.Name, c.Value, unit, env)
+		if err != nil {
+			return err
+		}
+		uncertainty, err := constantValue(c.Name, c.Uncertainty, unit)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range append([]string{c.Name}, c.Symbols...) {
+			if err := env.bind(name, value); err != nil {
+				return err
+			} else if err := env.Declare(name+units.UncertaintySuffix, uncertainty, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// constantBinding returns the binding of a constant whose value is either a
+// number or an expression of the constants declared in the environment.
+func constantBinding(name, s string, unit units.Compound, env *Environment) (*binding, error) {
+	if value, err := constantValue(name, s, unit); err == nil {
+		return &binding{value: value, constant: true}, nil
+	}
+
+	expr, err := parser.ParseExpression(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for constant %s: %s", name, s)
+	}
+	derive := func(c *Config) (ast.Expression, error) {
+		scope := env.NewScope()
+		scope.config = c
+		value, err := evalExpression(expr, scope)
+		if err != nil {
+			return nil, fmt.Errorf("constant %s: %w", name, err)
+		}
+		q, ok := value.(*ast.QuantityLiteral)
+		if !ok {
+			return nil, fmt.Errorf("constant %s: expected a quantity in %s, found %s", name, unit, value.String())
+		}
+		return ast.ConvertQuantity(q, unit)
+	}
+
+	// The expression is checked once as it cannot fail when referenced
+	if _, err := derive(env.Config()); err != nil {
+		return nil, err
+	}
+	return &binding{constant: true, derive: derive}, nil
+}
+
+// constantValue returns a value of the named constant with its unit
+// attached. Integers are kept exact.
+func constantValue(name, s string, unit units.Compound) (ast.Expression, error) {
+	var value ast.Expression
+	if i, ok := new(big.Int).SetString(s, 10); ok {
+		value = &ast.IntegerLiteral{Value: i}
+	} else if f, ok := new(big.Float).SetString(s); ok {
+		value = &ast.DecimalLiteral{Value: f}
+	} else {
+		return nil, fmt.Errorf("invalid value for constant %s: %s", name, s)
+	}
+	return ast.NewQuantity(value, unit)
+}
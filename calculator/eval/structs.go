@@ -0,0 +1,207 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+)
+
+// evalStructDeclaration declares the struct type under its name.
+func evalStructDeclaration(expr *ast.StructDeclaration, env *Environment) (ast.Expression, error) {
+	for i, f := range expr.Fields {
+		if expr.Field(f.Name) != i {
+			return nil, fmt.Errorf("duplicate field %s in struct %s", f.Name, expr.Name)
+		}
+	}
+	if err := env.Declare(expr.Name, expr, false); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// evalStructExpression creates a struct from its keyed fields. Values are
+// checked against the field annotations and missing fields are set to the
+// zero value of their type.
+func evalStructExpression(expr *ast.StructExpression, env *Environment) (ast.Expression, error) {
+	decl, err := lookupStruct(expr.Name, env)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]ast.Expression, len(decl.Fields))
+	for _, fv := range expr.Fields {
+		i := decl.Field(fv.Name)
+		if i < 0 {
+			return nil, fmt.Errorf("unknown field %s in struct %s", fv.Name, decl.Name)
+		} else if values[i] != nil {
+			return nil, fmt.Errorf("duplicate field %s in struct %s", fv.Name, decl.Name)
+		}
+
+		value, err := evalExpression(fv.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		if values[i], err = fieldValue(decl.Fields[i].Annotation, value, env); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", decl.Name, fv.Name, err)
+		}
+	}
+
+	for i, f := range decl.Fields {
+		if values[i] == nil {
+			if values[i], err = zeroValue(f.Annotation, env); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &ast.StructLiteral{Decl: decl, Values: values}, nil
+}
+
+// evalFieldExpression returns a field of a struct, a member of an enum or a
+// declaration of a module.
+func evalFieldExpression(expr *ast.FieldExpression, env *Environment) (ast.Expression, error) {
+	value, err := evalExpression(expr.Expr, env)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := value.(*Module); ok {
+		if field, ok := m.Get(expr.Name); ok {
+			return field, nil
+		}
+		return nil, &UndefinedError{Name: m.Name + "." + expr.Name}
+	}
+	s, ok := value.(*ast.StructLiteral)
+	if !ok {
+		return enumField(value, expr.Name)
+	}
+	field, ok := s.Get(expr.Name)
+	if !ok {
+		return nil, fmt.Errorf("unknown field %s in struct %s", expr.Name, s.Decl.Name)
+	}
+	return field, nil
+}
+
+// fieldValue checks the value of a field. Plain numbers given for fields
+// with a unit are taken to be in that unit, so `Mass: 5.972E24` is in kg,
+// and integers and fractions given for float fields become decimals.
+func fieldValue(annotation *ast.TypeAnnotation, value ast.Expression, env *Environment) (ast.Expression, error) {
+	annotation = resolveAlias(annotation, env)
+	if _, ok := declaredType(annotation, env); !ok && annotation.Unit != nil && ast.IsNumeric(value) {
+		unit, err := resolveUnit(annotation.Unit, env)
+		if err != nil {
+			return nil, err
+		}
+		value = &ast.QuantityLiteral{Value: value, Unit: unit}
+	}
+
+	value, err := checkAnnotation(annotation, value, env)
+	if err != nil {
+		return nil, err
+	} else if annotation.Name == "float" {
+		if f, ok := env.Config().float(value); ok {
+			return &ast.DecimalLiteral{Value: f}, nil
+		}
+	}
+	return value, nil
+}
+
+// lookupStruct returns the declaration of a struct type.
+func lookupStruct(name string, env *Environment) (*ast.StructDeclaration, error) {
+	value, ok := env.Get(name)
+	if !ok {
+		return nil, &UndefinedError{Name: name}
+	}
+	decl, ok := value.(*ast.StructDeclaration)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a struct", name)
+	}
+	return decl, nil
+}
+
+// declaredType returns the struct, enum or alias declaration named by an
+// annotation written like a single unit, such as `Planet`.
+func declaredType(annotation *ast.TypeAnnotation, env *Environment) (ast.Expression, bool) {
+	name, ok := annotation.Symbol()
+	if !ok {
+		return nil, false
+	}
+	value, ok := env.Get(name)
+	if !ok {
+		return nil, false
+	}
+	switch value.Type() {
+	case ast.StructDeclarationType, ast.EnumDeclarationType, ast.TypeDeclarationType:
+		return value, true
+	default:
+		return nil, false
+	}
+}
+
+// zeroValue returns the value of a field which was not given in a struct
+// literal. Enums default to their first member while nested structs and
+// timestamps default to nil.
+func zeroValue(annotation *ast.TypeAnnotation, env *Environment) (ast.Expression, error) {
+	annotation = resolveAlias(annotation, env)
+	if annotation.Elem != nil {
+		return &ast.ArrayLiteral{Elements: []ast.Expression{}}, nil
+	} else if decl, ok := declaredType(annotation, env); ok {
+		if enum, ok := decl.(*ast.EnumDeclaration); ok {
+			return &ast.EnumLiteral{Decl: enum}, nil
+		}
+		return &ast.NilLiteral{}, nil
+	} else if annotation.Unit != nil {
+		unit, err := resolveUnit(annotation.Unit, env)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.QuantityLiteral{Value: &ast.IntegerLiteral{Value: big.NewInt(0)}, Unit: unit}, nil
+	}
+
+	switch annotation.Name {
+	case "int":
+		return &ast.IntegerLiteral{Value: big.NewInt(0)}, nil
+	case "float":
+		config := env.Config()
+		return &ast.DecimalLiteral{Value: new(big.Float).SetPrec(config.precision()).SetMode(config.Rounding)}, nil
+	case "string":
+		return &ast.StringLiteral{Value: ""}, nil
+	case "boolean":
+		return &ast.BooleanLiteral{Value: false}, nil
+	case "duration":
+		return &ast.DurationLiteral{Value: 0}, nil
+	default:
+		return &ast.NilLiteral{}, nil
+	}
+}
+
+// structFields returns the fields of a struct as `[name, value]` pairs.
+func structFields(s *ast.StructLiteral) []ast.Expression {
+	fields := make([]ast.Expression, len(s.Values))
+	for i, v := range s.Values {
+		name := &ast.StringLiteral{Value: s.Decl.Fields[i].Name}
+		fields[i] = &ast.ArrayLiteral{Elements: []ast.Expression{name, v}}
+	}
+	return fields
+}
+
+// checkDeclaredType verifies that the value is an instance of the declared
+// struct or a member of the declared enum. Fields of a struct type may be
+// nil.
+func checkDeclaredType(decl ast.Expression, value ast.Expression) error {
+	switch d := decl.(type) {
+	case *ast.EnumDeclaration:
+		if e, ok := value.(*ast.EnumLiteral); ok && e.Decl == d {
+			return nil
+		}
+		return typeMismatch(d.Name, value)
+	case *ast.StructDeclaration:
+		if s, ok := value.(*ast.StructLiteral); ok && s.Decl == d {
+			return nil
+		} else if value.Type() == ast.NilLiteralType {
+			return nil
+		}
+		return typeMismatch(d.Name, value)
+	default:
+		return fmt.Errorf("%s is not a type", decl.String())
+	}
+}
@@ -0,0 +1,105 @@
+package eval
+
+import (
+	"fmt"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+)
+
+// evalTypeDeclaration declares a type alias. Aliases may refer to other
+// aliases but not to themselves.
+func evalTypeDeclaration(expr *ast.TypeDeclaration, env *Environment) (ast.Expression, error) {
+	for annotation := expr.Annotation; annotation != nil; {
+		name, ok := annotation.Symbol()
+		if !ok {
+			break
+		} else if name == expr.Name {
+			return nil, fmt.Errorf("type %s refers to itself", expr.Name)
+		}
+
+		alias, ok := lookupAlias(name, env)
+		if !ok {
+			break
+		}
+		annotation = alias.Annotation
+	}
+
+	if err := env.Declare(expr.Name, expr, false); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// lookupAlias returns the named type alias.
+func lookupAlias(name string, env *Environment) (*ast.TypeDeclaration, bool) {
+	value, ok := env.Get(name)
+	if !ok {
+		return nil, false
+	}
+	alias, ok := value.(*ast.TypeDeclaration)
+	return alias, ok
+}
+
+// resolveAlias returns the annotation an alias stands for.
+func resolveAlias(annotation *ast.TypeAnnotation, env *Environment) *ast.TypeAnnotation {
+	for {
+		name, ok := annotation.Symbol()
+		if !ok {
+			return annotation
+		}
+		alias, ok := lookupAlias(name, env)
+		if !ok {
+			return annotation
+		}
+		annotation = alias.Annotation
+	}
+}
+
+// checkFunctionType verifies that the value is a function accepting the
+// number of parameters of the function type.
+func checkFunctionType(fn *ast.FuncType, value ast.Expression) (ast.Expression, error) {
+	if !isFunction(value) {
+		return nil, typeMismatch(fn.String(), value)
+	} else if n := arity(value); n >= 0 && n != len(fn.Params) {
+		return nil, &TypeMismatchError{Expected: fn.String(), Found: fmt.Sprintf("function with %d parameter(s)", n), Type: ast.FunctionTypeName}
+	}
+	return value, nil
+}
+
+// checkGeneric checks an argument or return value against an annotation
+// which may refer to the type parameters of the function. A type parameter
+// is bound to the type of the first value it annotates and later values
+// must have the same type.
+func (f *Function) checkGeneric(annotation *ast.TypeAnnotation, value ast.Expression, bindings map[string]string) (ast.Expression, error) {
+	if name, ok := f.Decl.IsTypeParam(annotation); ok {
+		t, ok := ast.UnifyTypes(bindings[name], ast.TypeOf(value))
+		if !ok {
+			return nil, fmt.Errorf("type parameter %s is %s, found %s", name, bindings[name], ast.TypeOf(value))
+		}
+		bindings[name] = t
+		return value, nil
+	} else if annotation != nil && annotation.Elem != nil && f.usesTypeParam(annotation.Elem) {
+		arr, ok := value.(*ast.ArrayLiteral)
+		if !ok {
+			return nil, typeMismatch(annotation.String(), value)
+		}
+		for _, el := range arr.Elements {
+			if _, err := f.checkGeneric(annotation.Elem, el, bindings); err != nil {
+				return nil, err
+			}
+		}
+		return value, nil
+	}
+	return checkAnnotation(annotation, value, f.Env)
+}
+
+// usesTypeParam returns true if the annotation or its element type is a type
+// parameter of the function.
+func (f *Function) usesTypeParam(annotation *ast.TypeAnnotation) bool {
+	for ; annotation != nil; annotation = annotation.Elem {
+		if _, ok := f.Decl.IsTypeParam(annotation); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/units"
+	"github.com/eliquious/lexer"
+)
+
+func evalQuantityExpression(expr *ast.QuantityExpression, env *Environment) (ast.Expression, error) {
+	value, err := evalExpression(expr.Value, env)
+	if err != nil {
+		return nil, err
+	}
+
+	unit, err := resolveUnit(expr.Unit, env)
+	if err != nil {
+		return nil, err
+	}
+
+	quantity, err := ast.NewQuantity(value, unit)
+	if err != nil {
+		return nil, err
+	}
+	return env.Config().number(quantity), nil
+}
+
+func evalConversionExpression(expr *ast.ConversionExpression, env *Environment) (ast.Expression, error) {
+	value, err := evalExpression(expr.Expr, env)
+	if err != nil {
+		return nil, err
+	}
+
+	unit, err := resolveUnit(expr.Unit, env)
+	if err != nil {
+		return nil, err
+	}
+
+	q, ok := value.(*ast.QuantityLiteral)
+	if !ok {
+		q = &ast.QuantityLiteral{Value: value}
+	}
+	converted, err := ast.ConvertQuantity(q, unit)
+	if err != nil {
+		return nil, err
+	}
+	return env.Config().number(converted), nil
+}
+
+func evalUnaryQuantityExpression(op lexer.Token, expr *ast.QuantityLiteral) (ast.Expression, error) {
+	if op != lexer.MINUS && op != lexer.PLUS {
+		return nil, fmt.Errorf("Unsupported quantity unary expression: %s", op)
+	}
+
+	value, err := evalUnaryOperand(op, expr.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.QuantityLiteral{Value: value, Unit: expr.Unit}, nil
+}
+
+// evalUnitDeclaration declares a unit. Units without conversions are base
+// units. Each conversion either defines the declared unit in terms of known
+// units, as in `1 = 1 kg * m / s^2`, or defines a new unit in terms of the
+// declared one, as in `1 = 100 cm`.
+func evalUnitDeclaration(expr *ast.UnitDeclaration, env *Environment) (ast.Expression, error) {
+	registry := env.declaredUnits()
+	if len(expr.Conversions) == 0 {
+		unit, err := registry.DefineBase(expr.Name, expr.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		return unitQuantity(unit), nil
+	}
+
+	var unit *units.Unit
+	for _, conv := range expr.Conversions {
+		amount, err := exactValue(conv.Amount, env)
+		if err != nil {
+			return nil, err
+		}
+		value, err := exactValue(conv.Value, env)
+		if err != nil {
+			return nil, err
+		}
+
+		target, err := resolveUnit(conv.Unit, env)
+		if unknown, ok := err.(*UnknownUnitError); ok && unit == nil {
+			return nil, err
+		} else if ok {
+			// `amount unit = value target` defines the target in terms of
+			// the declared unit
+			if err := defineUnknownUnit(registry, unknown.Symbol, conv.Unit, amount, value, units.Of(unit)); err != nil {
+				return nil, err
+			}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		factor := new(big.Rat).Quo(value, amount)
+		if unit == nil {
+			if unit, err = registry.Define(expr.Name, expr.Symbol, factor, target); err != nil {
+				return nil, err
+			}
+		} else if err := checkConversion(units.Of(unit), big.NewRat(1, 1), target, factor); err != nil {
+			return nil, err
+		}
+	}
+	return unitQuantity(unit), nil
+}
+
+// evalConversionDeclaration relates two units. One of the units may be
+// undeclared in which case it is defined by the conversion.
+func evalConversionDeclaration(expr *ast.ConversionDeclaration, env *Environment) (ast.Expression, error) {
+	registry := env.declaredUnits()
+	lv, err := exactValue(expr.LValue.Value, env)
+	if err != nil {
+		return nil, err
+	}
+	rv, err := exactValue(expr.RValue.Value, env)
+	if err != nil {
+		return nil, err
+	}
+
+	lu, lerr := resolveUnit(expr.LValue.Unit, env)
+	ru, rerr := resolveUnit(expr.RValue.Unit, env)
+	switch {
+	case lerr == nil && rerr == nil:
+		if err := checkConversion(lu, lv, ru, rv); err != nil {
+			return nil, err
+		}
+	case lerr == nil:
+		unknown, ok := rerr.(*UnknownUnitError)
+		if !ok {
+			return nil, rerr
+		} else if err := defineUnknownUnit(registry, unknown.Symbol, expr.RValue.Unit, lv, rv, lu); err != nil {
+			return nil, err
+		}
+	case rerr == nil:
+		unknown, ok := lerr.(*UnknownUnitError)
+		if !ok {
+			return nil, lerr
+		} else if err := defineUnknownUnit(registry, unknown.Symbol, expr.LValue.Unit, rv, lv, ru); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, lerr
+	}
+	return evalQuantityExpression(expr.RValue, env)
+}
+
+// defineUnknownUnit defines the symbol so that `amount known = value symbol`.
+// The unknown unit must appear alone in its unit expression.
+func defineUnknownUnit(registry *units.Registry, symbol string, unit *ast.UnitExpression, amount, value *big.Rat, known units.Compound) error {
+	if len(unit.Terms) != 1 || unit.Terms[0].Exp != 1 {
+		return &UnknownUnitError{Symbol: symbol}
+	}
+	_, err := registry.Define(symbol, symbol, new(big.Rat).Quo(amount, value), known)
+	return err
+}
+
+// checkConversion verifies that `lv lu` and `rv ru` are the same quantity.
+func checkConversion(lu units.Compound, lv *big.Rat, ru units.Compound, rv *big.Rat) error {
+	if !lu.Compatible(ru) {
+		return &units.DimensionError{Op: "convert", Left: lu, Right: ru}
+	}
+
+	lh := new(big.Rat).Mul(lv, lu.Factor())
+	rh := new(big.Rat).Mul(rv, ru.Factor())
+	if lh.Cmp(rh) != 0 {
+		return fmt.Errorf("conversion conflicts with existing definition: %s %s = %s %s",
+			lv.RatString(), lu, new(big.Rat).Quo(lh, ru.Factor()).RatString(), ru)
+	}
+	return nil
+}
+
+// resolveUnit looks up every symbol of the unit expression. Irrational
+// unit factors are computed to the configured precision.
+func resolveUnit(expr *ast.UnitExpression, env *Environment) (units.Compound, error) {
+	registry := env.Units()
+	registry.SetPrecision(env.Config().precision())
+	var compound units.Compound
+	for _, t := range expr.Terms {
+		unit, ok := registry.Lookup(t.Symbol)
+		if !ok {
+			return nil, &UnknownUnitError{Symbol: t.Symbol}
+		}
+		compound = compound.Mul(units.Compound{{Unit: unit, Exp: t.Exp}})
+	}
+	return compound, nil
+}
+
+// exactValue evaluates a numeric expression to an exact fraction. Division
+// is evaluated exactly and decimals are read by their shortest representation
+// so that `3.28084` and `1/12` are exact.
+func exactValue(expr ast.Expression, env *Environment) (*big.Rat, error) {
+	scope := env.withConfig(func(c *Config) { c.Division = RationalDivision })
+	value, err := evalExpression(expr, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if r, ok := shortestRat(value); ok {
+		return r, nil
+	}
+	return nil, fmt.Errorf("unit conversion requires a number, found %s", value.String())
+}
+
+// shortestRat converts a number to a fraction reading decimals by their
+// shortest decimal representation rather than their binary value.
+func shortestRat(value ast.Expression) (*big.Rat, bool) {
+	if d, ok := value.(*ast.DecimalLiteral); ok && !d.Value.IsInf() {
+		if r, ok := new(big.Rat).SetString(d.Value.Text('g', -1)); ok {
+			return r, true
+		}
+	}
+	return ast.ToRat(value)
+}
+
+// unitQuantity returns one of the unit.
+func unitQuantity(unit *units.Unit) ast.Expression {
+	return &ast.QuantityLiteral{Value: &ast.IntegerLiteral{Value: big.NewInt(1)}, Unit: units.Of(unit)}
+}
@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
+)
+
+// parseArrayExpression parses the elements of an array literal or the rows
+// of a matrix literal, which are separated by semicolons. The opening bracket
+// has already been consumed.
+func (p *Parser) parseArrayExpression() (ast.Expression, error) {
+	defer p.allowStructLiterals()()
+
+	var rows [][]ast.Expression
+	for {
+		row, tok, pos, lit, err := p.parseRow()
+		if err != nil {
+			return nil, err
+		}
+
+		matrix := len(rows) > 0 || tok == lexer.SEMICOLON
+		if matrix && len(row) == 0 {
+			return nil, tokenError("Empty matrix row", tok, pos, lit)
+		} else if matrix && len(rows) > 0 && len(row) != len(rows[0]) {
+			return nil, tokenError("Matrix rows must have the same length", tok, pos, lit)
+		}
+		rows = append(rows, row)
+
+		if tok == lexer.RBRACKET && len(rows) == 1 {
+			return &ast.ArrayLiteral{Elements: row}, nil
+		} else if tok == lexer.RBRACKET {
+			return &ast.MatrixLiteral{Rows: rows}, nil
+		}
+	}
+}
+
+// parseRow parses comma separated expressions up to and including the
+// semicolon or closing bracket which ends a row of an array or matrix.
+func (p *Parser) parseRow() (row []ast.Expression, tok lexer.Token, pos lexer.Pos, lit string, err error) {
+	if tok, pos, lit = p.scanIgnoreWhitespace(); tok == lexer.RBRACKET || tok == lexer.SEMICOLON {
+		return nil, tok, pos, lit, nil
+	}
+	p.unscan()
+
+	for {
+		expr, err := p.parseExpression(lowestPrecedence)
+		if err != nil {
+			return nil, tok, pos, lit, err
+		}
+		row = append(row, expr)
+
+		tok, pos, lit = p.scanIgnoreWhitespace()
+		if tok == lexer.RBRACKET || tok == lexer.SEMICOLON {
+			return row, tok, pos, lit, nil
+		} else if tok != lexer.COMMA {
+			return nil, tok, pos, lit, newParseError(tokstr(tok, lit), []string{",", ";", "]"}, pos)
+		}
+	}
+}
+
+// parseIndexExpression parses an index `a[i]` or a slice `a[i:j]` where
+// either bound of the slice may be omitted. The opening bracket has already
+// been consumed.
+func (p *Parser) parseIndexExpression(expr ast.Expression, pos lexer.Pos) (ast.Expression, error) {
+	defer p.allowStructLiterals()()
+
+	var start ast.Expression
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok != lexer.COLON {
+		p.unscan()
+
+		var err error
+		if start, err = p.parseExpression(lowestPrecedence); err != nil {
+			return nil, err
+		}
+
+		tok, next, lit := p.scanIgnoreWhitespace()
+		if tok == lexer.RBRACKET {
+			return &ast.IndexExpression{Expr: expr, Index: start}, nil
+		} else if tok != lexer.COLON {
+			return nil, newParseError(tokstr(tok, lit), []string{":", "]"}, next)
+		}
+	}
+
+	slice := &ast.SliceExpression{Expr: expr, Start: start}
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == lexer.RBRACKET {
+		return slice, nil
+	}
+	p.unscan()
+
+	end, err := p.parseExpression(lowestPrecedence)
+	if err != nil {
+		return nil, err
+	}
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.RBRACKET {
+		return nil, newParseError(tokstr(tok, lit), []string{"]"}, pos)
+	}
+	slice.End = end
+	return slice, nil
+}
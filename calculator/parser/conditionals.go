@@ -0,0 +1,171 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
+)
+
+// parseIfExpression parses an if expression and its else branches. The IF
+// keyword at pos has already been consumed. An else branch may be followed
+// by a condition without repeating `if`, as in `else x < 0 { ... }`.
+func (p *Parser) parseIfExpression(pos lexer.Pos) (ast.Expression, error) {
+	cond, err := p.parseHeader()
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.parseBraceBlock()
+	if err != nil {
+		return nil, err
+	}
+	expr := &ast.IfExpression{Condition: cond, Body: body}
+
+	// The else keyword must follow the closing brace on the same line
+	tok, pos, _ := p.scanOperator()
+	switch tok {
+	case ELSE:
+	case ELSEIF:
+		expr.Else, err = p.parseIfExpression(pos)
+		return expr, err
+	default:
+		p.unscan()
+		return expr, nil
+	}
+
+	switch tok, next, _ := p.scanIgnoreWhitespace(); tok {
+	case IF:
+		expr.Else, err = p.parseIfExpression(next)
+	case lexer.LBRACE:
+		var block *ast.BlockExpression
+		if block, err = p.parseBlock(); err == nil {
+			expr.Else = &ast.ElseExpression{Body: block}
+			p.setSpan(expr.Else, pos)
+		}
+	default:
+		p.unscan()
+		expr.Else, err = p.parseIfExpression(pos)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// parseMatchExpression parses `match x { A -> 1; B, C -> 2; else -> 3 }`.
+// Arms are separated by newlines or semicolons and the else arm must be
+// last. The MATCH keyword has already been consumed.
+func (p *Parser) parseMatchExpression() (ast.Expression, error) {
+	subject, err := p.parseHeader()
+	if err != nil {
+		return nil, err
+	}
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.LBRACE {
+		return nil, newParseError(tokstr(tok, lit), []string{"{"}, pos)
+	}
+	defer p.allowStructLiterals()()
+
+	expr := &ast.MatchExpression{Subject: subject}
+	for {
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		switch tok {
+		case lexer.RBRACE:
+			if len(expr.Arms) == 0 && expr.Else == nil {
+				return nil, tokenError("Match requires at least one arm", tok, pos, lit)
+			}
+			return expr, nil
+		case lexer.SEMICOLON:
+			continue
+		case lexer.EOF:
+			return nil, newParseError(tokstr(tok, lit), []string{"}"}, pos)
+		}
+
+		if expr.Else != nil {
+			return nil, tokenError("Else must be the last arm of a match", tok, pos, lit)
+		} else if tok == ELSE {
+			if expr.Else, err = p.parseMatchBody(); err != nil {
+				return nil, err
+			}
+		} else {
+			p.unscan()
+			arm, err := p.parseMatchArm()
+			if err != nil {
+				return nil, err
+			}
+			expr.Arms = append(expr.Arms, arm)
+		}
+
+		// Arms end at a line break, a semicolon or the closing brace
+		tok, pos, lit = p.scan()
+		if tok == lexer.WS {
+			if strings.Contains(lit, "\n") {
+				continue
+			}
+			tok, pos, lit = p.scan()
+		}
+		if tok == lexer.RBRACE {
+			return expr, nil
+		} else if tok != lexer.SEMICOLON {
+			return nil, newParseError(tokstr(tok, lit), []string{";", "}"}, pos)
+		}
+	}
+}
+
+// parseMatchArm parses the comma separated patterns of a match arm and its
+// body. Patterns bind tighter than the arrow's `-`, so patterns with sums or
+// comparisons must be grouped.
+func (p *Parser) parseMatchArm() (*ast.MatchArm, error) {
+	arm := &ast.MatchArm{}
+	for {
+		p.noLambda = true
+		pattern, err := p.parseExpression(sumPrecedence)
+		p.noLambda = false
+		if err != nil {
+			return nil, err
+		}
+		arm.Patterns = append(arm.Patterns, pattern)
+
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok == lexer.MINUS {
+			break
+		} else if tok != lexer.COMMA {
+			return nil, newParseError(tokstr(tok, lit), []string{",", "->"}, pos)
+		}
+	}
+	if err := p.expectArrow(); err != nil {
+		return nil, err
+	}
+
+	body, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	arm.Body = body
+	return arm, nil
+}
+
+// parseMatchBody parses the arrow and body of the else arm of a match.
+func (p *Parser) parseMatchBody() (ast.Expression, error) {
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.MINUS {
+		return nil, newParseError(tokstr(tok, lit), []string{"->"}, pos)
+	} else if err := p.expectArrow(); err != nil {
+		return nil, err
+	}
+	return p.parseBody()
+}
+
+// parseBraceBlock parses a block including its opening brace.
+func (p *Parser) parseBraceBlock() (*ast.BlockExpression, error) {
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.LBRACE {
+		return nil, newParseError(tokstr(tok, lit), []string{"{"}, pos)
+	}
+	return p.parseBlock()
+}
+
+// parseHeader parses the condition of an if or the iterable of a for which
+// is followed by a block rather than a struct literal.
+func (p *Parser) parseHeader() (ast.Expression, error) {
+	noStructLiteral := p.noStructLiteral
+	p.noStructLiteral = true
+	defer func() { p.noStructLiteral = noStructLiteral }()
+	return p.parseExpression(lowestPrecedence)
+}
@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
+)
+
+// parseDeclaration parses `var`, `let` and `const` declarations. The
+// declaration keyword has already been consumed.
+func (p *Parser) parseDeclaration(keyword lexer.Token) (ast.Expression, error) {
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.EQ {
+		return nil, newParseError(tokstr(tok, lit), []string{"="}, pos)
+	}
+
+	value, err := p.parseExpression(lowestPrecedence)
+	if err != nil {
+		return nil, err
+	}
+
+	switch keyword {
+	case CONST:
+		return &ast.ConstantDeclaration{Name: name, Value: value}, nil
+	case LET:
+		return &ast.VariableDeclaration{Name: name, Value: value, Scoped: true}, nil
+	default:
+		return &ast.VariableDeclaration{Name: name, Value: value}, nil
+	}
+}
+
+// parseIdent parses an identifier and returns its name. Keywords naming
+// builtins, such as `filter`, may be redeclared.
+func (p *Parser) parseIdent() (string, error) {
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok != lexer.IDENT && !isFunctionKeyword(tok) {
+		return "", newParseError(tokstr(tok, lit), []string{"identifier"}, pos)
+	}
+	return lit, nil
+}
@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
+)
+
+// parseEnumDeclaration parses `enum Level = { LOW MEDIUM HIGH }`. Members may
+// be separated by whitespace, newlines, semicolons or commas. The ENUM
+// keyword has already been consumed.
+func (p *Parser) parseEnumDeclaration() (ast.Expression, error) {
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.EQ {
+		return nil, newParseError(tokstr(tok, lit), []string{"="}, pos)
+	}
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.LBRACE {
+		return nil, newParseError(tokstr(tok, lit), []string{"{"}, pos)
+	}
+
+	decl := &ast.EnumDeclaration{Name: name}
+	for {
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		switch tok {
+		case lexer.RBRACE:
+			if len(decl.Members) == 0 {
+				return nil, tokenError("Enum requires at least one member", tok, pos, lit)
+			}
+			return decl, nil
+		case lexer.SEMICOLON, lexer.COMMA:
+		case lexer.IDENT:
+			decl.Members = append(decl.Members, lit)
+		default:
+			return nil, newParseError(tokstr(tok, lit), []string{"identifier", "}"}, pos)
+		}
+	}
+}
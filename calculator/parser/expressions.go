@@ -0,0 +1,354 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
+)
+
+// Binding powers from loosest to tightest. Binary operators follow Go's
+// precedence levels with exponentiation binding tighter than the prefix
+// operators so that `-2 ** 2` is `-(2 ** 2)`. Assignment binds loosest so
+// that its target is never the operand of another operator.
+const (
+	lowestPrecedence = iota
+	assignPrecedence
+	toPrecedence
+	orPrecedence
+	andPrecedence
+	comparePrecedence
+	sumPrecedence
+	productPrecedence
+	prefixPrecedence
+	powerPrecedence
+	postfixPrecedence
+	callPrecedence
+)
+
+// infixPrecedence maps binary operators to their binding power.
+var infixPrecedence = map[lexer.Token]int{
+	lexer.OR:        orPrecedence,
+	lexer.AND:       andPrecedence,
+	lexer.EQEQ:      comparePrecedence,
+	lexer.NEQ:       comparePrecedence,
+	lexer.LT:        comparePrecedence,
+	lexer.LTE:       comparePrecedence,
+	lexer.GT:        comparePrecedence,
+	lexer.GTE:       comparePrecedence,
+	lexer.PLUS:      sumPrecedence,
+	lexer.MINUS:     sumPrecedence,
+	lexer.PIPE:      sumPrecedence,
+	lexer.XOR:       sumPrecedence,
+	lexer.MUL:       productPrecedence,
+	lexer.DIV:       productPrecedence,
+	lexer.LSHIFT:    productPrecedence,
+	lexer.RSHIFT:    productPrecedence,
+	lexer.AMPERSAND: productPrecedence,
+	lexer.POW:       powerPrecedence,
+	lexer.EQ:        assignPrecedence,
+	TO:              toPrecedence,
+}
+
+// rightAssociative contains the binary operators which group from the right.
+var rightAssociative = map[lexer.Token]bool{
+	lexer.POW: true,
+	lexer.EQ:  true,
+}
+
+// precedence returns the binding power of an infix or postfix operator.
+func precedence(tok lexer.Token) int {
+	if ast.IsUnaryOperator(tok) {
+		return postfixPrecedence
+	} else if tok == lexer.LPAREN || tok == lexer.LBRACKET || tok == lexer.DOT {
+		return callPrecedence
+	}
+	return infixPrecedence[tok]
+}
+
+// parseExpression parses an expression whose operators bind tighter than
+// the given precedence.
+func (p *Parser) parseExpression(prec int) (ast.Expression, error) {
+	left, err := p.parsePrefix()
+	if err != nil {
+		return nil, err
+	}
+
+	start := left.Position().Start
+	for {
+		tok, pos, lit := p.scanOperator()
+		if precedence(tok) <= prec {
+			p.unscan()
+			return left, nil
+		}
+
+		left, err = p.parseInfix(left, tok, pos, lit)
+		if err != nil {
+			return nil, err
+		}
+		p.setSpan(left, start)
+	}
+}
+
+// parsePrefix parses an operand or a prefix operator expression.
+func (p *Parser) parsePrefix() (ast.Expression, error) {
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	expr, err := p.parseOperand(tok, pos, lit)
+	if err != nil {
+		return nil, err
+	}
+	p.setSpan(expr, pos)
+	return expr, nil
+}
+
+// parseOperand parses the operand starting with the token.
+func (p *Parser) parseOperand(tok lexer.Token, pos lexer.Pos, lit string) (ast.Expression, error) {
+	switch tok {
+	case lexer.INTEGER, lexer.DECIMAL:
+		value, err := p.parseLiteral(tok, pos, lit)
+		if err != nil {
+			return nil, err
+		}
+		return p.parseQuantity(p.parseImaginary(value))
+	case lexer.STRING, lexer.TRUE, lexer.FALSE, lexer.DURATION:
+		return p.parseLiteral(tok, pos, lit)
+	case lexer.IDENT:
+		return p.parseIdentExpression(tok, pos, lit)
+	case lexer.LPAREN:
+		return p.parseParenExpression(pos)
+	case lexer.PLUS, lexer.MINUS, lexer.XOR:
+		return p.parsePrefixExpression(tok, pos)
+	case IF:
+		return p.parseIfExpression(pos)
+	case FOR:
+		return p.parseForExpression(pos)
+	case MATCH:
+		return p.parseMatchExpression()
+	case lexer.LBRACKET:
+		return p.parseArrayExpression()
+	case lexer.EOF:
+		return nil, newParseError(tokstr(tok, lit), []string{"expression"}, pos)
+	default:
+		if isFunctionKeyword(tok) {
+			return &ast.Identifier{Name: lit}, nil
+		}
+		return nil, tokenError("Unrecognized input error", tok, pos, lit)
+	}
+}
+
+// parsePrefixExpression parses the operand of a prefix operator. Signs
+// directly in front of a numeric literal are folded into the literal.
+func (p *Parser) parsePrefixExpression(op lexer.Token, pos lexer.Pos) (ast.Expression, error) {
+	expr, err := p.parseExpression(prefixPrecedence)
+	if err != nil {
+		return nil, err
+	}
+
+	if op == lexer.MINUS {
+		value := expr
+		if q, ok := expr.(*ast.QuantityExpression); ok {
+			value = q.Value
+		}
+
+		switch lit := value.(type) {
+		case *ast.IntegerLiteral:
+			lit.Value.Neg(lit.Value)
+			return expr, nil
+		case *ast.DecimalLiteral:
+			lit.Value.Neg(lit.Value)
+			return expr, nil
+		case *ast.ComplexLiteral:
+			lit.Im.Neg(lit.Im)
+			return expr, nil
+		}
+	} else if op == lexer.PLUS && ast.IsLiteral(expr) {
+		return expr, nil
+	}
+	return &ast.UnaryExpression{Op: op, Expr: expr, Prefix: true, OpPos: pos}, nil
+}
+
+// parseInfix parses the remainder of a binary or postfix expression whose
+// left hand side has already been parsed.
+func (p *Parser) parseInfix(left ast.Expression, op lexer.Token, pos lexer.Pos, lit string) (ast.Expression, error) {
+	if ast.IsUnaryOperator(op) {
+		return &ast.UnaryExpression{Op: op, Expr: left, OpPos: pos}, nil
+	} else if op == lexer.LPAREN {
+		return p.parseCallExpression(left, pos, lit)
+	} else if op == lexer.LBRACKET {
+		return p.parseIndexExpression(left, pos)
+	} else if op == lexer.DOT {
+		return p.parseMethodCall(left)
+	} else if op == TO {
+		return p.parseConversionExpression(left, pos)
+	}
+
+	prec, ok := infixPrecedence[op]
+	if !ok {
+		return nil, tokenError("Invalid binary operator", op, pos, lit)
+	} else if rightAssociative[op] {
+		prec--
+	}
+
+	// Only a declared name can be assigned to
+	ident, isIdent := left.(*ast.Identifier)
+	if op == lexer.EQ && !isIdent {
+		return nil, tokenError("Invalid assignment target", op, pos, lit)
+	}
+
+	right, err := p.parseExpression(prec)
+	if err != nil {
+		return nil, err
+	}
+
+	if op == lexer.EQ {
+		return &ast.AssignmentExpression{Name: ident.Name, Value: right}, nil
+	}
+	return &ast.BinaryExpression{Op: op, LExpr: left, RExpr: right, OpPos: pos}, nil
+}
+
+// parseCallExpression parses the arguments of a function call. The opening
+// parenthesis has already been consumed.
+func (p *Parser) parseCallExpression(fn ast.Expression, pos lexer.Pos, lit string) (ast.Expression, error) {
+	if ast.IsLiteral(fn) {
+		return nil, tokenError("Invalid function call", lexer.LPAREN, pos, lit)
+	}
+
+	args, err := p.parseExpressionList(lexer.RPAREN)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.CallExpression{Function: fn, Args: args}, nil
+}
+
+// parseExpressionList parses comma separated expressions up to and including
+// the closing token. Struct literals are allowed within the list even in the
+// header of an if or for.
+func (p *Parser) parseExpressionList(end lexer.Token) ([]ast.Expression, error) {
+	defer p.allowStructLiterals()()
+
+	var exprs []ast.Expression
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == end {
+		return exprs, nil
+	}
+	p.unscan()
+
+	for {
+		expr, err := p.parseExpression(lowestPrecedence)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		if tok == end {
+			return exprs, nil
+		} else if tok != lexer.COMMA {
+			return nil, newParseError(tokstr(tok, lit), []string{",", end.String()}, pos)
+		}
+	}
+}
+
+// allowStructLiterals enables struct literals within parentheses and
+// brackets, where a brace cannot start the block of an if or for, and
+// returns a function which restores the previous setting.
+func (p *Parser) allowStructLiterals() func() {
+	noStructLiteral := p.noStructLiteral
+	p.noStructLiteral = false
+	return func() { p.noStructLiteral = noStructLiteral }
+}
+
+// scanOperator scans the next token on the current line. A line break ends
+// the expression unless the line ends with an operator.
+func (p *Parser) scanOperator() (tok lexer.Token, pos lexer.Pos, lit string) {
+	tok, pos, lit = p.scan()
+	if tok == lexer.WS {
+		if strings.Contains(lit, "\n") {
+			return
+		}
+		tok, pos, lit = p.scan()
+	}
+	return
+}
+
+// parseIdentExpression parses an identifier reference or a struct literal.
+func (p *Parser) parseIdentExpression(tok lexer.Token, pos lexer.Pos, lit string) (ast.Expression, error) {
+	if tok != lexer.IDENT {
+		return nil, tokenError("Invalid identifier", tok, pos, lit)
+	}
+
+	// Struct literals follow the name of the struct without whitespace
+	if next, _, _ := p.scan(); next == lexer.LBRACE && !p.noStructLiteral {
+		return p.parseStructExpression(lit, pos)
+	}
+	p.unscan()
+	return &ast.Identifier{Name: lit}, nil
+}
+
+// parseParenExpression parses a parenthesized expression or the parameters
+// of a lambda, `(a, i) -> a > 5`. The opening parenthesis has already been
+// consumed at pos.
+func (p *Parser) parseParenExpression(pos lexer.Pos) (ast.Expression, error) {
+	exprs, err := p.parseExpressionList(lexer.RPAREN)
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, _, _ := p.scanOperator(); tok == lexer.MINUS && !p.noLambda {
+		if tok, _, _ := p.scan(); tok == lexer.GT {
+			return p.parseLambdaExpression(exprs)
+		}
+		p.unscan()
+	}
+	p.unscan()
+
+	if len(exprs) != 1 {
+		return nil, tokenError("Expected a single expression", lexer.LPAREN, pos, "(")
+	}
+	return &ast.GroupExpression{Expr: exprs[0]}, nil
+}
+
+// parseLambdaExpression parses the body of a lambda whose parameters have
+// been parsed as identifiers and whose arrow has been consumed.
+func (p *Parser) parseLambdaExpression(exprs []ast.Expression) (ast.Expression, error) {
+	params := make([]*ast.Parameter, len(exprs))
+	for i, expr := range exprs {
+		ident, ok := expr.(*ast.Identifier)
+		if !ok {
+			return nil, tokenError("Invalid lambda parameter", lexer.IDENT, expr.Position().Start, expr.String())
+		}
+		params[i] = &ast.Parameter{Name: ident.Name}
+	}
+
+	body, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.LambdaExpression{Params: params, Body: body}, nil
+}
+
+// parseMethodCall parses the method call `x.f(args)` and the field access
+// `x.f`. The dot has already been consumed.
+func (p *Parser) parseMethodCall(recv ast.Expression) (ast.Expression, error) {
+	tok, pos, lit := p.scan()
+	if tok != lexer.IDENT && !isFunctionKeyword(tok) {
+		return nil, newParseError(tokstr(tok, lit), []string{"identifier"}, pos)
+	}
+	name := lit
+	if next, _, _ := p.scan(); next != lexer.LPAREN {
+		p.unscan()
+		if tok != lexer.IDENT {
+			return nil, tokenError("Invalid field name", tok, pos, lit)
+		}
+		return &ast.FieldExpression{Expr: recv, Name: name}, nil
+	}
+	args, err := p.parseExpressionList(lexer.RPAREN)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.MethodCallExpression{Recv: recv, Name: name, Args: args}, nil
+}
+
+// isFunctionKeyword returns true for the keywords which name builtins.
+func isFunctionKeyword(tok lexer.Token) bool {
+	return tok == FILTER || (tok > startFunctions && tok < endFunctions)
+}
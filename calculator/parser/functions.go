@@ -0,0 +1,273 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
+)
+
+// typeNames maps the built-in type keywords to their annotation names.
+var typeNames = map[lexer.Token]string{
+	STRING_TYPE:    "string",
+	INT_TYPE:       "int",
+	FLOAT_TYPE:     "float",
+	TIMESTAMP_TYPE: "timestamp",
+	DURATION_TYPE:  "duration",
+	BOOLEAN_TYPE:   "boolean",
+}
+
+// parseFunctionDeclaration parses a function with an expression body,
+// `func in2m(x float) -> x * 0.0254`, or with a declared return type and a
+// block body, `func fNewton(M kg, m kg, r m) N = { ... }`. Type parameters
+// may precede the parameters, `func pair<A, B>(a A, b B) A`. The FUNC
+// keyword has already been consumed.
+func (p *Parser) parseFunctionDeclaration() (ast.Expression, error) {
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	typeParams, err := p.parseTypeParams()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.LPAREN {
+		return nil, newParseError(tokstr(tok, lit), []string{"("}, pos)
+	}
+	params, err := p.parseParameters()
+	if err != nil {
+		return nil, err
+	}
+	decl := &ast.FunctionDeclaration{Name: name, TypeParams: typeParams, Params: params}
+
+	// Optional return annotation
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok != lexer.EQ && tok != lexer.MINUS {
+		p.unscan()
+		if decl.Return, err = p.parseTypeAnnotation(); err != nil {
+			return nil, err
+		}
+		tok, pos, lit = p.scanIgnoreWhitespace()
+	}
+
+	switch tok {
+	case lexer.EQ:
+	case lexer.MINUS:
+		if err := p.expectArrow(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, newParseError(tokstr(tok, lit), []string{"=", "->"}, pos)
+	}
+
+	if decl.Body, err = p.parseBody(); err != nil {
+		return nil, err
+	}
+	return decl, nil
+}
+
+// parseParameters parses a comma separated parameter list with optional
+// annotations. The opening parenthesis has already been consumed.
+func (p *Parser) parseParameters() ([]*ast.Parameter, error) {
+	var params []*ast.Parameter
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == lexer.RPAREN {
+		return params, nil
+	}
+	p.unscan()
+
+	for {
+		name, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		param := &ast.Parameter{Name: name}
+
+		if tok, _, _ := p.scanIgnoreWhitespace(); tok != lexer.COMMA && tok != lexer.RPAREN {
+			p.unscan()
+			if param.Annotation, err = p.parseTypeAnnotation(); err != nil {
+				return nil, err
+			}
+		} else {
+			p.unscan()
+		}
+		params = append(params, param)
+
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		if tok == lexer.RPAREN {
+			return params, nil
+		} else if tok != lexer.COMMA {
+			return nil, newParseError(tokstr(tok, lit), []string{",", ")"}, pos)
+		}
+	}
+}
+
+// parseTypeParams parses an optional list of type parameters written in
+// angle brackets, `<A, B>`, or in square brackets, `[A, B]`.
+func (p *Parser) parseTypeParams() ([]string, error) {
+	var end lexer.Token
+	switch tok, _, _ := p.scanIgnoreWhitespace(); tok {
+	case lexer.LT:
+		end = lexer.GT
+	case lexer.LBRACKET:
+		end = lexer.RBRACKET
+	default:
+		p.unscan()
+		return nil, nil
+	}
+
+	var params []string
+	for {
+		name, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, name)
+
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		if tok == end {
+			return params, nil
+		} else if tok != lexer.COMMA {
+			return nil, newParseError(tokstr(tok, lit), []string{",", end.String()}, pos)
+		}
+	}
+}
+
+// parseFunctionType parses `func [A] (a A, i int) -> bool`. The FUNC keyword
+// has already been consumed.
+func (p *Parser) parseFunctionType() (*ast.FuncType, error) {
+	typeParams, err := p.parseTypeParams()
+	if err != nil {
+		return nil, err
+	}
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.LPAREN {
+		return nil, newParseError(tokstr(tok, lit), []string{"("}, pos)
+	}
+	params, err := p.parseParameters()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.MINUS {
+		return nil, newParseError(tokstr(tok, lit), []string{"->"}, pos)
+	} else if err := p.expectArrow(); err != nil {
+		return nil, err
+	}
+	ret, err := p.parseTypeAnnotation()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.FuncType{TypeParams: typeParams, Params: params, Return: ret}, nil
+}
+
+// parseTypeDeclaration parses `type Filter = func [A] (a A) -> bool`. The
+// TYPE keyword has already been consumed.
+func (p *Parser) parseTypeDeclaration() (ast.Expression, error) {
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.EQ {
+		return nil, newParseError(tokstr(tok, lit), []string{"="}, pos)
+	}
+	annotation, err := p.parseTypeAnnotation()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.TypeDeclaration{Name: name, Annotation: annotation}, nil
+}
+
+// parseTypeAnnotation parses a built-in type name, a unit, an array type or
+// a function type.
+func (p *Parser) parseTypeAnnotation() (*ast.TypeAnnotation, error) {
+	tok, _, lit := p.scanIgnoreWhitespace()
+	if name, ok := typeNames[tok]; ok {
+		return &ast.TypeAnnotation{Name: name}, nil
+	} else if tok == lexer.IDENT && lit == "bool" {
+		return &ast.TypeAnnotation{Name: "boolean", Alias: lit}, nil
+	} else if isFunctionKeyword(tok) {
+		// Declared types may share their name with a builtin, as in `Filter`
+		p.unscan()
+		name, _ := p.parseIdent()
+		return &ast.TypeAnnotation{Unit: &ast.UnitExpression{Terms: []ast.UnitTerm{{Symbol: name, Exp: 1}}}}, nil
+	} else if tok == FUNC {
+		fn, err := p.parseFunctionType()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.TypeAnnotation{Func: fn}, nil
+	} else if tok == lexer.LBRACKET {
+		if tok, pos, lit := p.scan(); tok != lexer.RBRACKET {
+			return nil, newParseError(tokstr(tok, lit), []string{"]"}, pos)
+		}
+		elem, err := p.parseTypeAnnotation()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.TypeAnnotation{Elem: elem}, nil
+	}
+	p.unscan()
+
+	unit, err := p.parseUnitExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.TypeAnnotation{Unit: unit}, nil
+}
+
+// expectArrow consumes the `>` of an arrow whose `-` has been consumed.
+func (p *Parser) expectArrow() error {
+	if tok, pos, lit := p.scan(); tok != lexer.GT {
+		return newParseError(tokstr(tok, lit), []string{"->"}, pos)
+	}
+	return nil
+}
+
+// parseBody parses a block or a single expression.
+func (p *Parser) parseBody() (ast.Expression, error) {
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == lexer.LBRACE {
+		return p.parseBlock()
+	}
+	p.unscan()
+	return p.parseExpression(lowestPrecedence)
+}
+
+// parseBlock parses statements separated by newlines or semicolons up to the
+// closing brace. The opening brace has already been consumed.
+func (p *Parser) parseBlock() (*ast.BlockExpression, error) {
+	block := &ast.BlockExpression{}
+	start := p.last().Start
+	defer func() { p.setSpan(block, start) }()
+	for {
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		switch tok {
+		case lexer.RBRACE:
+			return block, nil
+		case lexer.SEMICOLON:
+			continue
+		case lexer.EOF:
+			return nil, newParseError(tokstr(tok, lit), []string{"}"}, pos)
+		}
+		p.unscan()
+
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		block.Exprs = append(block.Exprs, stmt)
+
+		// Statements end at a line break, a semicolon or the closing brace
+		tok, pos, lit = p.scan()
+		if tok == lexer.WS {
+			if strings.Contains(lit, "\n") {
+				continue
+			}
+			tok, pos, lit = p.scan()
+		}
+		if tok == lexer.RBRACE {
+			return block, nil
+		} else if tok != lexer.SEMICOLON {
+			return nil, newParseError(tokstr(tok, lit), []string{";", "}"}, pos)
+		}
+	}
+}
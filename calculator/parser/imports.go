@@ -0,0 +1,23 @@
+package parser
+
+import (
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
+)
+
+// parseImportExpression parses `import "path/to/file.calc"` or
+// `import physics`. The IMPORT keyword has already been consumed.
+func (p *Parser) parseImportExpression() (ast.Expression, error) {
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	switch tok {
+	case lexer.STRING:
+		if lit == "" {
+			return nil, tokenError("Empty import path", tok, pos, lit)
+		}
+		return &ast.ImportExpression{Path: lit}, nil
+	case lexer.IDENT:
+		return &ast.ImportExpression{Name: lit}, nil
+	default:
+		return nil, newParseError(tokstr(tok, lit), []string{"module name", "path"}, pos)
+	}
+}
@@ -8,55 +8,6 @@ import (
 	"time"
 )
 
-func (p *Parser) parseLiteralExpression(tok lexer.Token, pos lexer.Pos, lit string) (ast.Expression, error) {
-	expr, err := p.parseLiteral(tok, pos, lit)
-	if err != nil {
-		return nil, err
-	}
-
-	// Scan for operators
-	tok, pos, lit = p.scanIgnoreWhitespace()
-	if ast.IsUnaryOperator(tok) {
-		return &ast.UnaryExpression{tok, expr}, nil
-	} else if ast.IsBinaryOperator(tok) {
-		return p.parseBinaryExpression(expr, tok, pos, lit)
-	} else {
-		p.unscan()
-	}
-	return expr, nil
-}
-
-func (p *Parser) parseBinaryExpression(lh ast.Expression, op lexer.Token, pos lexer.Pos, lit string) (ast.Expression, error) {
-	if !ast.IsBinaryOperator(op) {
-		return nil, tokenError("Invalid binary operator", op, pos, lit)
-	}
-
-	// Process right hand expression
-	expr, err := p.ParseExpression()
-	if err != nil {
-		return nil, err
-	}
-
-	// If literal or unary expression, set to right hand side.
-	if ast.IsLiteral(expr) || expr.Type() == ast.UnaryExpressionType {
-		return &ast.BinaryExpression{Op: op, LExpr: lh, RExpr: expr}, nil
-	} else if expr.Type() == ast.BinaryExpressionType {
-		return handleBinaryPrecedence(lh, op, expr.(*ast.BinaryExpression))
-	} else {
-		return nil, fmt.Errorf("Unsupported binary expression: %s", expr.String())
-	}
-}
-
-// Swap binary expressions based on precedence
-func handleBinaryPrecedence(lh ast.Expression, op lexer.Token, rh *ast.BinaryExpression) (ast.Expression, error) {
-	if op.Precedence() < rh.Precedence() {
-		return &ast.BinaryExpression{op, lh, rh}, nil
-	} else if op.Precedence() > rh.Precedence() {
-		return &ast.BinaryExpression{rh.Op, &ast.BinaryExpression{op, lh, rh.LExpr}, rh.RExpr}, nil
-	}
-	return &ast.BinaryExpression{op, lh, rh}, nil
-}
-
 func (p *Parser) parseLiteral(tok lexer.Token, pos lexer.Pos, lit string) (ast.Expression, error) {
 	switch tok {
 	case lexer.INTEGER:
@@ -80,31 +31,56 @@ func (p *Parser) parseLiteralInteger(tok lexer.Token, pos lexer.Pos, lit string)
 	if err != nil {
 		return nil, tokenError("Integer literal parse error", tok, pos, lit)
 	}
-	return &ast.IntegerLiteral{i}, nil
+	return &ast.IntegerLiteral{Value: i}, nil
 }
 
+// parseLiteralDecimal reads a decimal with enough precision to keep every
+// digit of the literal so that it can be rounded to any precision later.
 func (p *Parser) parseLiteralDecimal(tok lexer.Token, pos lexer.Pos, lit string) (ast.Expression, error) {
-	f := new(big.Float)
+	prec := uint(len(lit)) * 4
+	if prec < 64 {
+		prec = 64
+	}
+	f := new(big.Float).SetPrec(prec)
 	_, err := fmt.Sscan(lit, f)
 	if err != nil {
 		return nil, tokenError("Decimal literal parse error", tok, pos, lit)
 	}
-	return &ast.DecimalLiteral{f}, nil
+	return &ast.DecimalLiteral{Value: f}, nil
+}
+
+// parseImaginary returns an imaginary number for a numeric literal which is
+// directly followed by `i`, such as `3i` or `4.5i`. Whitespace in between
+// makes `i` a unit.
+func (p *Parser) parseImaginary(value ast.Expression) ast.Expression {
+	if tok, _, lit := p.scan(); tok != lexer.IDENT || lit != "i" {
+		p.unscan()
+		return value
+	}
+
+	var im *big.Float
+	switch v := value.(type) {
+	case *ast.IntegerLiteral:
+		im = new(big.Float).SetInt(v.Value)
+	case *ast.DecimalLiteral:
+		im = v.Value
+	}
+	return &ast.ComplexLiteral{Re: new(big.Float).SetPrec(im.Prec()), Im: im}
 }
 
 func (p *Parser) parseLiteralBoolean(tok lexer.Token, pos lexer.Pos, lit string) (ast.Expression, error) {
 	switch tok {
 	case lexer.TRUE:
-		return &ast.BooleanLiteral{true}, nil
+		return &ast.BooleanLiteral{Value: true}, nil
 	case lexer.FALSE:
-		return &ast.BooleanLiteral{false}, nil
+		return &ast.BooleanLiteral{Value: false}, nil
 	default:
 		return nil, tokenError("Invalid boolean literal", tok, pos, lit)
 	}
 }
 
 func (p *Parser) parseLiteralString(tok lexer.Token, pos lexer.Pos, lit string) (ast.Expression, error) {
-	return &ast.StringLiteral{lit}, nil
+	return &ast.StringLiteral{Value: lit}, nil
 }
 
 func (p *Parser) parseLiteralDuration(tok lexer.Token, pos lexer.Pos, lit string) (ast.Expression, error) {
@@ -112,5 +88,5 @@ func (p *Parser) parseLiteralDuration(tok lexer.Token, pos lexer.Pos, lit string
 	if err != nil {
 		return nil, tokenError("Invalid duration literal", tok, pos, lit)
 	}
-	return &ast.DurationLiteral{duration}, nil
+	return &ast.DurationLiteral{Value: duration}, nil
 }
@@ -0,0 +1,29 @@
+package parser
+
+import (
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
+)
+
+// parseForExpression parses `for x in iterable { ... }`. The FOR keyword at
+// pos has already been consumed.
+func (p *Parser) parseForExpression(pos lexer.Pos) (ast.Expression, error) {
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.IDENT || lit != "in" {
+		return nil, newParseError(tokstr(tok, lit), []string{"in"}, pos)
+	}
+
+	iterable, err := p.parseHeader()
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.parseBraceBlock()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.ForExpression{Name: name, Iterable: iterable, Body: body}, nil
+}
@@ -1,7 +1,9 @@
 package parser
 
 import (
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"errors"
 	"github.com/eliquious/aechbar/calculator/ast"
@@ -15,8 +17,27 @@ var EOF = errors.New("End of Input")
 // Parser represents an InfluxQL parser.
 type Parser struct {
 	s *lexer.TokenBuffer
+
+	// noQuantity disables attaching units to numeric literals
+	noQuantity bool
+
+	// noStructLiteral disables struct literals so that the brace following
+	// the condition of an if or the iterable of a for starts the block
+	noStructLiteral bool
+
+	// noLambda disables lambdas so that the arrow following a grouped
+	// pattern of a match starts the body of the arm
+	noLambda bool
+
+	// scanned holds the spans of the most recently scanned tokens so that
+	// the span of an expression can end after its last token
+	scanned []ast.Span
 }
 
+// maxScanned is the number of token spans kept, which must exceed the
+// number of tokens the buffer can unscan.
+const maxScanned = 8
+
 // NewParser returns a new instance of Parser.
 func NewParser(r io.Reader) *Parser {
 	return &Parser{s: lexer.NewTokenBuffer(r)}
@@ -30,59 +51,127 @@ func ParseExpression(s string) (ast.Expression, error) {
 func (p *Parser) ParseExpression() (ast.Expression, error) {
 
 	// Inspect the first token.
-	tok, pos, lit := p.scanIgnoreWhitespace()
+	tok, _, _ := p.scanIgnoreWhitespace()
 	switch tok {
-	// case VAR, LET, CONST:
-	// 	return p.parseAssignment(tok)
-	// case FUNC:
-	// 	return p.parseFunctionDeclaration()
-	// case STRUCT:
-	// 	return p.parseStructDeclaration()
-	// case UNIT:
-	// 	return p.parseUnitDeclaration()
-	// case IF:
-	// 	return p.parseIfExpression()
-	// case ELSEIF:
-	// 	return p.parseElseIfExpression()
-	// case ELSE:
-	// 	return p.parseElseExpression()
-	// case IMPORT:
-	// 	return p.parseImportExpression()
-	// case FOR:
-	// 	return p.parseForExpression()
-	case lexer.INTEGER, lexer.DECIMAL, lexer.STRING, lexer.TRUE, lexer.FALSE, lexer.DURATION:
-		return p.parseLiteralExpression(tok, pos, lit)
-	case lexer.PLUS, lexer.MINUS:
-		tok2, _, lit2 := p.scanIgnoreWhitespace()
-		if tok2 == lexer.INTEGER {
-			return p.parseLiteralInteger(tok2, pos, lit+lit2)
-		} else if tok2 == lexer.DECIMAL {
-			return p.parseLiteralDecimal(tok2, pos, lit+lit2)
-		}
-		p.unscan()
-		return nil, tokenError("Invalid input", tok, pos, lit)
-
-	// case lexer.IDENT:
-	// 	return p.parseIdentExpression()
-	// case lexer.LPAREN:
-	// 	return p.parseParenExpression()
-	// case lexer.LBRACKET:
-	// 	return p.parseArrayExpression()
 	case lexer.SEMICOLON:
 		return nil, EOL
 	case lexer.EOF:
 		return nil, EOF
 	default:
-		return nil, tokenError("Unrecognized input error", tok, pos, lit)
-		// return nil, newParseError(tokstr(tok, lit), []string{"USE", "CREATE", "SHOW", "DROP"}, pos)
+		p.unscan()
+	}
+
+	expr, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	} else if err := p.parseStatementEnd(); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// parseStatementEnd ensures a statement is followed by a line break, a
+// semicolon or the end of the input so that `1 2` is not read as two
+// statements.
+func (p *Parser) parseStatementEnd() error {
+	tok, pos, lit := p.scanOperator()
+	switch {
+	case tok == lexer.WS:
+		return nil
+	case tok == lexer.SEMICOLON, tok == lexer.EOF:
+		p.unscan()
+		return nil
+	}
+	return newParseError(tokstr(tok, lit), []string{";", "newline"}, pos)
+}
+
+// parseStatement parses a declaration or an expression.
+func (p *Parser) parseStatement() (ast.Expression, error) {
+	tok, pos, _ := p.scanIgnoreWhitespace()
+	expr, err := p.parseStatementToken(tok)
+	if err != nil {
+		return nil, err
+	}
+	p.setSpan(expr, pos)
+	return expr, nil
+}
+
+// parseStatementToken parses the statement starting with the token.
+func (p *Parser) parseStatementToken(tok lexer.Token) (ast.Expression, error) {
+	switch tok {
+	case VAR, LET, CONST:
+		return p.parseDeclaration(tok)
+	case FUNC:
+		return p.parseFunctionDeclaration()
+	case STRUCT:
+		return p.parseStructDeclaration()
+	case ENUM:
+		return p.parseEnumDeclaration()
+	case TYPE:
+		return p.parseTypeDeclaration()
+	case UNIT:
+		return p.parseUnitDeclaration()
+	case CONVERSION:
+		return p.parseConversionDeclaration()
+	case IMPORT:
+		return p.parseImportExpression()
+	default:
+		p.unscan()
+		return p.parseExpression(lowestPrecedence)
 	}
 }
 
 // scan returns the next token from the underlying scanner.
-func (p *Parser) scan() (tok lexer.Token, pos lexer.Pos, lit string) { return p.s.Scan() }
+func (p *Parser) scan() (tok lexer.Token, pos lexer.Pos, lit string) {
+	tok, pos, lit = p.s.Scan()
+	if len(p.scanned) == 2*maxScanned {
+		p.scanned = append(p.scanned[:0], p.scanned[maxScanned:]...)
+	}
+
+	var span ast.Span
+	if tok != lexer.WS && tok != lexer.EOF {
+		span = tokenSpan(tok, pos, lit)
+	}
+	p.scanned = append(p.scanned, span)
+	return
+}
 
 // unscan pushes the previously read token back onto the buffer.
-func (p *Parser) unscan() { p.s.Unscan() }
+func (p *Parser) unscan() {
+	if len(p.scanned) > 0 {
+		p.scanned = p.scanned[:len(p.scanned)-1]
+	}
+	p.s.Unscan()
+}
+
+// last returns the span of the last token read which is not whitespace.
+func (p *Parser) last() ast.Span {
+	for i := len(p.scanned) - 1; i >= 0; i-- {
+		if p.scanned[i].IsValid() {
+			return p.scanned[i]
+		}
+	}
+	return ast.Span{}
+}
+
+// setSpan records that the expression starts at the position and ends with
+// the last token read.
+func (p *Parser) setSpan(expr ast.Expression, start lexer.Pos) {
+	if node, ok := expr.(interface{ SetPosition(start, end lexer.Pos) }); ok {
+		node.SetPosition(start, p.last().End)
+	}
+}
+
+// tokenSpan returns the span of the token's text. Strings are measured as
+// quoted literals.
+func tokenSpan(tok lexer.Token, pos lexer.Pos, lit string) ast.Span {
+	text := tokstr(tok, lit)
+	if tok == lexer.STRING {
+		text = strconv.Quote(lit)
+	}
+	end := lexer.Pos{Line: pos.Line, Char: pos.Char + utf8.RuneCountInString(text)}
+	return ast.Span{Start: pos, End: end}
+}
 
 // // peekRune returns the next rune that would be read by the scanner.
 // func (p *Parser) peekRune() rune { return p.s.s.Peek() }
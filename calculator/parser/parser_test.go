@@ -2,9 +2,503 @@ package parser
 
 import (
 	// "github.com/stretchr/testify/assert"
+	"fmt"
+	"strings"
 	"testing"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
 )
 
 func TestParserExpressions(t *testing.T) {
 	ParseExpression("1+5")
 }
+
+// assertParse parses each input and compares the String() of the resulting
+// expression.
+func assertParse(t *testing.T, tests []struct{ input, output string }) {
+	t.Helper()
+	for _, test := range tests {
+		expr, err := ParseExpression(test.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", test.input, err)
+			continue
+		}
+		if expr.String() != test.output {
+			t.Errorf("%q: expected %s, got %s", test.input, test.output, expr.String())
+		}
+	}
+}
+
+func TestParserPrecedence(t *testing.T) {
+	assertParse(t, []struct{ input, output string }{
+		// Literals and signs
+		{"1", "1"},
+		{"-1", "-1"},
+		{"+1", "1"},
+		{"1.5", "1.5000000000000000E+00"},
+		{"-1.5", "-1.5000000000000000E+00"},
+		{"a", "a"},
+		{"-a", "-a"},
+		{"- a", "-a"},
+		{"- -1", "1"},
+
+		// Associativity
+		{"1 + 2", "(1 + 2)"},
+		{"1 - 2 - 3", "((1 - 2) - 3)"},
+		{"1 - 2 - 3 - 4", "(((1 - 2) - 3) - 4)"},
+		{"1 + 2 - 3 + 4", "(((1 + 2) - 3) + 4)"},
+		{"8 / 4 / 2", "((8 / 4) / 2)"},
+		{"8 / 4 * 2", "((8 / 4) * 2)"},
+		{"2 ** 3 ** 2", "(2 ** (3 ** 2))"},
+		{"2 ** 3 ** 2 ** 1", "(2 ** (3 ** (2 ** 1)))"},
+		{"a = b = 3", "a = b = 3"},
+
+		// Precedence
+		{"1 + 2 * 3", "(1 + (2 * 3))"},
+		{"1 * 2 + 3", "((1 * 2) + 3)"},
+		{"2 * 3 + 4 * 5", "((2 * 3) + (4 * 5))"},
+		{"2 * 3 + 4 * 5 - 6 / 2", "(((2 * 3) + (4 * 5)) - (6 / 2))"},
+		{"1 + 2 * 3 ** 2", "(1 + (2 * (3 ** 2)))"},
+		{"2 ** 3 * 4", "((2 ** 3) * 4)"},
+		{"4 * 2 ** 3", "(4 * (2 ** 3))"},
+		{"1 + 2 * 3 - 4 / 5 ** 6", "((1 + (2 * 3)) - (4 / (5 ** 6)))"},
+		{"1 < 2 + 3", "(1 < (2 + 3))"},
+		{"1 + 2 == 3", "((1 + 2) == 3)"},
+		{"a * b / c * d", "(((a * b) / c) * d)"},
+		{"a + b * c + d", "((a + (b * c)) + d)"},
+		{"a * b + c * d + e * f", "(((a * b) + (c * d)) + (e * f))"},
+
+		// Prefix operators
+		{"-2 ** 2", "-(2 ** 2)"},
+		{"-2 * 3", "(-2 * 3)"},
+		{"-a * b", "(-a * b)"},
+		{"-a ** b", "-(a ** b)"},
+		{"2 ** -1", "(2 ** -1)"},
+		{"1 - -1", "(1 - -1)"},
+		{"a - -b", "(a - -b)"},
+		{"-(1 + 2)", "-((1 + 2))"},
+		{"-(1 + 2) * 3", "(-((1 + 2)) * 3)"},
+
+		// Postfix operators
+		{"a++", "a++"},
+		{"a--", "a--"},
+		{"a++ + 1", "(a++ + 1)"},
+		{"1 + a++", "(1 + a++)"},
+		{"2 * a--", "(2 * a--)"},
+		{"-a++", "-a++"},
+
+		// Bitwise complement
+		{"^a", "^a"},
+		{"^a & b", "(^a & b)"},
+		{"a ^ ^b", "(a ^ ^b)"},
+
+		// Calls
+		{"f()", "f()"},
+		{"f(1, 2)", "f(1, 2)"},
+		{"f(a + 1) * 2", "(f((a + 1)) * 2)"},
+		{"-f(x) ** 2", "-(f(x) ** 2)"},
+		{"f(g(x), 1)", "f(g(x), 1)"},
+		{"f(x)(y)", "f(x)(y)"},
+
+		// Grouping
+		{"(1)", "(1)"},
+		{"(1 + 2)", "((1 + 2))"},
+		{"(1 + 2) * 3", "(((1 + 2)) * 3)"},
+		{"3 * (1 + 2)", "(3 * ((1 + 2)))"},
+		{"(2 ** 3) ** 2", "(((2 ** 3)) ** 2)"},
+		{"1 - (2 - 3)", "(1 - ((2 - 3)))"},
+		{"((1))", "((1))"},
+
+		// Assignment and declarations
+		{"a = 1 + 2", "a = (1 + 2)"},
+		{"a = b or c", "a = (b OR c)"},
+		{"a = b = 1 + 2", "a = b = (1 + 2)"},
+		{"let a = 1 + 2 * 3", "let a = (1 + (2 * 3))"},
+		{"var x = a * 2", "var x = (a * 2)"},
+		{"const G = 6.674E-11", "const G = 6.6740000000000000E-11"},
+
+		// Line breaks end expressions unless the line ends with an operator
+		{"1 +\n2", "(1 + 2)"},
+		{"1\n+ 2", "1"},
+	})
+
+	// Assignment binds loosest so its target cannot be an operand
+	for _, input := range []string{"2 * a = 3 + 1", "1 + a = 5", "-a = 1", "(a) = 1"} {
+		if expr, err := ParseExpression(input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, expr)
+		}
+	}
+}
+
+// precedenceLevels lists the binary operators from loosest to tightest.
+var precedenceLevels = [][]lexer.Token{
+	{lexer.OR},
+	{lexer.AND},
+	{lexer.EQEQ, lexer.NEQ, lexer.LT, lexer.LTE, lexer.GT, lexer.GTE},
+	{lexer.PLUS, lexer.MINUS, lexer.PIPE, lexer.XOR},
+	{lexer.MUL, lexer.DIV, lexer.LSHIFT, lexer.RSHIFT, lexer.AMPERSAND},
+	{lexer.POW},
+}
+
+// TestParserOperatorPairs checks the grouping of `a op1 b op2 c` for every
+// pair of binary operators.
+func TestParserOperatorPairs(t *testing.T) {
+	level := make(map[lexer.Token]int)
+	for i, ops := range precedenceLevels {
+		for _, op := range ops {
+			level[op] = i
+		}
+	}
+
+	var tests []struct{ input, output string }
+	for op1, l1 := range level {
+		for op2, l2 := range level {
+			input := fmt.Sprintf("a %s b %s c", op1, op2)
+			output := fmt.Sprintf("((a %s b) %s c)", op1, op2)
+			if l2 > l1 || (l1 == l2 && op1 == lexer.POW) {
+				output = fmt.Sprintf("(a %s (b %s c))", op1, op2)
+			}
+			tests = append(tests, struct{ input, output string }{input, output})
+
+			// Grouping overrides precedence
+			input = fmt.Sprintf("(a %s b) %s c", op1, op2)
+			output = fmt.Sprintf("(((a %s b)) %s c)", op1, op2)
+			tests = append(tests, struct{ input, output string }{input, output})
+		}
+	}
+	assertParse(t, tests)
+}
+
+func TestParserErrors(t *testing.T) {
+	for _, input := range []string{
+		"1 +",
+		"(1 + 2",
+		"1 + * 2",
+		"let = 5",
+		"let a 5",
+		")",
+		"--1",
+		"f(1,",
+		"f(1 2)",
+		"1(2)",
+		"1 2",
+		"f(1) 2",
+	} {
+		if expr, err := ParseExpression(input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, expr)
+		}
+	}
+}
+
+func TestParserUnits(t *testing.T) {
+	assertParse(t, []struct{ input, output string }{
+		{"5 kg", "5 kg"},
+		{"-5 kg", "-5 kg"},
+		{"9.81 m/s^2", "9.8100000000000000E+00 m/s^2"},
+		{"2 kg*m**2/s^2", "2 kg*m^2/s^2"},
+		{"5 kg*2", "(5 kg * 2)"},
+		{"5 kg/2", "(5 kg / 2)"},
+		{"5 kg*m/2", "(5 kg*m / 2)"},
+		{"10 m / t", "(10 m / t)"},
+		{"2 m * 3 m", "(2 m * 3 m)"},
+		{"6.674E-11 (m^3 * kg^-1 * s^-2)", "6.6740000000000000E-11 m^3/kg/s^2"},
+		{"1 (1 / s)", "1 1/s"},
+		{"1 m to ft", "(1 m to ft)"},
+		{"1 m + 2 ft to in", "((1 m + 2 ft) to in)"},
+		{"x to km / h", "(x to km/h)"},
+		{"unit Meter (m)", "unit Meter (m)"},
+		{"unit Newton (N) { 1 = 1 kg * m / s^2 }", "unit Newton (N) { 1 = 1 kg*m/s^2 }"},
+		{"unit Foot (ft) {\n  1 = 12 in\n  3 = 1 yd\n}", "unit Foot (ft) { 1 = 12 in; 3 = 1 yd }"},
+		{"unit Inch (in) { 1 = 1/12 ft }", "unit Inch (in) { 1 = (1 / 12) ft }"},
+		{"conversion 1 m = 3.28084 ft", "conversion 1 m = 3.2808400000000000E+00 ft"},
+		{"3i", "3.0000000000000000E+00i"},
+		{"-4.5i ohm", "-4.5000000000000000E+00i ohm"},
+		{"2 + 1i", "(2 + 1.0000000000000000E+00i)"},
+		{"3 i", "3 i"},
+	})
+
+	for _, input := range []string{
+		"5 m^",
+		"5 m/",
+		"5 (m",
+		"1 m to",
+		"unit (m)",
+		"unit Meter m",
+		"unit Meter (m) { 1 = 100 }",
+		"conversion 1 m 3.28 ft",
+	} {
+		if expr, err := ParseExpression(input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, expr)
+		}
+	}
+}
+
+func TestParserFunctions(t *testing.T) {
+	assertParse(t, []struct{ input, output string }{
+		{"func in2m(x float) -> x * 0.0254", "func in2m(x float) -> (x * 2.5400000000000000E-02)"},
+		{"func f() -> 1", "func f() -> 1"},
+		{"func add(a, b) -> a + b", "func add(a, b) -> (a + b)"},
+		{"func speed(d m, t s) m/s = d / t", "func speed(d m, t s) m/s = (d / t)"},
+		{"func fNewton (M kg, m kg, r m) N = {\n\tG * m * M / r**2\n}", "func fNewton(M kg, m kg, r m) N = { (((G * m) * M) / (r ** 2)) }"},
+		{"func f(x int) int = { let y = x * 2; y + 1 }", "func f(x int) int = { let y = (x * 2); (y + 1) }"},
+		{"func f(v (m / s)) -> {\n\tlet a = v\n\n\ta * 2\n}", "func f(v m/s) -> { let a = v; (a * 2) }"},
+		{"in2m(12)", "in2m(12)"},
+	})
+
+	for _, input := range []string{
+		"func (x) -> x",
+		"func f x -> x",
+		"func f(x -> x",
+		"func f(x) x",
+		"func f(x) - x",
+		"func f(x) = { x",
+		"func f(x) = { x x }",
+		"func f(x, ) -> x",
+	} {
+		if expr, err := ParseExpression(input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, expr)
+		}
+	}
+}
+
+func TestParserConditionals(t *testing.T) {
+	assertParse(t, []struct{ input, output string }{
+		{"if a { 1 }", "if a { 1 }"},
+		{"if a > 1 { 1 } else { 2 }", "if (a > 1) { 1 } else { 2 }"},
+		{"if a { 1 } else if b { 2 } else { 3 }", "if a { 1 } else if b { 2 } else { 3 }"},
+		{"if a { 1 } elseif b { 2 }", "if a { 1 } else if b { 2 }"},
+		{"if Vs/Vo > 0 {\n\t- fN\n} else Vs/Vo < 0 {\n\tfN\n} else {\n\t0\n}", "if ((Vs / Vo) > 0) { -fN } else if ((Vs / Vo) < 0) { fN } else { 0 }"},
+		{"var x = if a { 1 } else { 2 }", "var x = if a { 1 } else { 2 }"},
+		{"1 + if a { 1 } else { 2 }", "(1 + if a { 1 } else { 2 })"},
+	})
+
+	for _, input := range []string{
+		"if a",
+		"if a 1",
+		"if a { 1 } else",
+		"if a { 1 } else b",
+		"if { 1 }",
+		"else { 1 }",
+	} {
+		if expr, err := ParseExpression(input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, expr)
+		}
+	}
+}
+
+func TestParserLoops(t *testing.T) {
+	assertParse(t, []struct{ input, output string }{
+		{"for i in range(5) { i**2 }", "for i in range(5) { (i ** 2) }"},
+		{"var squares = for i in range(5) {\n\ti**2\n}", "var squares = for i in range(5) { (i ** 2) }"},
+		{"for i in xs { for j in ys { i * j } }", "for i in xs { for j in ys { (i * j) } }"},
+		{"for c in \"abc\" { c }", "for c in \"abc\" { c }"},
+	})
+
+	for _, input := range []string{
+		"for in xs { 1 }",
+		"for i xs { 1 }",
+		"for i in xs",
+		"for i in { 1 }",
+	} {
+		if expr, err := ParseExpression(input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, expr)
+		}
+	}
+}
+
+func TestParserArrays(t *testing.T) {
+	assertParse(t, []struct{ input, output string }{
+		{"[]", "[]"},
+		{"[1, 2, 3]", "[1, 2, 3]"},
+		{"[1, [2, 3]]", "[1, [2, 3]]"},
+		{"[\n\t1,\n\t2\n]", "[1, 2]"},
+		{"[1, 2] * 2", "([1, 2] * 2)"},
+		{"a[0]", "a[0]"},
+		{"a[-1]", "a[-1]"},
+		{"a[1:2]", "a[1:2]"},
+		{"a[:2]", "a[:2]"},
+		{"a[1:]", "a[1:]"},
+		{"a[:]", "a[:]"},
+		{"a[i + 1][0]", "a[(i + 1)][0]"},
+		{"-a[0]", "-a[0]"},
+		{"a[0] ** 2", "(a[0] ** 2)"},
+		{"f(x)[0]", "f(x)[0]"},
+		{"len(a)", "len(a)"},
+		{"[1, 2; 3, 4]", "[1, 2; 3, 4]"},
+		{"[1; 2]", "[1; 2]"},
+		{"[a + 1, -b;\n c, d]", "[(a + 1), -b; c, d]"},
+		{"[1, 2; 3, 4] * [1, 1]", "([1, 2; 3, 4] * [1, 1])"},
+	})
+
+	for _, input := range []string{"[1, 2", "[1 2]", "a[", "a[1", "a[1:2", "a[1 2]", "[,]", "[1, 2; 3]", "[1, 2;]", "[;]", "[1; 2"} {
+		if expr, err := ParseExpression(input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, expr)
+		}
+	}
+}
+
+func TestParserLambdas(t *testing.T) {
+	assertParse(t, []struct{ input, output string }{
+		{"(a) -> a > 5", "(a) -> (a > 5)"},
+		{"(a, i) -> { a > 5 }", "(a, i) -> { (a > 5) }"},
+		{"() -> 1", "() -> 1"},
+		{"(a) - 1", "((a) - 1)"},
+		{"array.filter((a, i) -> { a > 5 })", "array.filter((a, i) -> { (a > 5) })"},
+		{"array.LEN()", "array.LEN()"},
+		{"a.map(f).sum()", "a.map(f).sum()"},
+		{"x[0].abs()", "x[0].abs()"},
+	})
+
+	for _, input := range []string{"(1) -> 1", "(a + b) -> a", "(a, b)", "()", "a.", "a.1()"} {
+		if expr, err := ParseExpression(input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, expr)
+		}
+	}
+
+	// Errors point at the parentheses or the invalid parameter
+	for _, test := range []struct{ input, position string }{
+		{"1 + (1, 2)", "at (1, 5)"},
+		{"(a, 2) -> a", "at (1, 5)"},
+	} {
+		if _, err := ParseExpression(test.input); err == nil || !strings.HasSuffix(err.Error(), test.position) {
+			t.Errorf("%q: expected error %s, got %v", test.input, test.position, err)
+		}
+	}
+}
+
+func TestParserStructs(t *testing.T) {
+	assertParse(t, []struct{ input, output string }{
+		{"struct Planet = { Name string; Mass (kg); Radius (m) }", "struct Planet = { Name string; Mass kg; Radius m }"},
+		{"struct Planet = {\n\tName string\n\tMass (kg)\n}", "struct Planet = { Name string; Mass kg }"},
+		{"struct SolarSystem = { Name string; Planets []Planet }", "struct SolarSystem = { Name string; Planets []Planet }"},
+		{"struct Empty = {}", "struct Empty = {  }"},
+		{`Planet{Name: "Earth", Mass: 5.972E24}`, `Planet{Name: "Earth", Mass: 5.9720000000000000E+24}`},
+		{"Planet{\n\tName: \"Earth\",\n\tRadius: 6371 km,\n}", `Planet{Name: "Earth", Radius: 6371 km}`},
+		{"Planet{}", "Planet{}"},
+		{"earth.Mass", "earth.Mass"},
+		{"sol.Planets[0].Name", "sol.Planets[0].Name"},
+		{"earth.Mass * 2", "(earth.Mass * 2)"},
+		{"if ok { 1 }", "if ok { 1 }"},
+		{"for f in earth { f }", "for f in earth { f }"},
+		{"for f in (Planet{}) { f }", "for f in (Planet{}) { f }"},
+		{"for p in [P{X: 1}, P{X: 2}] { p.X }", "for p in [P{X: 1}, P{X: 2}] { p.X }"},
+		{"if [P{X: 1}][0].X == 1 { 1 }", "if ([P{X: 1}][0].X == 1) { 1 }"},
+		{"if xs[P{X: 1}.X] { 1 }", "if xs[P{X: 1}.X] { 1 }"},
+		{"if f(P{}) { 1 }", "if f(P{}) { 1 }"},
+	})
+
+	for _, input := range []string{"struct = {}", "struct P { A int }", "struct P = { A }", "P{A 1}", "P{A: 1 B: 2}", "P{1}", "a.len"} {
+		if expr, err := ParseExpression(input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, expr)
+		}
+	}
+}
+
+func TestParserEnums(t *testing.T) {
+	assertParse(t, []struct{ input, output string }{
+		{"enum Level = { LOW MEDIUM HIGH }", "enum Level = { LOW MEDIUM HIGH }"},
+		{"enum Level = {\n\tLOW\n\tMEDIUM\n\tHIGH\n}", "enum Level = { LOW MEDIUM HIGH }"},
+		{"enum Level = { LOW, MEDIUM; HIGH }", "enum Level = { LOW MEDIUM HIGH }"},
+		{"Level.LOW < Level.HIGH", "(Level.LOW < Level.HIGH)"},
+		{"match l { Level.LOW -> 1; Level.MEDIUM, Level.HIGH -> 2 }", "match l { Level.LOW -> 1; Level.MEDIUM, Level.HIGH -> 2 }"},
+		{"match l {\n\tLevel.LOW -> { 1 }\n\telse -> -1\n}", "match l { Level.LOW -> { 1 }; else -> -1 }"},
+		{"match alarm.Level { Level.LOW->0 }", "match alarm.Level { Level.LOW -> 0 }"},
+		{"match x { (1 + 1) -> P{}; else -> 0 }", "match x { ((1 + 1)) -> P{}; else -> 0 }"},
+	})
+
+	for _, input := range []string{
+		"enum Level = {}", "enum Level { LOW }", "enum = { LOW }", "enum Level = { 1 }", "enum Level = { LOW",
+		"match l {}", "match l { Level.LOW }", "match l { Level.LOW -> 1 Level.HIGH -> 2 }", "match l { else -> 1; Level.LOW -> 2 }", "match l { Level.LOW -> 1",
+	} {
+		if expr, err := ParseExpression(input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, expr)
+		}
+	}
+}
+
+func TestParserImports(t *testing.T) {
+	assertParse(t, []struct{ input, output string }{
+		{`import "path/to/file.calc"`, `import "path/to/file.calc"`},
+		{"import physics", "import physics"},
+		{"IMPORT physics", "import physics"},
+		{"physics.G * 2", "(physics.G * 2)"},
+		{"physics.force(1 kg)", "physics.force(1 kg)"},
+	})
+
+	for _, input := range []string{"import", `import ""`, "import 5", "import (physics)"} {
+		if expr, err := ParseExpression(input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, expr)
+		}
+	}
+}
+
+func TestParserGenerics(t *testing.T) {
+	assertParse(t, []struct{ input, output string }{
+		{"func pair<A, B>(a A, b B) A = a", "func pair<A, B>(a A, b B) A = a"},
+		{"func first[A](xs []A) A -> xs[0]", "func first<A>(xs []A) A = xs[0]"},
+		{"func keep<A>(xs []A, f Filter) []A -> filter(xs, f)", "func keep<A>(xs []A, f Filter) []A = filter(xs, f)"},
+		{"type Filter = func [A] (a A, i int) -> bool", "type Filter = func [A] (a A, i int) -> bool"},
+		{"var Len = 5", "var Len = 5"},
+		{"type Pred = func <A> (a A) -> boolean", "type Pred = func [A] (a A) -> boolean"},
+		{"type Unary = func (x float) -> float", "type Unary = func (x float) -> float"},
+		{"type Length = (m)", "type Length = m"},
+		{"type Names = []string", "type Names = []string"},
+		{"func apply(f func (x float) -> float, x float) -> f(x)", "func apply(f func (x float) -> float, x float) -> f(x)"},
+	})
+
+	for _, input := range []string{"func f<>(a) -> a", "func f<A(a A) -> a", "func f<A,>(a A) -> a", "type = int", "type F int", "type F = func (a A) bool", "type F = func (a A) ->"} {
+		if expr, err := ParseExpression(input); err == nil {
+			t.Errorf("%q: expected error, got %s", input, expr)
+		}
+	}
+}
+
+func TestParserSpans(t *testing.T) {
+	span := func(startLine, startChar, endLine, endChar int) ast.Span {
+		return ast.Span{Start: lexer.Pos{Line: startLine, Char: startChar}, End: lexer.Pos{Line: endLine, Char: endChar}}
+	}
+
+	expr, err := ParseExpression(`1 + foo("ab", -x)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := expr.(*ast.BinaryExpression)
+	call := binary.RExpr.(*ast.CallExpression)
+	for _, test := range []struct {
+		expr ast.Expression
+		span ast.Span
+	}{
+		{binary, span(0, 0, 0, 17)},
+		{binary.LExpr, span(0, 0, 0, 1)},
+		{call, span(0, 4, 0, 17)},
+		{call.Function, span(0, 4, 0, 7)},
+		{call.Args[0], span(0, 8, 0, 12)},
+		{call.Args[1], span(0, 14, 0, 16)},
+	} {
+		if test.expr.Position() != test.span {
+			t.Errorf("%s: expected span %v, got %v", test.expr, test.span, test.expr.Position())
+		}
+	}
+
+	expr, err = ParseExpression("var x = if y {\n  1 m\n} else {\n  2 m\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decl := expr.(*ast.VariableDeclaration)
+	cond := decl.Value.(*ast.IfExpression)
+	for _, test := range []struct {
+		expr ast.Expression
+		span ast.Span
+	}{
+		{decl, span(0, 0, 4, 1)},
+		{cond, span(0, 8, 4, 1)},
+		{cond.Body, span(0, 13, 2, 1)},
+		{cond.Body.Exprs[0], span(1, 2, 1, 5)},
+		{cond.Else, span(2, 2, 4, 1)},
+	} {
+		if test.expr.Position() != test.span {
+			t.Errorf("%s: expected span %v, got %v", test.expr, test.span, test.expr.Position())
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
+)
+
+// parseStructDeclaration parses `struct Planet = { Name string; Mass (kg) }`.
+// Fields are separated by newlines, semicolons or commas. The STRUCT keyword
+// has already been consumed.
+func (p *Parser) parseStructDeclaration() (ast.Expression, error) {
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.EQ {
+		return nil, newParseError(tokstr(tok, lit), []string{"="}, pos)
+	}
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.LBRACE {
+		return nil, newParseError(tokstr(tok, lit), []string{"{"}, pos)
+	}
+
+	decl := &ast.StructDeclaration{Name: name}
+	for {
+		tok, _, _ := p.scanIgnoreWhitespace()
+		if tok == lexer.RBRACE {
+			return decl, nil
+		} else if tok == lexer.SEMICOLON || tok == lexer.COMMA {
+			continue
+		}
+		p.unscan()
+
+		field, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		annotation, err := p.parseTypeAnnotation()
+		if err != nil {
+			return nil, err
+		}
+		decl.Fields = append(decl.Fields, &ast.StructField{Name: field, Annotation: annotation})
+	}
+}
+
+// parseStructExpression parses the keyed fields of a struct literal,
+// `Planet{Name: "Earth", Mass: 5.972E24}`. The opening brace has already
+// been consumed.
+func (p *Parser) parseStructExpression(name string, pos lexer.Pos) (ast.Expression, error) {
+	expr := &ast.StructExpression{Name: name}
+	for {
+		tok, _, _ := p.scanIgnoreWhitespace()
+		if tok == lexer.RBRACE {
+			return expr, nil
+		}
+		p.unscan()
+
+		field, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.COLON {
+			return nil, newParseError(tokstr(tok, lit), []string{":"}, pos)
+		}
+		value, err := p.parseExpression(lowestPrecedence)
+		if err != nil {
+			return nil, err
+		}
+		expr.Fields = append(expr.Fields, &ast.FieldValue{Name: field, Value: value})
+
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		if tok == lexer.RBRACE {
+			return expr, nil
+		} else if tok != lexer.COMMA {
+			return nil, newParseError(tokstr(tok, lit), []string{",", "}"}, pos)
+		}
+	}
+}
@@ -28,6 +28,7 @@ const (
 	CONST
 	STRUCT
 	ENUM
+	TYPE
 
 	// Units
 	UNIT
@@ -41,6 +42,7 @@ const (
 	FOR
 	FILTER
 	IMPORT
+	MATCH
 
 	endKeywords
 
@@ -73,6 +75,8 @@ var keywords = map[lexer.Token]string{
 	LET:    "LET",
 	CONST:  "CONST",
 	STRUCT: "STRUCT",
+	IMPORT: "IMPORT",
+	TYPE:   "TYPE",
 
 	// Units
 	UNIT:       "UNIT",
@@ -82,9 +86,11 @@ var keywords = map[lexer.Token]string{
 	// Expressions
 	IF:     "IF",
 	ELSE:   "ELSE",
+	ELSEIF: "ELSEIF",
 	FOR:    "FOR",
 	FILTER: "FILTER",
 	ENUM:   "ENUM",
+	MATCH:  "MATCH",
 
 	// Functions
 	APPEND: "APPEND",
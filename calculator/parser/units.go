@@ -0,0 +1,270 @@
+package parser
+
+import (
+	"strconv"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/lexer"
+)
+
+// parseQuantity parses a unit following a numeric literal. Units written
+// without spaces around their operators belong to the quantity, so
+// `9.81 m/s^2` is a single quantity while `10 m / t` divides by `t`.
+// Parenthesized units may contain spaces: `6.674E-11 (m^3 * kg^-1 * s^-2)`.
+func (p *Parser) parseQuantity(value ast.Expression) (ast.Expression, error) {
+	if p.noQuantity {
+		return value, nil
+	}
+
+	tok, _, _ := p.scanOperator()
+	switch tok {
+	case lexer.IDENT:
+		p.unscan()
+		unit, err := p.parseCompactUnit()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.QuantityExpression{Value: value, Unit: unit}, nil
+	case lexer.LPAREN:
+		unit, err := p.parseUnitExpression()
+		if err != nil {
+			return nil, err
+		}
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.RPAREN {
+			return nil, newParseError(tokstr(tok, lit), []string{")"}, pos)
+		}
+		return &ast.QuantityExpression{Value: value, Unit: unit}, nil
+	default:
+		p.unscan()
+		return value, nil
+	}
+}
+
+// parseCompactUnit parses a unit whose operators are not separated by
+// whitespace, such as `kg*m/s^2`.
+func (p *Parser) parseCompactUnit() (*ast.UnitExpression, error) {
+	unit := &ast.UnitExpression{}
+	sign := 1
+	for {
+		tok, pos, lit := p.scan()
+		if tok != lexer.IDENT {
+			return nil, newParseError(tokstr(tok, lit), []string{"unit"}, pos)
+		} else if len(unit.Terms) == 0 {
+			unit.Start = pos
+		}
+
+		exp, err := p.parseUnitExponent(p.scan)
+		if err != nil {
+			return nil, err
+		}
+		unit.Terms = append(unit.Terms, ast.UnitTerm{Symbol: lit, Exp: sign * exp})
+
+		// An operator belongs to the unit only when another unit follows,
+		// so `5 kg*2` multiplies the quantity by 2
+		op, _, _ := p.scan()
+		if op == lexer.MUL || op == lexer.DIV {
+			next, _, _ := p.scan()
+			p.unscan()
+			if next == lexer.IDENT {
+				sign = 1
+				if op == lexer.DIV {
+					sign = -1
+				}
+				continue
+			}
+		}
+		p.unscan()
+		p.setSpan(unit, unit.Start)
+		return unit, nil
+	}
+}
+
+// parseUnitExpression parses a product of units which may contain whitespace
+// and parentheses, such as `kg * m / s^2`.
+func (p *Parser) parseUnitExpression() (*ast.UnitExpression, error) {
+	unit := &ast.UnitExpression{}
+	sign := 1
+
+	_, start, _ := p.scanIgnoreWhitespace()
+	p.unscan()
+	for {
+		terms, err := p.parseUnitTerm()
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range terms {
+			unit.Terms = append(unit.Terms, ast.UnitTerm{Symbol: t.Symbol, Exp: sign * t.Exp})
+		}
+
+		switch tok, _, _ := p.scanIgnoreWhitespace(); tok {
+		case lexer.MUL:
+			sign = 1
+		case lexer.DIV:
+			sign = -1
+		default:
+			p.unscan()
+			p.setSpan(unit, start)
+			return unit, nil
+		}
+	}
+}
+
+// parseUnitTerm parses a unit symbol, a parenthesized unit or the
+// dimensionless `1`, followed by an optional exponent.
+func (p *Parser) parseUnitTerm() ([]ast.UnitTerm, error) {
+	var terms []ast.UnitTerm
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	switch {
+	case tok == lexer.IDENT:
+		terms = []ast.UnitTerm{{Symbol: lit, Exp: 1}}
+	case tok == lexer.LPAREN:
+		unit, err := p.parseUnitExpression()
+		if err != nil {
+			return nil, err
+		}
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.RPAREN {
+			return nil, newParseError(tokstr(tok, lit), []string{")"}, pos)
+		}
+		terms = unit.Terms
+	case tok == lexer.INTEGER && lit == "1":
+		return nil, nil
+	default:
+		return nil, newParseError(tokstr(tok, lit), []string{"unit"}, pos)
+	}
+
+	exp, err := p.parseUnitExponent(p.scanIgnoreWhitespace)
+	if err != nil {
+		return nil, err
+	}
+	for i := range terms {
+		terms[i].Exp *= exp
+	}
+	return terms, nil
+}
+
+// parseUnitExponent parses an optional `^n` or `**n` exponent using the
+// given scan function. The exponent defaults to one.
+func (p *Parser) parseUnitExponent(scan func() (lexer.Token, lexer.Pos, string)) (int, error) {
+	if tok, _, _ := scan(); tok != lexer.XOR && tok != lexer.POW {
+		p.unscan()
+		return 1, nil
+	}
+
+	sign := 1
+	tok, pos, lit := scan()
+	if tok == lexer.MINUS {
+		sign = -1
+		tok, pos, lit = scan()
+	}
+	if tok != lexer.INTEGER {
+		return 0, newParseError(tokstr(tok, lit), []string{"integer exponent"}, pos)
+	}
+
+	exp, err := strconv.Atoi(lit)
+	if err != nil {
+		return 0, tokenError("Invalid unit exponent", tok, pos, lit)
+	}
+	return sign * exp, nil
+}
+
+// parseConversionExpression parses the unit of a `to` conversion.
+func (p *Parser) parseConversionExpression(expr ast.Expression, pos lexer.Pos) (ast.Expression, error) {
+	unit, err := p.parseUnitExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.ConversionExpression{Expr: expr, Unit: unit}, nil
+}
+
+// parseUnitDeclaration parses `unit Name (symbol)` followed by an optional
+// block of conversions. The UNIT keyword has already been consumed.
+func (p *Parser) parseUnitDeclaration() (ast.Expression, error) {
+	name, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.LPAREN {
+		return nil, newParseError(tokstr(tok, lit), []string{"("}, pos)
+	}
+	symbol, err := p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.RPAREN {
+		return nil, newParseError(tokstr(tok, lit), []string{")"}, pos)
+	}
+
+	decl := &ast.UnitDeclaration{Name: name, Symbol: symbol}
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok != lexer.LBRACE {
+		p.unscan()
+		return decl, nil
+	}
+
+	for {
+		tok, _, _ := p.scanIgnoreWhitespace()
+		if tok == lexer.RBRACE {
+			return decl, nil
+		} else if tok == lexer.SEMICOLON {
+			continue
+		}
+		p.unscan()
+
+		amount, err := p.parseUnitAmount()
+		if err != nil {
+			return nil, err
+		}
+		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.EQ {
+			return nil, newParseError(tokstr(tok, lit), []string{"="}, pos)
+		}
+		value, err := p.parseUnitAmount()
+		if err != nil {
+			return nil, err
+		}
+		unit, err := p.parseUnitExpression()
+		if err != nil {
+			return nil, err
+		}
+		decl.Conversions = append(decl.Conversions, &ast.UnitConversion{Amount: amount, Value: value, Unit: unit})
+	}
+}
+
+// parseConversionDeclaration parses `conversion 1 m = 3.28084 ft`. The
+// CONVERSION keyword has already been consumed.
+func (p *Parser) parseConversionDeclaration() (ast.Expression, error) {
+	lh, err := p.parseDeclaredQuantity()
+	if err != nil {
+		return nil, err
+	}
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != lexer.EQ {
+		return nil, newParseError(tokstr(tok, lit), []string{"="}, pos)
+	}
+	rh, err := p.parseDeclaredQuantity()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.ConversionDeclaration{LValue: lh, RValue: rh}, nil
+}
+
+// parseDeclaredQuantity parses a numeric expression followed by a unit.
+func (p *Parser) parseDeclaredQuantity() (*ast.QuantityExpression, error) {
+	value, err := p.parseUnitAmount()
+	if err != nil {
+		return nil, err
+	}
+
+	unit, err := p.parseUnitExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.QuantityExpression{Value: value, Unit: unit}, nil
+}
+
+// parseUnitAmount parses a numeric expression without attaching units to
+// its literals, so that `1/12 ft` scales the unit by a twelfth. The amount
+// stops before the `=` of a conversion.
+func (p *Parser) parseUnitAmount() (ast.Expression, error) {
+	p.noQuantity = true
+	defer func() { p.noQuantity = false }()
+	return p.parseExpression(assignPrecedence)
+}
@@ -0,0 +1,58 @@
+package units
+
+// Constant is a physical constant with its value and standard uncertainty
+// written as decimal strings in the given unit. Exact constants have an
+// uncertainty of zero. Exact constants which are irrational or products of
+// other constants have an expression of the preceding constants as their
+// value, such as `h / (2 * pi)`.
+type Constant struct {
+	Name        string
+	Symbols     []string
+	Value       string
+	Uncertainty string
+	Unit        string
+}
+
+// UncertaintySuffix is appended to the names and symbols of a constant to
+// name its standard uncertainty, such as `G_uncertainty`.
+const UncertaintySuffix = "_uncertainty"
+
+// Constants lists the CODATA 2018 recommended values of the fundamental
+// physical constants along with the conventional standard gravity.
+var Constants = []Constant{
+	{"speed_of_light", []string{"c"}, "299792458", "0", "m/s"},
+	{"planck_constant", []string{"h"}, "6.62607015E-34", "0", "J*s"},
+	{"reduced_planck_constant", []string{"hbar", "ℏ"}, "h / (2 * pi)", "0", "J*s"},
+	{"elementary_charge", []string{"q_e"}, "1.602176634E-19", "0", "C"},
+	{"boltzmann_constant", []string{"k_B"}, "1.380649E-23", "0", "J/K"},
+	{"avogadro_constant", []string{"N_A"}, "6.02214076E23", "0", "1/mol"},
+	{"molar_gas_constant", []string{"R_gas"}, "N_A * k_B", "0", "J/mol/K"},
+	{"faraday_constant", []string{"F_c"}, "N_A * q_e", "0", "C/mol"},
+	{"stefan_boltzmann_constant", []string{"sigma_SB"}, "2 * pi**5 * k_B**4 / (15 * h**3 * c**2)", "0", "W/m^2/K^4"},
+	{"gravitational_constant", []string{"G"}, "6.67430E-11", "0.00015E-11", "m^3/kg/s^2"},
+	{"vacuum_permittivity", []string{"epsilon_0", "ε0"}, "8.8541878128E-12", "0.0000000013E-12", "F/m"},
+	{"vacuum_permeability", []string{"mu_0", "μ0"}, "1.25663706212E-6", "0.00000000019E-6", "N/A^2"},
+	{"fine_structure_constant", []string{"alpha", "α"}, "7.2973525693E-3", "0.0000000011E-3", "1"},
+	{"rydberg_constant", []string{"R_inf"}, "10973731.568160", "0.000021", "1/m"},
+	{"bohr_radius", []string{"a_0"}, "5.29177210903E-11", "0.00000000080E-11", "m"},
+	{"electron_mass", []string{"m_e"}, "9.1093837015E-31", "0.0000000028E-31", "kg"},
+	{"proton_mass", []string{"m_p"}, "1.67262192369E-27", "0.00000000051E-27", "kg"},
+	{"neutron_mass", []string{"m_n"}, "1.67492749804E-27", "0.00000000095E-27", "kg"},
+	{"atomic_mass_constant", []string{"m_u"}, "1.66053906660E-27", "0.00000000050E-27", "kg"},
+	{"standard_gravity", []string{"g_n"}, "9.80665", "0", "m/s^2"},
+}
+
+// LookupConstant returns the constant with the given name or symbol.
+func LookupConstant(s string) (Constant, bool) {
+	for _, c := range Constants {
+		if c.Name == s {
+			return c, true
+		}
+		for _, symbol := range c.Symbols {
+			if symbol == s {
+				return c, true
+			}
+		}
+	}
+	return Constant{}, false
+}
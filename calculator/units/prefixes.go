@@ -0,0 +1,54 @@
+package units
+
+import "math/big"
+
+// Prefix is an SI prefix which scales a unit by a power of ten.
+type Prefix struct {
+	Name string
+
+	// Symbols lists the accepted spellings, the first being canonical
+	Symbols []string
+	Factor  *big.Rat
+}
+
+// Prefixes lists the SI prefixes from quecto to quetta. Two letter symbols
+// come first so that `da` is not read as `d`.
+var Prefixes = []Prefix{
+	newPrefix("deca", 1, "da"),
+	newPrefix("quecto", -30, "q"),
+	newPrefix("ronto", -27, "r"),
+	newPrefix("yocto", -24, "y"),
+	newPrefix("zepto", -21, "z"),
+	newPrefix("atto", -18, "a"),
+	newPrefix("femto", -15, "f"),
+	newPrefix("pico", -12, "p"),
+	newPrefix("nano", -9, "n"),
+	newPrefix("micro", -6, "μ", "µ", "u"),
+	newPrefix("milli", -3, "m"),
+	newPrefix("centi", -2, "c"),
+	newPrefix("deci", -1, "d"),
+	newPrefix("hecto", 2, "h"),
+	newPrefix("kilo", 3, "k"),
+	newPrefix("mega", 6, "M"),
+	newPrefix("giga", 9, "G"),
+	newPrefix("tera", 12, "T"),
+	newPrefix("peta", 15, "P"),
+	newPrefix("exa", 18, "E"),
+	newPrefix("zetta", 21, "Z"),
+	newPrefix("yotta", 24, "Y"),
+	newPrefix("ronna", 27, "R"),
+	newPrefix("quetta", 30, "Q"),
+}
+
+func newPrefix(name string, exp int, symbols ...string) Prefix {
+	return Prefix{Name: name, Symbols: symbols, Factor: pow10(exp)}
+}
+
+// pow10 returns ten raised to an integer power.
+func pow10(exp int) *big.Rat {
+	n := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exp))), nil)
+	if exp < 0 {
+		return new(big.Rat).SetFrac(big.NewInt(1), n)
+	}
+	return new(big.Rat).SetInt(n)
+}
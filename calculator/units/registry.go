@@ -0,0 +1,176 @@
+package units
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Registry stores the units known to an environment by symbol and name.
+// Registries may extend a parent registry in which case lookups fall through
+// to the parent and definitions shadow the parent's units.
+type Registry struct {
+	parent  *Registry
+	symbols map[string]*Unit
+	names   map[string]*Unit
+
+	// irrational lists the units with irrational factors
+	irrational []*Unit
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{symbols: make(map[string]*Unit), names: make(map[string]*Unit)}
+}
+
+// Extend returns an empty registry whose lookups fall through to this one.
+func (r *Registry) Extend() *Registry {
+	child := NewRegistry()
+	child.parent = r
+	return child
+}
+
+// Lookup returns the unit with the given symbol or name. Symbols which are not
+// declared are resolved as an SI prefix applied to a prefixable unit, such as
+// `km` or `GHz`.
+func (r *Registry) Lookup(s string) (*Unit, bool) {
+	if u, ok := r.lookup(s); ok {
+		return u, true
+	}
+
+	for _, p := range Prefixes {
+		for _, symbol := range p.Symbols {
+			if len(s) <= len(symbol) || !strings.HasPrefix(s, symbol) {
+				continue
+			}
+			if u, ok := r.lookup(s[len(symbol):]); ok && u.Prefixable {
+				return u.withPrefix(p, symbol), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// lookup returns the declared unit with the given symbol or name.
+func (r *Registry) lookup(s string) (*Unit, bool) {
+	for reg := r; reg != nil; reg = reg.parent {
+		if u, ok := reg.symbols[s]; ok {
+			return u, true
+		} else if u, ok := reg.names[s]; ok {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+// DefineBase declares a new base unit with its own dimension.
+func (r *Registry) DefineBase(name, symbol string) (*Unit, error) {
+	return r.add(&Unit{Name: name, Symbol: symbol, Factor: big.NewRat(1, 1), Dim: Dimension{symbol: 1}})
+}
+
+// Define declares a unit equal to factor times the compound unit. Units
+// defined in terms of units with irrational factors are irrational as well.
+func (r *Registry) Define(name, symbol string, factor *big.Rat, unit Compound) (*Unit, error) {
+	if factor.Sign() <= 0 {
+		return nil, fmt.Errorf("unit %s must have a positive scale factor", symbol)
+	}
+	f := new(big.Rat).Mul(factor, unit.Factor())
+	u := &Unit{Name: name, Symbol: symbol, Factor: f, Dim: unit.Dimension()}
+	if pi := unit.Pi(); pi != 0 {
+		u.Ratio = new(big.Rat).Mul(factor, unit.product((*Unit).ratio))
+		u.Pi = pi
+		u.SetPrecision(unit.Precision())
+	}
+	return r.add(u)
+}
+
+// SetPrecision computes the irrational factors of the units of the registry
+// and its parents to at least prec bits.
+func (r *Registry) SetPrecision(prec uint) {
+	for reg := r; reg != nil; reg = reg.parent {
+		for _, u := range reg.irrational {
+			u.SetPrecision(prec)
+		}
+	}
+}
+
+// add registers the unit unless the symbol or name is already in use by this
+// registry. Units of a parent registry may be shadowed.
+func (r *Registry) add(u *Unit) (*Unit, error) {
+	if _, ok := r.symbols[u.Symbol]; ok {
+		return nil, fmt.Errorf("unit %s already defined", u.Symbol)
+	} else if _, ok := r.names[u.Symbol]; ok {
+		return nil, fmt.Errorf("unit %s already defined", u.Symbol)
+	} else if _, ok := r.symbols[u.Name]; ok && u.Name != u.Symbol {
+		return nil, fmt.Errorf("unit %s already defined", u.Name)
+	} else if _, ok := r.names[u.Name]; ok && u.Name != u.Symbol {
+		return nil, fmt.Errorf("unit %s already defined", u.Name)
+	}
+	r.symbols[u.Symbol] = u
+	r.names[u.Name] = u
+	if u.Pi != 0 {
+		r.irrational = append(r.irrational, u)
+	}
+	return u, nil
+}
+
+// Alias makes the unit available under another symbol.
+func (r *Registry) Alias(alias string, u *Unit) error {
+	if _, ok := r.symbols[alias]; ok {
+		return fmt.Errorf("unit %s already defined", alias)
+	}
+	r.symbols[alias] = u
+	return nil
+}
+
+// Units returns the units registered in this registry excluding those of the
+// parent.
+func (r *Registry) Units() []*Unit {
+	units := make([]*Unit, 0, len(r.names))
+	for _, u := range r.names {
+		units = append(units, u)
+	}
+	return units
+}
+
+// Parse resolves a unit written in the compact form used by the evaluator,
+// such as `kg*m^2/s^2` or `1/mol`.
+func (r *Registry) Parse(s string) (Compound, error) {
+	var c Compound
+	sign := 1
+	for len(s) > 0 {
+		end := strings.IndexAny(s, "*/")
+		if end < 0 {
+			end = len(s)
+		}
+		term := s[:end]
+
+		exp := 1
+		if i := strings.IndexByte(term, '^'); i >= 0 {
+			n, err := strconv.Atoi(term[i+1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid unit exponent in %s", s)
+			}
+			term, exp = term[:i], n
+		}
+
+		if term != "1" {
+			u, ok := r.Lookup(term)
+			if !ok {
+				return nil, fmt.Errorf("unknown unit: %s", term)
+			}
+			c = c.Mul(Compound{{Unit: u, Exp: sign * exp}})
+		}
+
+		if end == len(s) {
+			break
+		} else if s[end] == '/' {
+			sign = -1
+		} else {
+			sign = 1
+		}
+		s = s[end+1:]
+	}
+	return c, nil
+}
@@ -0,0 +1,175 @@
+package units
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Definition declares a unit of the standard library. Units without a
+// definition are base units.
+type Definition struct {
+	Name       string
+	Symbol     string
+	Factor     string
+	Unit       string
+	Prefixable bool
+	Aliases    []string
+
+	// Pi is the power of π by which the factor is scaled
+	Pi int
+
+	// Offset is added to scaled values of an affine unit
+	Offset string
+}
+
+// base declares an SI base unit.
+func base(name, symbol string, prefixable bool) Definition {
+	return Definition{Name: name, Symbol: symbol, Prefixable: prefixable}
+}
+
+// derived declares a unit equal to factor times a compound of known units.
+func derived(name, symbol, factor, unit string, prefixable bool, aliases ...string) Definition {
+	return Definition{Name: name, Symbol: symbol, Factor: factor, Unit: unit, Prefixable: prefixable, Aliases: aliases}
+}
+
+// angle declares a unit equal to factor times π radians.
+func angle(name, symbol, factor string, aliases ...string) Definition {
+	return Definition{Name: name, Symbol: symbol, Factor: factor, Unit: "rad", Aliases: aliases, Pi: 1}
+}
+
+// SI contains the SI base units, the coherent derived units with special names
+// and the non-SI units accepted for use with the SI. The kilogram is the base
+// unit of mass while prefixes are applied to the gram. The degree Celsius is
+// offset from the kelvin, so `20 degC to K` is 293.15 K, but measures
+// differences in sums and compound units, so `20 degC + 5 K` is 25 degC.
+var SI = []Definition{
+	base("Meter", "m", true),
+	base("Kilogram", "kg", false),
+	base("Second", "s", true),
+	base("Ampere", "A", true),
+	base("Kelvin", "K", true),
+	base("Mole", "mol", true),
+	base("Candela", "cd", true),
+
+	derived("Gram", "g", "1/1000", "kg", true),
+	derived("Radian", "rad", "1", "1", true),
+	derived("Steradian", "sr", "1", "1", true),
+	derived("Hertz", "Hz", "1", "1/s", true),
+	derived("Newton", "N", "1", "kg*m/s^2", true),
+	derived("Pascal", "Pa", "1", "N/m^2", true),
+	derived("Joule", "J", "1", "N*m", true),
+	derived("Watt", "W", "1", "J/s", true),
+	derived("Coulomb", "C", "1", "A*s", true),
+	derived("Volt", "V", "1", "W/A", true),
+	derived("Farad", "F", "1", "C/V", true),
+	derived("Ohm", "Ω", "1", "V/A", true, "ohm"),
+	derived("Siemens", "S", "1", "A/V", true),
+	derived("Weber", "Wb", "1", "V*s", true),
+	derived("Tesla", "T", "1", "Wb/m^2", true),
+	derived("Henry", "H", "1", "Wb/A", true),
+	derived("Lumen", "lm", "1", "cd*sr", true),
+	derived("Lux", "lx", "1", "lm/m^2", true),
+	derived("Becquerel", "Bq", "1", "1/s", true),
+	derived("Gray", "Gy", "1", "J/kg", true),
+	derived("Sievert", "Sv", "1", "J/kg", true),
+	derived("Katal", "kat", "1", "mol/s", true),
+	{Name: "DegreeCelsius", Symbol: "degC", Factor: "1", Unit: "K", Offset: "273.15", Aliases: []string{"°C"}},
+
+	derived("Minute", "min", "60", "s", false),
+	derived("Hour", "h", "60", "min", false),
+	derived("Day", "d", "24", "h", false),
+	derived("Year", "yr", "365.25", "d", false),
+	derived("Liter", "L", "1/1000", "m^3", true, "l"),
+	derived("Tonne", "t", "1000", "kg", true),
+	derived("Hectare", "ha", "10000", "m^2", false),
+	angle("Degree", "deg", "1/180", "°"),
+	derived("Electronvolt", "eV", "1.602176634e-19", "J", true),
+	derived("Dalton", "Da", "1.66053906660e-27", "kg", true),
+	derived("AstronomicalUnit", "au", "149597870700", "m", false),
+	derived("LightYear", "ly", "9460730472580800", "m", false),
+	derived("Parsec", "pc", "30856775814913673", "m", true),
+	derived("Bar", "bar", "100000", "Pa", true),
+	derived("Atmosphere", "atm", "101325", "Pa", false),
+	derived("Calorie", "cal", "4.184", "J", true),
+}
+
+// Imperial contains the common imperial and US customary units defined by
+// the international yard and pound agreement.
+var Imperial = []Definition{
+	derived("Inch", "in", "0.0254", "m", false),
+	derived("Foot", "ft", "12", "in", false),
+	derived("Yard", "yd", "3", "ft", false),
+	derived("Mile", "mi", "5280", "ft", false),
+	derived("NauticalMile", "nmi", "1852", "m", false),
+	derived("Acre", "acre", "43560", "ft^2", false),
+	derived("Ounce", "oz", "0.028349523125", "kg", false),
+	derived("Pound", "lb", "0.45359237", "kg", false),
+	derived("Stone", "st", "14", "lb", false),
+	derived("ShortTon", "ton", "2000", "lb", false),
+	derived("Gallon", "gal", "231", "in^3", false),
+	derived("Quart", "qt", "1/4", "gal", false),
+	derived("Pint", "pt", "1/8", "gal", false),
+	derived("FluidOunce", "floz", "1/128", "gal", false),
+	derived("PoundForce", "lbf", "9.80665", "lb*m/s^2", false),
+	derived("PoundPerSquareInch", "psi", "1", "lbf/in^2", false),
+	derived("Horsepower", "hp", "550", "ft*lbf/s", false),
+	derived("MilePerHour", "mph", "1", "mi/h", false),
+	derived("Knot", "kn", "1", "nmi/h", false),
+}
+
+// Load declares the units in the registry in order. Later definitions may
+// refer to earlier ones.
+func Load(r *Registry, defs []Definition) error {
+	for _, def := range defs {
+		var u *Unit
+		var err error
+		if def.Unit == "" {
+			u, err = r.DefineBase(def.Name, def.Symbol)
+		} else {
+			u, err = defineUnit(r, def)
+		}
+		if err != nil {
+			return err
+		}
+
+		u.Prefixable = def.Prefixable
+		for _, alias := range def.Aliases {
+			if err := r.Alias(alias, u); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func defineUnit(r *Registry, def Definition) (*Unit, error) {
+	factor, ok := new(big.Rat).SetString(def.Factor)
+	if !ok {
+		return nil, fmt.Errorf("invalid factor for unit %s: %s", def.Symbol, def.Factor)
+	}
+	unit, err := r.Parse(def.Unit)
+	if err != nil {
+		return nil, err
+	} else if def.Pi != 0 {
+		unit = unit.Mul(Compound{{Unit: piUnit(), Exp: def.Pi}})
+	}
+	u, err := r.Define(def.Name, def.Symbol, factor, unit)
+	if err != nil || def.Offset == "" {
+		return u, err
+	}
+	if u.Offset, ok = new(big.Rat).SetString(def.Offset); !ok {
+		return nil, fmt.Errorf("invalid offset for unit %s: %s", def.Symbol, def.Offset)
+	}
+	return u, nil
+}
+
+// Standard returns a registry containing the SI and imperial units.
+func Standard() *Registry {
+	r := NewRegistry()
+	for _, defs := range [][]Definition{SI, Imperial} {
+		if err := Load(r, defs); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
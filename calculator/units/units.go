@@ -0,0 +1,406 @@
+// Package units implements units of measure and dimensional analysis.
+//
+// Every unit has a dimension, a product of base units raised to integer
+// powers, and an exact scale factor relative to those base units. Compound
+// units such as `kg*m/s^2` are products of named units. Angles measured in
+// fractions of a turn, such as the degree, have a rational multiple of a
+// power of π as their factor, which is computed to the precision in use.
+package units
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/eliquious/aechbar/calculator/bigmath"
+)
+
+// guardBits are the extra bits of precision of irrational factors.
+const guardBits = 64
+
+// initialPrecision is the precision in bits irrational factors are computed
+// to before a precision is set.
+const initialPrecision = 64
+
+// Dimension maps the symbols of base units to their exponents. Dimensions
+// are immutable once created.
+type Dimension map[string]int
+
+// Mul returns the dimension of the product of two quantities.
+func (d Dimension) Mul(o Dimension) Dimension {
+	z := make(Dimension, len(d)+len(o))
+	for k, v := range d {
+		z[k] = v
+	}
+	for k, v := range o {
+		if z[k] += v; z[k] == 0 {
+			delete(z, k)
+		}
+	}
+	return z
+}
+
+// Pow returns the dimension raised to an integer power.
+func (d Dimension) Pow(n int) Dimension {
+	z := make(Dimension, len(d))
+	if n == 0 {
+		return z
+	}
+	for k, v := range d {
+		z[k] = v * n
+	}
+	return z
+}
+
+// Equal returns true if both dimensions have the same exponents.
+func (d Dimension) Equal(o Dimension) bool {
+	if len(d) != len(o) {
+		return false
+	}
+	for k, v := range d {
+		if o[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the dimension as a product of base units.
+func (d Dimension) String() string {
+	if len(d) == 0 {
+		return "1"
+	}
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var terms []Term
+	for _, k := range keys {
+		terms = append(terms, Term{Unit: &Unit{Symbol: k}, Exp: d[k]})
+	}
+	return Compound(terms).String()
+}
+
+// Unit is a named unit of measure.
+type Unit struct {
+	Name   string
+	Symbol string
+
+	// Factor converts a value in this unit to its base units. Units with a
+	// non-zero Pi have the irrational factor Ratio·π^Pi, which Factor
+	// approximates to the precision last set.
+	Factor *big.Rat
+	Ratio  *big.Rat
+	Pi     int
+	Dim    Dimension
+
+	// Prefixable units may be combined with SI prefixes
+	Prefixable bool
+
+	// Offset is added to a scaled value to give the value in base units for
+	// affine units such as the degree Celsius, and is nil otherwise
+	Offset *big.Rat
+
+	// prec is the precision in bits of an irrational factor
+	prec uint
+}
+
+// piUnit returns the dimensionless unit π, which scales the factors of
+// angles declared in fractions of a turn.
+func piUnit() *Unit {
+	u := &Unit{Name: "Pi", Symbol: "π", Ratio: big.NewRat(1, 1), Pi: 1, Dim: Dimension{}}
+	u.SetPrecision(initialPrecision)
+	return u
+}
+
+// SetPrecision computes an irrational factor to at least prec bits. Factors
+// which are already as precise are kept.
+func (u *Unit) SetPrecision(prec uint) {
+	if u.Pi == 0 || prec <= u.prec {
+		return
+	}
+
+	pi := bigmath.Pi(prec + guardBits)
+	f := new(big.Float).SetPrec(prec + guardBits).SetRat(u.Ratio)
+	for i := 0; i < abs(u.Pi); i++ {
+		if u.Pi > 0 {
+			f.Mul(f, pi)
+		} else {
+			f.Quo(f, pi)
+		}
+	}
+	u.Factor, _ = f.Rat(nil)
+	u.prec = prec
+}
+
+// ratio returns the rational part of the factor.
+func (u *Unit) ratio() *big.Rat {
+	if u.Pi == 0 {
+		return u.Factor
+	}
+	return u.Ratio
+}
+
+// withPrefix returns the unit scaled by the prefix.
+func (u *Unit) withPrefix(p Prefix, symbol string) *Unit {
+	prefixed := &Unit{
+		Name:   p.Name + strings.ToLower(u.Name),
+		Symbol: symbol + u.Symbol,
+		Factor: new(big.Rat).Mul(p.Factor, u.Factor),
+		Pi:     u.Pi,
+		Dim:    u.Dim,
+		prec:   u.prec,
+	}
+	if u.Pi != 0 {
+		prefixed.Ratio = new(big.Rat).Mul(p.Factor, u.Ratio)
+	}
+	return prefixed
+}
+
+// IsBase returns true if the unit is a base unit.
+func (u *Unit) IsBase() bool {
+	return len(u.Dim) == 1 && u.Dim[u.Symbol] == 1 && u.Factor.Cmp(big.NewRat(1, 1)) == 0
+}
+
+// String returns the unit declaration.
+func (u *Unit) String() string {
+	if u.IsBase() {
+		return fmt.Sprintf("%s (%s)", u.Name, u.Symbol)
+	} else if u.Pi == 1 {
+		return fmt.Sprintf("%s (%s) = %s*π %s", u.Name, u.Symbol, u.Ratio.RatString(), u.Dim)
+	} else if u.Pi != 0 {
+		return fmt.Sprintf("%s (%s) = %s*π^%d %s", u.Name, u.Symbol, u.Ratio.RatString(), u.Pi, u.Dim)
+	} else if u.Offset != nil {
+		return fmt.Sprintf("%s (%s) = %s %s + %s", u.Name, u.Symbol, u.Factor.RatString(), u.Dim, u.Offset.RatString())
+	}
+	return fmt.Sprintf("%s (%s) = %s %s", u.Name, u.Symbol, u.Factor.RatString(), u.Dim)
+}
+
+// Term is a unit raised to an integer power.
+type Term struct {
+	Unit *Unit
+	Exp  int
+}
+
+// Compound is a product of units. The zero value is dimensionless.
+type Compound []Term
+
+// Of returns the compound consisting of a single unit.
+func Of(u *Unit) Compound {
+	return Compound{{Unit: u, Exp: 1}}
+}
+
+// IsEmpty returns true if the compound has no units.
+func (c Compound) IsEmpty() bool { return len(c) == 0 }
+
+// Dimension returns the combined dimension of the units.
+func (c Compound) Dimension() Dimension {
+	d := Dimension{}
+	for _, t := range c {
+		d = d.Mul(t.Unit.Dim.Pow(t.Exp))
+	}
+	return d
+}
+
+// Factor returns the combined scale factor of the units. The factor is
+// exact when the powers of π of the units cancel, as in `deg/deg`, and is
+// approximated to the Precision of the compound otherwise.
+func (c Compound) Factor() *big.Rat {
+	if c.Pi() == 0 {
+		return c.product((*Unit).ratio)
+	}
+	return c.product(func(u *Unit) *big.Rat { return u.Factor })
+}
+
+// product returns the product of the factors of the units raised to their
+// exponents.
+func (c Compound) product(factor func(u *Unit) *big.Rat) *big.Rat {
+	z := big.NewRat(1, 1)
+	for _, t := range c {
+		f := factor(t.Unit)
+		num := new(big.Int).Exp(f.Num(), big.NewInt(int64(abs(t.Exp))), nil)
+		den := new(big.Int).Exp(f.Denom(), big.NewInt(int64(abs(t.Exp))), nil)
+		if t.Exp < 0 {
+			num, den = den, num
+		}
+		z.Mul(z, new(big.Rat).SetFrac(num, den))
+	}
+	return z
+}
+
+// Pi returns the power of π of the combined scale factor.
+func (c Compound) Pi() int {
+	n := 0
+	for _, t := range c {
+		n += t.Unit.Pi * t.Exp
+	}
+	return n
+}
+
+// Precision returns the precision in bits of the combined scale factor, or
+// zero if the factor is exact.
+func (c Compound) Precision() uint {
+	if c.Pi() == 0 {
+		return 0
+	}
+	var prec uint
+	for _, t := range c {
+		if t.Unit.Pi != 0 && (prec == 0 || t.Unit.prec < prec) {
+			prec = t.Unit.prec
+		}
+	}
+	return prec
+}
+
+// Mul returns the product of the compounds. Exponents of the same unit are
+// combined and units with a zero exponent are removed.
+func (c Compound) Mul(o Compound) Compound {
+	z := append(Compound{}, c...)
+	for _, t := range o {
+		found := false
+		for i := range z {
+			if z[i].Unit.Symbol == t.Unit.Symbol {
+				z[i].Exp += t.Exp
+				found = true
+				break
+			}
+		}
+		if !found {
+			z = append(z, t)
+		}
+	}
+
+	n := 0
+	for _, t := range z {
+		if t.Exp != 0 {
+			z[n] = t
+			n++
+		}
+	}
+	return z[:n]
+}
+
+// Div returns the quotient of the compounds.
+func (c Compound) Div(o Compound) Compound {
+	return c.Mul(o.Pow(-1))
+}
+
+// Pow returns the compound raised to an integer power.
+func (c Compound) Pow(n int) Compound {
+	if n == 0 {
+		return Compound{}
+	}
+	z := make(Compound, len(c))
+	for i, t := range c {
+		z[i] = Term{Unit: t.Unit, Exp: t.Exp * n}
+	}
+	return z
+}
+
+// Root returns the nth root of the compound if every exponent is divisible by n.
+func (c Compound) Root(n int) (Compound, bool) {
+	z := make(Compound, len(c))
+	for i, t := range c {
+		if n == 0 || t.Exp%n != 0 {
+			return nil, false
+		}
+		z[i] = Term{Unit: t.Unit, Exp: t.Exp / n}
+	}
+	return z, true
+}
+
+// Compatible returns true if both compounds have the same dimension.
+func (c Compound) Compatible(o Compound) bool {
+	return c.Dimension().Equal(o.Dimension())
+}
+
+// ConversionFactor returns the factor which converts values in this unit to
+// the target unit. The factor is exact unless the powers of π of the units
+// differ.
+func (c Compound) ConversionFactor(to Compound) (*big.Rat, error) {
+	if !c.Compatible(to) {
+		return nil, &DimensionError{Op: "convert", Left: c, Right: to}
+	}
+	return c.Div(to).Factor(), nil
+}
+
+// Offset returns the offset of a compound consisting of a single affine unit,
+// such as `degC`, and nil otherwise. Affine units raised to a power or
+// combined with other units, as in `J/degC`, measure differences.
+func (c Compound) Offset() *big.Rat {
+	if len(c) == 1 && c[0].Exp == 1 {
+		return c[0].Unit.Offset
+	}
+	return nil
+}
+
+// ConversionOffset returns the value added after scaling by the conversion
+// factor when converting an absolute value, such as a temperature in degC,
+// to the target unit. It returns nil if neither compound has an offset.
+func (c Compound) ConversionOffset(to Compound) *big.Rat {
+	from, target := c.Offset(), to.Offset()
+	if from == nil && target == nil {
+		return nil
+	}
+	z := new(big.Rat)
+	if from != nil {
+		z.Add(z, from)
+	}
+	if target != nil {
+		z.Sub(z, target)
+	}
+	return z.Quo(z, to.Factor())
+}
+
+// String formats the compound as a product of units followed by the divisors,
+// such as `kg*m/s^2`.
+func (c Compound) String() string {
+	var num, den []string
+	for _, t := range c {
+		switch {
+		case t.Exp == 1:
+			num = append(num, t.Unit.Symbol)
+		case t.Exp > 1:
+			num = append(num, fmt.Sprintf("%s^%d", t.Unit.Symbol, t.Exp))
+		case t.Exp == -1:
+			den = append(den, t.Unit.Symbol)
+		case t.Exp < -1:
+			den = append(den, fmt.Sprintf("%s^%d", t.Unit.Symbol, -t.Exp))
+		}
+	}
+
+	s := strings.Join(num, "*")
+	if s == "" {
+		s = "1"
+	}
+	for _, d := range den {
+		s += "/" + d
+	}
+	return s
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// DimensionError is returned when an operation requires compatible units.
+type DimensionError struct {
+	Op          string
+	Left, Right Compound
+}
+
+// Error returns the string representation of the error. The dimensions are
+// included only if they differ from the units.
+func (e *DimensionError) Error() string {
+	ld, rd := e.Left.Dimension().String(), e.Right.Dimension().String()
+	if ld == e.Left.String() && rd == e.Right.String() {
+		return fmt.Sprintf("cannot %s %s and %s: incompatible dimensions", e.Op, e.Left, e.Right)
+	}
+	return fmt.Sprintf("cannot %s %s and %s: incompatible dimensions %s and %s", e.Op, e.Left, e.Right, ld, rd)
+}
@@ -0,0 +1,159 @@
+package units
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	m, _ := r.DefineBase("Meter", "m")
+	s, _ := r.DefineBase("Second", "s")
+	km, err := r.Define("Kilometer", "km", big.NewRat(1000, 1), Of(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.DefineBase("Minute", "m"); err == nil {
+		t.Fatal("expected error redefining m")
+	}
+	if u, ok := r.Lookup("Kilometer"); !ok || u != km {
+		t.Fatal("expected lookup by name")
+	}
+
+	speed := Of(km).Div(Of(s))
+	if got := speed.String(); got != "km/s" {
+		t.Errorf("expected km/s, got %s", got)
+	}
+	if got := speed.Dimension().String(); got != "m/s" {
+		t.Errorf("expected dimension m/s, got %s", got)
+	}
+
+	factor, err := speed.ConversionFactor(Of(m).Div(Of(s)))
+	if err != nil || factor.RatString() != "1000" {
+		t.Errorf("expected factor 1000, got %v, %v", factor, err)
+	}
+	if _, err := speed.ConversionFactor(Of(m)); err == nil {
+		t.Error("expected dimension error")
+	}
+
+	if c := Of(m).Mul(Of(m).Pow(-1)); !c.IsEmpty() {
+		t.Errorf("expected m/m to cancel, got %s", c)
+	}
+	if root, ok := Of(m).Pow(4).Root(2); !ok || root.String() != "m^2" {
+		t.Errorf("expected m^2, got %s", root)
+	}
+	if _, ok := Of(m).Root(2); ok {
+		t.Error("expected no square root of m")
+	}
+}
+
+func TestStandard(t *testing.T) {
+	r := Standard()
+	tests := []struct {
+		unit   string
+		to     string
+		factor string
+	}{
+		{"km", "m", "1000"},
+		{"mg", "kg", "1/1000000"},
+		{"μs", "s", "1/1000000"},
+		{"us", "s", "1/1000000"},
+		{"dam", "m", "10"},
+		{"qm", "m", "1/1000000000000000000000000000000"},
+		{"Qm", "m", "1000000000000000000000000000000"},
+		{"kW*h", "J", "3600000"},
+		{"GHz", "1/s", "1000000000"},
+		{"min", "s", "60"},
+		{"mi", "ft", "5280"},
+		{"ft", "m", "381/1250"},
+		{"N", "kg*m/s^2", "1"},
+		{"psi", "Pa", "8896443230521/1290320000"},
+		{"mL", "cm^3", "1"},
+	}
+	for _, test := range tests {
+		from, err := r.Parse(test.unit)
+		if err != nil {
+			t.Errorf("%s: %s", test.unit, err)
+			continue
+		}
+		to, err := r.Parse(test.to)
+		if err != nil {
+			t.Errorf("%s: %s", test.to, err)
+			continue
+		}
+		factor, err := from.ConversionFactor(to)
+		if err != nil {
+			t.Errorf("%s to %s: %s", test.unit, test.to, err)
+		} else if factor.RatString() != test.factor {
+			t.Errorf("%s to %s: expected %s, got %s", test.unit, test.to, test.factor, factor.RatString())
+		}
+	}
+
+	// Offsets apply to absolute values in a single affine unit
+	for _, test := range []struct{ unit, to, offset string }{
+		{"degC", "K", "5463/20"},
+		{"K", "degC", "-5463/20"},
+		{"degC", "degC", "0"},
+		{"K", "mK", ""},
+		{"degC^2", "K^2", ""},
+	} {
+		offset := mustParse(t, r, test.unit).ConversionOffset(mustParse(t, r, test.to))
+		if (offset == nil && test.offset != "") || (offset != nil && offset.RatString() != test.offset) {
+			t.Errorf("%s to %s: expected offset %q, got %v", test.unit, test.to, test.offset, offset)
+		}
+	}
+
+	for _, unit := range []string{"kft", "kkg", "kmin", "da", "furlong"} {
+		if _, err := r.Parse(unit); err == nil {
+			t.Errorf("%s: expected unknown unit", unit)
+		}
+	}
+
+	// Extended registries shadow the standard units
+	child := r.Extend()
+	if _, err := child.Define("Foot", "ft", big.NewRat(3, 10), Of(mustLookup(t, r, "m"))); err != nil {
+		t.Fatal(err)
+	}
+	if u := mustLookup(t, child, "ft"); u.Factor.RatString() != "3/10" {
+		t.Errorf("expected shadowed foot, got %s", u)
+	}
+	if u := mustLookup(t, r, "ft"); u.Factor.RatString() != "381/1250" {
+		t.Errorf("expected standard foot, got %s", u)
+	}
+}
+
+func TestConstants(t *testing.T) {
+	r := Standard()
+	for _, c := range Constants {
+		if _, err := r.Parse(c.Unit); err != nil {
+			t.Errorf("%s: %s", c.Name, err)
+		}
+		if _, ok := new(big.Float).SetString(c.Value); !ok && c.Uncertainty != "0" {
+			t.Errorf("%s: derived value %s must be exact", c.Name, c.Value)
+		}
+		if _, ok := new(big.Float).SetString(c.Uncertainty); !ok {
+			t.Errorf("%s: invalid uncertainty %s", c.Name, c.Uncertainty)
+		}
+	}
+	if c, ok := LookupConstant("G"); !ok || c.Uncertainty != "0.00015E-11" {
+		t.Errorf("expected G with uncertainty, got %v", c)
+	}
+}
+
+func mustLookup(t *testing.T, r *Registry, symbol string) *Unit {
+	t.Helper()
+	u, ok := r.Lookup(symbol)
+	if !ok {
+		t.Fatalf("unknown unit %s", symbol)
+	}
+	return u
+}
+
+func mustParse(t *testing.T, r *Registry, unit string) Compound {
+	t.Helper()
+	c, err := r.Parse(unit)
+	if err != nil {
+		t.Fatalf("%s: %s", unit, err)
+	}
+	return c
+}
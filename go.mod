@@ -0,0 +1,3 @@
+module github.com/eliquious/aechbar
+
+go 1.13
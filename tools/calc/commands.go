@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/eval"
+)
+
+// Session holds the state of the REPL between lines.
+type Session struct {
+	Env *eval.Environment
+
+	// Last is the result of the last evaluated expression
+	Last ast.Expression
+}
+
+var divisionModes = map[string]eval.DivisionMode{
+	"decimal": eval.DecimalDivision,
+	"exact":   eval.RationalDivision,
+}
+
+// IsCommand returns true if the line is a REPL command.
+func IsCommand(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), ":")
+}
+
+// RunCommand executes a REPL command and returns the message to display.
+//
+//	:mode [decimal|exact]                    division of integers
+//	:format [default|fraction|mixed|decimal] display of results
+//	:as fraction|mixed|decimal               display of the last result
+//	:precision [<n> [bits|digits]]           mantissa precision of decimals
+//	:rounding [<mode>]                       rounding mode of decimals
+//	:digits [<n>]                            digits displayed for decimals
+func (s *Session) RunCommand(line string) (string, error) {
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(line), ":"))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("missing command")
+	}
+
+	config := s.Env.Config()
+	switch name, args := strings.ToLower(fields[0]), fields[1:]; {
+	case name == "mode" && len(args) == 0:
+		for name, mode := range divisionModes {
+			if mode == config.Division {
+				return "mode " + name, nil
+			}
+		}
+	case name == "mode" && len(args) == 1:
+		mode, ok := divisionModes[strings.ToLower(args[0])]
+		if !ok {
+			return "", fmt.Errorf("unknown mode %s, expected decimal or exact", args[0])
+		}
+		config.Division = mode
+		return "mode " + strings.ToLower(args[0]), nil
+	case name == "format" && len(args) == 0:
+		return "format " + formatName(config.Format), nil
+	case name == "format" && len(args) == 1:
+		format, ok := eval.ParseNumberFormat(args[0])
+		if !ok {
+			return "", fmt.Errorf("unknown format %s, expected default, fraction, mixed or decimal", args[0])
+		}
+		config.Format = format
+		return "format " + formatName(format), nil
+	case name == "as" && len(args) == 1:
+		format, ok := eval.ParseNumberFormat(args[0])
+		if !ok {
+			return "", fmt.Errorf("unknown format %s, expected fraction, mixed or decimal", args[0])
+		} else if s.Last == nil {
+			return "", fmt.Errorf("no result to display")
+		}
+		return formatResult(s.Last, format, s.Env.Config().Digits), nil
+	case name == "precision" && len(args) == 0:
+		return fmt.Sprintf("precision %d bits", config.Precision), nil
+	case name == "precision" && len(args) <= 2:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("invalid precision %s", args[0])
+		}
+		prec := uint(n)
+		if len(args) == 2 && strings.HasPrefix("digits", strings.ToLower(args[1])) {
+			prec = eval.DigitsPrecision(n)
+		} else if len(args) == 2 && !strings.HasPrefix("bits", strings.ToLower(args[1])) {
+			return "", fmt.Errorf("unknown precision unit %s, expected bits or digits", args[1])
+		}
+		if prec > big.MaxPrec {
+			return "", fmt.Errorf("precision %d exceeds %d bits", prec, uint(big.MaxPrec))
+		}
+		config.Precision = prec
+		return fmt.Sprintf("precision %d bits", prec), nil
+	case name == "rounding" && len(args) == 0:
+		return "rounding " + config.Rounding.String(), nil
+	case name == "rounding" && len(args) == 1:
+		mode, ok := eval.ParseRoundingMode(args[0])
+		if !ok {
+			return "", fmt.Errorf("unknown rounding mode %s", args[0])
+		}
+		config.Rounding = mode
+		return "rounding " + mode.String(), nil
+	case name == "digits" && len(args) == 0:
+		return fmt.Sprintf("digits %d", config.Digits), nil
+	case name == "digits" && len(args) == 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return "", fmt.Errorf("invalid number of digits %s", args[0])
+		}
+		config.Digits = n
+		return fmt.Sprintf("digits %d", n), nil
+	case name == "mode" || name == "format" || name == "as" || name == "precision" || name == "rounding" || name == "digits":
+		return "", fmt.Errorf("usage: :%s <value>", name)
+	}
+	return "", fmt.Errorf("unknown command :%s", fields[0])
+}
+
+// formatResult returns the string representation of a result. Matrices are
+// displayed one row per line with their columns aligned, as are the factors
+// of decompositions such as lu(A) separated by blank lines.
+func formatResult(value ast.Expression, format eval.NumberFormat, digits int) string {
+	if m, ok := value.(*ast.MatrixLiteral); ok {
+		return eval.FormatMatrix(m, format, digits)
+	}
+
+	arr, ok := value.(*ast.ArrayLiteral)
+	if !ok || len(arr.Elements) == 0 {
+		return eval.FormatValue(value, format, digits)
+	}
+	factors := make([]string, len(arr.Elements))
+	for i, el := range arr.Elements {
+		m, ok := el.(*ast.MatrixLiteral)
+		if !ok {
+			return eval.FormatValue(value, format, digits)
+		}
+		factors[i] = eval.FormatMatrix(m, format, digits)
+	}
+	return strings.Join(factors, "\n\n")
+}
+
+// formatName returns the name of a number format.
+func formatName(format eval.NumberFormat) string {
+	for _, name := range []string{"default", "fraction", "mixed", "decimal"} {
+		if f, _ := eval.ParseNumberFormat(name); f == format {
+			return name
+		}
+	}
+	return "default"
+}
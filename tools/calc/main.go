@@ -1,31 +1,30 @@
 package main
 
 import (
-	// "fmt"
-	"bytes"
+	"errors"
 	"flag"
-	// "github.com/eliquious/aechbar/calculator/ast"
+	"strings"
+
+	"github.com/eliquious/aechbar/calculator/ast"
+	"github.com/eliquious/aechbar/calculator/check"
 	"github.com/eliquious/aechbar/calculator/eval"
 	"github.com/eliquious/aechbar/calculator/parser"
 	"github.com/subsilent/crypto/ssh/terminal"
 	"io"
 	"os"
+	"path/filepath"
 )
 
-var debug = flag.Bool("debug", false, "Enable debug logging")
+var path = flag.String("path", os.Getenv("AECHBAR_PATH"), "Module search path")
 
 const PROMPT = "\xc4\xa7 >>> "
 
 func main() {
-	// oldState, err := terminal.MakeRaw(0)
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// defer terminal.Restore(0, oldState)
-
-	// var scanner *lexer.Scanner
-	var inputBuffer bytes.Buffer
-	p := parser.NewParser(&inputBuffer)
+	flag.Parse()
+	env := eval.NewStandardEnvironment()
+	env.Loader().SearchPath = filepath.SplitList(*path)
+	checker := check.New()
+	session := &Session{Env: env}
 
 	rw := ReadWriter{os.Stdin, os.Stdout}
 	term := terminal.NewTerminal(rw, PROMPT)
@@ -44,9 +43,19 @@ func main() {
 			resp.Write([]byte("\n Exiting...\n"))
 			resp.Write(resp.Colors.Reset)
 			return
+		} else if IsCommand(line) {
+			if msg, err := session.RunCommand(line); err != nil {
+				writeError(&resp, line, err)
+			} else {
+				resp.Write(resp.Colors.LightYellow)
+				resp.Write([]byte(msg + "\n"))
+				resp.Write(resp.Colors.Reset)
+			}
+			continue
 		}
 
-		inputBuffer.WriteString(line)
+		// Each line is parsed separately so positions are columns of the line
+		p := parser.NewParser(strings.NewReader(line))
 		for {
 			expr, err := p.ParseExpression()
 			if err != nil {
@@ -55,54 +64,67 @@ func main() {
 				} else if err == parser.EOF {
 					break
 				}
-				resp.Write(resp.Colors.Red)
-				resp.Write([]byte(err.Error() + "\n"))
-				resp.Write(resp.Colors.Reset)
+
+				// The rest of the line is not parsed after a syntax error
+				writeError(&resp, line, err)
+				break
 			} else if expr != nil {
+				if errs := checker.Check(expr); len(errs) > 0 {
+					for _, err := range errs {
+						writeError(&resp, line, err)
+					}
+					continue
+				}
 
-				// TODO: Evaluate Expression
-				result, err := eval.Evaluate(expr)
+				value, err := eval.EvaluateValue(expr, env)
 				if err != nil {
-					resp.Write(resp.Colors.Red)
-					resp.Write([]byte(err.Error() + "\n"))
-					resp.Write(resp.Colors.Reset)
+					writeError(&resp, line, err)
 				} else {
+					session.Last = value
 					resp.Write(resp.Colors.Green)
-					resp.Write([]byte(result + "\n"))
+					resp.Write([]byte(formatResult(value, env.Config().Format, env.Config().Digits) + "\n"))
 					resp.Write(resp.Colors.Reset)
 				}
 			}
 		}
-		inputBuffer.Reset()
+	}
+}
 
-		// scanner = lexer.NewScanner(strings.NewReader(line))
-		// for {
-		// 	tok, pos, lit := scanner.Scan()
-		// 	if tok == lexer.EOF || tok == lexer.ILLEGAL {
-		// 		break
-		// 	} else if tok == lexer.WS {
-		// 		continue
-		// 	}
+// writeError writes an error followed by the input line with the source of
+// the error underlined.
+func writeError(resp *ResponseWriter, line string, err error) {
+	resp.Write(resp.Colors.Red)
+	resp.Write([]byte(err.Error() + "\n"))
+	if span, ok := errorSpan(err); ok && span.Start.Line == 0 && span.End.Line == 0 {
+		start, end := span.Start.Char, span.End.Char
+		if end <= start {
+			end = start + 1
+		}
+		resp.Write(resp.Colors.LightGrey)
+		resp.Write([]byte("  " + line + "\n"))
+		resp.Write(resp.Colors.Yellow)
+		resp.Write([]byte("  " + strings.Repeat(" ", start) + strings.Repeat("^", end-start) + "\n"))
+	}
 
-		// 	if *debug {
-		// 		resp.Write(resp.Colors.LightGrey)
-		// 		resp.Write([]byte("DEBUG: "))
-		// 		resp.Write(resp.Colors.Green)
-		// 		resp.Write([]byte(fmt.Sprintf("%-8s", tok.String())))
-		// 		// resp.Write(resp.Colors.White)
-		// 		resp.Write(resp.Colors.Yellow)
-		// 		resp.Write([]byte(fmt.Sprintf("%3d", pos.Line)))
-		// 		resp.Write(resp.Colors.White)
-		// 		resp.Write([]byte(":"))
-		// 		resp.Write(resp.Colors.Yellow)
-		// 		resp.Write([]byte(fmt.Sprintf("%-3d", pos.Char)))
-		// 		resp.Write(resp.Colors.White)
-		// 		resp.Write([]byte(lit))
-		// 		resp.Write([]byte("\n"))
-		// 	}
-		// }
+	var eerr *eval.Error
+	if errors.As(err, &eerr) && eerr.Hint != "" {
+		resp.Write(resp.Colors.LightGrey)
+		resp.Write([]byte("hint: " + eerr.Hint + "\n"))
+	}
+	resp.Write(resp.Colors.Reset)
+}
 
+// errorSpan returns the source span of parse, check and evaluation errors.
+func errorSpan(err error) (ast.Span, bool) {
+	var eerr *eval.Error
+	if errors.As(err, &eerr) {
+		return eerr.Span, true
+	} else if cerr, ok := err.(*check.Error); ok {
+		return ast.Span{Start: cerr.Pos}, true
+	} else if perr, ok := err.(*parser.ParseError); ok {
+		return ast.Span{Start: perr.Pos}, true
 	}
+	return ast.Span{}, false
 }
 
 type ReadWriter struct {